@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entries returns the configuration as sorted "section.key: value" lines, keyed
+// by the YAML names so they can be compared with the file they were loaded from.
+func (cfg *Config) Entries() []string {
+	var entries []string
+	appendEntries(&entries, "", reflect.ValueOf(cfg).Elem())
+	sort.Strings(entries)
+	return entries
+}
+
+// LogEffective logs the configuration in effect after defaults, the file and
+// environment overrides are applied, one line per key.
+func (cfg *Config) LogEffective() {
+	for _, entry := range cfg.Entries() {
+		log.Printf("Config ➜ Effective ➜ %s", entry)
+	}
+}
+
+// DefaultConfigJSON returns the default configuration as indented JSON, keyed
+// by the YAML names with durations in the YAML form, e.g. "10s", so tooling can
+// build on the real defaults instead of duplicating them.
+func DefaultConfigJSON() ([]byte, error) {
+	return json.MarshalIndent(sectionMap(reflect.ValueOf(getDefaultConfig()).Elem()), "", "  ")
+}
+
+// sectionMap returns the fields of the struct v keyed by their YAML names,
+// nested sections become nested maps like in appendEntries.
+func sectionMap(v reflect.Value) map[string]any {
+	t := v.Type()
+	section := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		value := v.Field(i)
+		switch val := value.Interface().(type) {
+		case time.Duration:
+			section[name] = val.String()
+		default:
+			switch {
+			case value.Kind() == reflect.Struct:
+				section[name] = sectionMap(value)
+			case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+				list := make([]map[string]any, value.Len())
+				for j := range list {
+					list[j] = sectionMap(value.Index(j))
+				}
+				section[name] = list
+			case value.Kind() == reflect.Slice && value.IsNil():
+				// An empty list rather than null, as in config.yaml.
+				section[name] = reflect.MakeSlice(value.Type(), 0, 0).Interface()
+			default:
+				section[name] = val
+			}
+		}
+	}
+	return section
+}
+
+// appendEntries appends one line per field of the struct v, recursing into
+// nested sections. Lists of sections are keyed by index, e.g. "dsp.onset_bands.0.name".
+func appendEntries(entries *[]string, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+
+		value := v.Field(i)
+		switch val := value.Interface().(type) {
+		case time.Duration:
+			*entries = append(*entries, fmt.Sprintf("%s: %s", key, val))
+		case string:
+			*entries = append(*entries, fmt.Sprintf("%s: %q", key, val))
+		default:
+			if value.Kind() == reflect.Struct {
+				appendEntries(entries, key+".", value)
+				continue
+			}
+			if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct {
+				for j := 0; j < value.Len(); j++ {
+					appendEntries(entries, fmt.Sprintf("%s.%d.", key, j), value.Index(j))
+				}
+				continue
+			}
+			*entries = append(*entries, fmt.Sprintf("%s: %v", key, val))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0
+package config
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Entries(t *testing.T) {
+	cfg := getDefaultConfig()
+	cfg.DSP.FFTWindow = "Hamming"
+
+	entries := cfg.Entries()
+
+	assert.True(t, sort.StringsAreSorted(entries), "Entries should be sorted by key")
+	assert.Contains(t, entries, `dsp.fft_window: "Hamming"`, "Strings should be quoted")
+	assert.Contains(t, entries, "dsp.confidence_smoothing: 1s", "Durations should be readable")
+	assert.Contains(t, entries, "transport.reconnect.max_interval: 30s", "Nested sections should be flattened")
+	assert.Contains(t, entries, "debug: false")
+	assert.Contains(t, entries, `dsp.onset_bands.1.name: "low"`, "Lists of sections should be keyed by index")
+	for _, entry := range entries {
+		assert.NotContains(t, entry, "{", "Sections should not be printed as a whole")
+	}
+}
+
+func TestDefaultConfigJSON(t *testing.T) {
+	data, err := DefaultConfigJSON()
+	require.NoError(t, err)
+
+	var defaults struct {
+		Debug bool `json:"debug"`
+		Input struct {
+			SampleRate float64 `json:"sample_rate"`
+		} `json:"input"`
+		Transport struct {
+			Reconnect struct {
+				MaxInterval string `json:"max_interval"`
+			} `json:"reconnect"`
+		} `json:"transport"`
+		DSP struct {
+			BPMOnsetMemory string    `json:"bpm_onset_memory"`
+			BPMHintRange   []float64 `json:"bpm_hint_range"`
+			OnsetBands     []struct {
+				Name string  `json:"name"`
+				Low  float64 `json:"low"`
+			} `json:"onset_bands"`
+		} `json:"dsp"`
+	}
+	require.NoError(t, json.Unmarshal(data, &defaults))
+
+	expected := getDefaultConfig()
+	assert.Equal(t, expected.Input.SampleRate, defaults.Input.SampleRate, "Fields should be keyed by their YAML names")
+	assert.Equal(t, "30s", defaults.Transport.Reconnect.MaxInterval, "Durations should be readable")
+	assert.Equal(t, "10s", defaults.DSP.BPMOnsetMemory, "Durations should be readable")
+	assert.NotNil(t, defaults.DSP.BPMHintRange, "Empty lists should not be null")
+	require.Len(t, defaults.DSP.OnsetBands, len(expected.DSP.OnsetBands))
+	assert.Equal(t, "low", defaults.DSP.OnsetBands[1].Name, "Lists of sections should be objects")
+	assert.NotContains(t, string(data), "null", "No field should be null")
+}
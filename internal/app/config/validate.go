@@ -1,13 +1,119 @@
 // SPDX-License-Identifier: Apache-2.0
 package config
 
-import "github.com/go-playground/validator/v10"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"phase4/internal/p4/analysis"
+	"phase4/pkg/bitint"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
 
 func init() {
 	av.validator = validator.New()
 
 	// Register custom validation functions here.
 	// See: https://pkg.go.dev/github.com/go-playground/validator/v10#hdr-Custom_Validation_Functions
+	_ = av.validator.RegisterValidation("hostname_port", isHostnamePort)
+	_ = av.validator.RegisterValidation("url_path", isURLPath)
+	_ = av.validator.RegisterValidation("fftwindow", isFFTWindow)
+	_ = av.validator.RegisterValidation("power_of_two", isPowerOfTwo)
+	_ = av.validator.RegisterValidation("onset_memory", isOnsetMemory)
+
+	av.validator.RegisterStructValidation(validateConfig, Config{})
+}
+
+// validateConfig checks rules that span config sections or elements. When
+// dsp.fft_size is 0 the FFT is sized from input.buffer_size, which must then be
+// a power of two or NewFFTProcessor rejects it during engine initialization.
+func validateConfig(sl validator.StructLevel) {
+	cfg := sl.Current().Interface().(Config)
+
+	bufferSize := cfg.Input.BufferSize
+	if cfg.DSP.Enabled && cfg.DSP.FFTSize == 0 && bufferSize > 0 && !bitint.IsPowerOfTwo(bufferSize) {
+		sl.ReportError(bufferSize, "Input.BufferSize", "BufferSize", "power_of_two", "")
+	}
+
+	// The tempo hint is a [low, high] pair, its elements are checked by tag.
+	if hint := cfg.DSP.BPMHintRange; len(hint) == 2 && hint[1] < hint[0] {
+		sl.ReportError(hint, "DSP.BPMHintRange", "BPMHintRange", "ascending", "")
+	}
+}
+
+// isPowerOfTwo checks that an integer is a positive power of two, as required
+// for FFT sizes.
+func isPowerOfTwo(fl validator.FieldLevel) bool {
+	return bitint.IsPowerOfTwo(int(fl.Field().Int()))
+}
+
+// explainPowerOfTwo adds the next valid size to a power_of_two failure, the
+// validator's own message only names the tag. The original error stays wrapped.
+func explainPowerOfTwo(err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	for _, fieldErr := range validationErrs {
+		if fieldErr.Tag() != "power_of_two" {
+			continue
+		}
+		size, ok := fieldErr.Value().(int)
+		if !ok {
+			continue
+		}
+		return fmt.Errorf("%s %d is not a power of two, try %d: %w",
+			fieldErr.Namespace(), size, bitint.NextPowerOfTwo(size), err)
+	}
+
+	return err
+}
+
+// isOnsetMemory checks that an onset memory fits the BPM detector's onset
+// buffer, see analysis.MaxOnsetMemory. Longer memories would silently lose
+// their oldest onsets.
+func isOnsetMemory(fl validator.FieldLevel) bool {
+	return time.Duration(fl.Field().Int()) <= analysis.MaxOnsetMemory
+}
+
+// isFFTWindow defers to analysis.ParseWindowFunc so the names accepted here can
+// not drift from the names the engine understands, including case and aliases.
+func isFFTWindow(fl validator.FieldLevel) bool {
+	_, err := analysis.ParseWindowFunc(fl.Field().String())
+	return err == nil
+}
+
+// isURLPath checks that a value can be registered as an HTTP mux pattern path,
+// i.e. it starts with a slash. A path without one never matches a request and
+// fails silently with a 404. An empty value is valid, use required to reject it.
+func isURLPath(fl validator.FieldLevel) bool {
+	path := fl.Field().String()
+	return path == "" || strings.HasPrefix(path, "/")
+}
+
+// isHostnamePort replaces the built-in hostname_port validation, which rejects IP
+// literals that are not also valid RFC 1123 hostnames (e.g. [::1]:9000). The host
+// may be empty, an IPv4/IPv6 literal or a hostname, the port must be 1-65535.
+func isHostnamePort(fl validator.FieldLevel) bool {
+	host, port, err := net.SplitHostPort(fl.Field().String())
+	if err != nil {
+		return false
+	}
+
+	if portNum, err := strconv.ParseInt(port, 10, 32); err != nil || portNum > 65535 || portNum < 1 {
+		return false
+	}
+
+	if host == "" || net.ParseIP(host) != nil {
+		return true
+	}
+
+	return av.validator.Var(host, "hostname_rfc1123") == nil
 }
 
 func GetValidator() *validator.Validate {
@@ -2,7 +2,9 @@
 package config
 
 import (
+	"phase4/internal/p4/analysis"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
@@ -32,3 +34,115 @@ func TestGetValidator_IsSingleton(t *testing.T) {
 	assert.NotNil(t, instance2, "GetValidator() returned nil unexpectedly (instance2)")
 	assert.Same(t, instance1, instance2, "Expected GetValidator() to return the same instance")
 }
+
+func TestGetValidator_HostnamePort(t *testing.T) {
+	testCases := []struct {
+		value string
+		valid bool
+	}{
+		{"127.0.0.1:8888", true},
+		{"localhost:8888", true},
+		{"239.0.0.1:9000", true},
+		{"[::1]:9000", true},
+		{"[ff02::1]:9000", true},
+		{":9000", true},
+		{"::1:9000", false},
+		{"127.0.0.1", false},
+		{"127.0.0.1:0", false},
+		{"127.0.0.1:65536", false},
+		{"invalid-address", false},
+	}
+
+	instance := GetValidator()
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			err := instance.Var(tc.value, "hostname_port")
+			if tc.valid {
+				assert.NoError(t, err, "Expected %q to be valid", tc.value)
+			} else {
+				assert.Error(t, err, "Expected %q to be invalid", tc.value)
+			}
+		})
+	}
+}
+
+func TestGetValidator_URLPath(t *testing.T) {
+	testCases := []struct {
+		value string
+		valid bool
+	}{
+		{"/ws", true},
+		{"/", true},
+		{"", true},
+		{"ws", false},
+		{"ws/", false},
+	}
+
+	instance := GetValidator()
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			err := instance.Var(tc.value, "url_path")
+			if tc.valid {
+				assert.NoError(t, err, "Expected %q to be valid", tc.value)
+			} else {
+				assert.Error(t, err, "Expected %q to be invalid", tc.value)
+			}
+		})
+	}
+}
+
+func TestGetValidator_FFTWindow(t *testing.T) {
+	testCases := []struct {
+		value string
+		valid bool
+	}{
+		{"Hann", true},
+		{"hann", true},
+		{"Hanning", true},
+		{"BartlettHann", true},
+		{"blackmannuttall", true},
+		{"Nuttall", true},
+		{"", false},
+		{"Kaiser", false},
+	}
+
+	instance := GetValidator()
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			err := instance.Var(tc.value, "fftwindow")
+			if tc.valid {
+				assert.NoError(t, err, "Expected %q to be valid", tc.value)
+			} else {
+				assert.Error(t, err, "Expected %q to be invalid", tc.value)
+			}
+		})
+	}
+}
+
+func TestGetValidator_OnsetMemory(t *testing.T) {
+	instance := GetValidator()
+
+	assert.NoError(t, instance.Var(10*time.Second, "onset_memory"), "The default memory should be valid")
+	assert.NoError(t, instance.Var(analysis.MaxOnsetMemory, "onset_memory"), "The buffer capacity should be valid")
+	assert.Error(t, instance.Var(analysis.MaxOnsetMemory+time.Second, "onset_memory"), "A memory beyond the buffer capacity should be invalid")
+}
+
+func TestValidateConfig_BPMHintRange(t *testing.T) {
+	cfg := getDefaultConfig()
+	assert.NoError(t, GetValidator().Struct(cfg), "No hint should be valid")
+
+	cfg.DSP.BPMHintRange = []float64{}
+	assert.NoError(t, GetValidator().Struct(cfg), "An empty hint, as in config.yaml, should be valid")
+
+	cfg.DSP.BPMHintRange = []float64{120, 140}
+	assert.NoError(t, GetValidator().Struct(cfg), "An ascending hint within 60-200 should be valid")
+
+	cfg.DSP.BPMHintRange = []float64{140, 120}
+	assert.Error(t, GetValidator().Struct(cfg), "A descending hint should be invalid")
+
+	cfg.DSP.BPMHintRange = []float64{120}
+	assert.Error(t, GetValidator().Struct(cfg), "A hint needs two bounds")
+
+	cfg.DSP.BPMHintRange = []float64{40, 140}
+	assert.Error(t, GetValidator().Struct(cfg), "A hint outside 60-200 should be invalid")
+}
@@ -2,7 +2,9 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"phase4/internal/app/errors"
@@ -11,34 +13,25 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+// stdin is read by LoadFrom for the path "-", tests replace it.
+var stdin io.Reader = os.Stdin
+
 // Load will attempt to load the configuration from a list of candidate
 // files. If no file is found, it will return an error. The configuration will
 // be parsed and validated. If any errors occur during loading or validation,
 // they will be returned. The function will apply any environment variables to
 // the configuration, taking precedence over the file values.
 func Load() (*Config, error) {
-	cfg := getDefaultConfig()
+	return LoadFrom("")
+}
 
-	var filePath = ""
-	candidates := []string{
-		"config.yaml",
-		"config/config.yaml",
-	}
-	for _, candidate := range candidates {
-		if _, err := os.Stat(candidate); err == nil {
-			filePath = candidate
-			log.Printf("Config ➜ Candidate file %s found", filePath)
-			break
-		}
-	}
-	if filePath == "" {
-		return nil, &errors.FatalError{
-			Message: "file not found",
-			Err:     fmt.Errorf("config.yaml was not found in the current directory or any candidate subdirectory"),
-		}
-	}
+// LoadFrom loads the configuration like Load, from path instead of the
+// candidate files. An empty path searches the candidates, "-" reads the YAML or
+// JSON configuration from stdin, e.g. piped from a secret store.
+func LoadFrom(path string) (*Config, error) {
+	cfg := getDefaultConfig()
 
-	data, err := os.ReadFile(filePath)
+	data, err := readConfig(path)
 	if err != nil {
 		return nil, err
 	}
@@ -59,32 +52,154 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// readConfig returns the raw configuration from path, see LoadFrom.
+func readConfig(path string) ([]byte, error) {
+	switch path {
+	case "-":
+		log.Print("Config ➜ Reading from stdin")
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, &errors.FatalError{
+				Message: "config empty",
+				Err:     fmt.Errorf("no configuration was read from stdin"),
+			}
+		}
+		return data, nil
+	case "":
+		path = findCandidate()
+		if path == "" {
+			return nil, &errors.FatalError{
+				Message: "file not found",
+				Err:     fmt.Errorf("config.yaml was not found in the current directory or any candidate subdirectory"),
+			}
+		}
+	}
+
+	return os.ReadFile(path)
+}
+
+// findCandidate returns the first existing candidate config file, or "".
+func findCandidate() string {
+	candidates := []string{
+		"config.yaml",
+		"config/config.yaml",
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			log.Printf("Config ➜ Candidate file %s found", candidate)
+			return candidate
+		}
+	}
+	return ""
+}
+
 func (cfg *Config) Validate() error {
 	validate := GetValidator()
-	return validate.Struct(cfg)
+	return explainPowerOfTwo(validate.Struct(cfg))
 }
 
 func getDefaultConfig() *Config {
 	return &Config{
-		Debug: false,
+		Debug:           false,
+		ShutdownTimeout: 10 * time.Second,
 		Input: InputConfig{
-			Device:     -1,
-			Channels:   2,
-			SampleRate: 44100,
-			BufferSize: 512,
-			LowLatency: false,
+			Device:       -1,
+			Channels:     2,
+			SampleRate:   44100,
+			BufferSize:   512,
+			SampleFormat: "int32",
+			GainDB:       0,
+			Source:       "device",
+			ReplaySpeed:  1,
+			SourceMode:   "separate",
+			SourceID:     "main",
+			LowLatency:   false,
+			// Retries are off by default, a missing device fails fast.
+			OpenRetries:       0,
+			OpenRetryInterval: time.Second,
 		},
 		Transport: TransportConfig{
-			UDPEnabled:       false,
-			UDPSendAddress:   "127.0.0.1:8888",
-			UDPSendInterval:  33 * time.Millisecond,
-			WebSocketEnabled: false,
-			WebSocketAddress: "127.0.0.1:8889",
-			WebSocketPath:    "/ws",
+			UDPEnabled:        false,
+			UDPSendAddress:    "127.0.0.1:8888",
+			UDPSendInterval:   33 * time.Millisecond,
+			UDPFormat:         "json",
+			UDPMulticastTTL:   1,
+			WebSocketEnabled:  false,
+			WebSocketAddress:  "127.0.0.1:8889",
+			WebSocketPath:     "/ws",
+			WebSocketWorkers:  8,
+			WebSocketMaxBins:  4096,
+			TCPEnabled:        false,
+			TCPAddress:        "127.0.0.1:8890",
+			MQTTEnabled:       false,
+			MQTTBroker:        "127.0.0.1:1883",
+			MQTTTopic:         "phase4/fft",
+			MQTTClientID:      "",
+			MQTTFormat:        "json",
+			MQTTSendInterval:  33 * time.Millisecond,
+			ReadLimit:         4096,
+			LogEnabled:        false,
+			LogInterval:       time.Second,
+			Stdout:            false,
+			StdoutInterval:    100 * time.Millisecond,
+			GapDetection:      false,
+			GapReportInterval: 10 * time.Second,
+			RecordFile:        "",
+			HeartbeatInterval: 0,
+			FailFast:          false,
+			Reconnect: ReconnectConfig{
+				InitialInterval: 500 * time.Millisecond,
+				MaxInterval:     30 * time.Second,
+				Multiplier:      2,
+				Jitter:          0.2,
+			},
 		},
 		DSP: DSPConfig{
-			Enabled:   false,
-			FFTWindow: "Hann",
+			Enabled:             false,
+			FFTWindow:           "Hann",
+			FluxMode:            "linear",
+			OnsetMethod:         "flux",
+			BPMMethod:           "histogram",
+			BPMConfidence:       "variation",
+			MagnitudeScaling:    "single_sided",
+			SpectrumType:        "magnitude",
+			ConfidenceSmoothing: time.Second,
+			BPMDisplayWindow:    4 * time.Second,
+			// About 1.2 BPM at 120 BPM, see BPMDetector.SetHistogramResolution.
+			BPMHistogramResolution: 5 * time.Millisecond,
+			BPMOnsetMemory:         10 * time.Second,
+			// 0 estimates on every onset, see BPMDetector.SetEstimateInterval.
+			BPMEstimateInterval: 0,
+			// Roughly 50ms at 256 samples/44.1kHz, enough for the device and
+			// the pre-emphasis/flux state to settle.
+			WarmupFrames:    8,
+			OnsetPreWindow:  0,
+			OnsetPostWindow: 0,
+			// 0 keeps the fixed 100ms debounce, see BPMDetector.SetOnsetInterval.
+			OnsetIntervalBeats: 0,
+			FixedBPM:           0,
+			BPMMinConfidence:   0,
+			BPMStats:           false,
+			EmitPhase:          false,
+			FluxNormalize:      false,
+			ReportLatency:      false,
+			LFO:                false,
+			LFOWaveform:        "sine",
+			LFOBeats:           1,
+			NoiseReduction:     false,
+			NoiseFloor:         0.05,
+			BandOnsets:         false,
+			OnsetBands: []OnsetBandConfig{
+				{Name: "sub", Low: 20, High: 60},
+				{Name: "low", Low: 60, High: 250},
+				{Name: "mid", Low: 250, High: 2000},
+				{Name: "high", Low: 2000, High: 16000},
+			},
+			// The first 10 bins at 256 samples/44.1kHz, kicks and bass.
+			BPMFreqHigh: 1600,
 		},
 	}
 }
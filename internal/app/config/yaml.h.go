@@ -4,31 +4,136 @@ package config
 import "time"
 
 type Config struct {
-	DSP       DSPConfig       `yaml:"dsp"       validate:"required"`
-	Transport TransportConfig `yaml:"transport" validate:"required"`
-	Input     InputConfig     `yaml:"input"     validate:"required"`
-	Debug     bool            `yaml:"debug"`
+	DSP             DSPConfig       `yaml:"dsp"              validate:"required"`
+	Transport       TransportConfig `yaml:"transport"        validate:"required"`
+	Input           InputConfig     `yaml:"input"            validate:"required"`
+	ShutdownTimeout time.Duration   `yaml:"shutdown_timeout" validate:"gt=0"`
+	Debug           bool            `yaml:"debug"`
 }
 
 type InputConfig struct {
-	Device           int     `yaml:"device"      validate:"gte=-1"`
-	Channels         int     `yaml:"channels"    validate:"gt=0"`
-	SampleRate       float64 `yaml:"sample_rate" validate:"gt=0"`
-	BufferSize       int     `yaml:"buffer_size" validate:"gt=0"`
-	LowLatency       bool    `yaml:"low_latency"`
-	UseDefaultDevice bool    `yaml:"use_default"`
+	Device            int                 `yaml:"device"              validate:"gte=-1"`
+	Channels          int                 `yaml:"channels"            validate:"gt=0"`
+	SampleRate        float64             `yaml:"sample_rate"         validate:"gt=0"`
+	BufferSize        int                 `yaml:"buffer_size"         validate:"gt=0"`
+	LatencyMs         float64             `yaml:"latency_ms"          validate:"gte=0"`
+	GainDB            float64             `yaml:"gain_db"             validate:"gte=-60,lte=60"`
+	OpenRetries       int                 `yaml:"open_retries"        validate:"gte=0"`
+	OpenRetryInterval time.Duration       `yaml:"open_retry_interval" validate:"gte=0"`
+	HostApi           string              `yaml:"host_api"`
+	File              string              `yaml:"file"`
+	Source            string              `yaml:"source"              validate:"oneof=device null replay"`
+	ReplayFile        string              `yaml:"replay_file"         validate:"required_if=Source replay"`
+	ReplaySpeed       float64             `yaml:"replay_speed"        validate:"gte=0"`
+	SampleFormat      string              `yaml:"sample_format"       validate:"oneof=int32 int16 float32"`
+	SourceMode        string              `yaml:"source_mode"         validate:"oneof=separate mix"`
+	SourceID          string              `yaml:"source_id"`
+	ExtraSources      []InputSourceConfig `yaml:"extra_sources"       validate:"dive"`
+	LowLatency        bool                `yaml:"low_latency"`
+	UseDefaultDevice  bool                `yaml:"use_default"`
+	FileLoop          bool                `yaml:"file_loop"`
+	AllowNoDevice     bool                `yaml:"allow_no_device"`
+}
+
+// InputSourceConfig is an additional capture device, see
+// InputConfig.ExtraSources.
+type InputSourceConfig struct {
+	ID       string `yaml:"id"       validate:"required"`
+	Device   int    `yaml:"device"   validate:"gte=0"`
+	Channels int    `yaml:"channels" validate:"gt=0"`
 }
 
 type TransportConfig struct {
-	UDPSendAddress   string        `yaml:"udp_send_address"  validate:"required_if=UDPEnabled true,hostname_port"`
-	WebSocketAddress string        `yaml:"websocket_address" validate:"required_if=WebSocketEnabled true,hostname_port"`
-	WebSocketPath    string        `yaml:"websocket_path"    validate:"required_if=WebSocketEnabled true"`
-	UDPSendInterval  time.Duration `yaml:"udp_send_interval" validate:"required_if=UDPEnabled true,gt=0"`
-	UDPEnabled       bool          `yaml:"udp_enabled"`
-	WebSocketEnabled bool          `yaml:"websocket_enabled"`
+	Reconnect         ReconnectConfig `yaml:"reconnect"`
+	UDPSendAddress    string          `yaml:"udp_send_address"    validate:"required_if=UDPEnabled true,hostname_port"`
+	WebSocketAddress  string          `yaml:"websocket_address"   validate:"required_if=WebSocketEnabled true,hostname_port"`
+	WebSocketPath     string          `yaml:"websocket_path"      validate:"required_if=WebSocketEnabled true,url_path"`
+	TCPAddress        string          `yaml:"tcp_address"         validate:"required_if=TCPEnabled true,hostname_port"`
+	UDPInterface      string          `yaml:"udp_interface"`
+	RecordFile        string          `yaml:"record_file"`
+	MQTTBroker        string          `yaml:"mqtt_broker"         validate:"required_if=MQTTEnabled true,hostname_port"`
+	MQTTTopic         string          `yaml:"mqtt_topic"          validate:"required_if=MQTTEnabled true"`
+	MQTTClientID      string          `yaml:"mqtt_client_id"      validate:"max=65535"`
+	MQTTFormat        string          `yaml:"mqtt_format"         validate:"oneof=json binary"`
+	MQTTSendInterval  time.Duration   `yaml:"mqtt_send_interval"  validate:"gte=0"`
+	UDPFormat         string          `yaml:"udp_format"          validate:"oneof=json binary"`
+	UDPSendInterval   time.Duration   `yaml:"udp_send_interval"   validate:"required_if=UDPEnabled true,gt=0"`
+	LogInterval       time.Duration   `yaml:"log_interval"        validate:"gte=0"`
+	StdoutInterval    time.Duration   `yaml:"stdout_interval"     validate:"gte=0"`
+	GapReportInterval time.Duration   `yaml:"gap_report_interval" validate:"gte=0"`
+	HeartbeatInterval time.Duration   `yaml:"heartbeat_interval"  validate:"gte=0"`
+	WebSocketWorkers  int             `yaml:"websocket_workers"   validate:"gt=0"`
+	WebSocketMaxBins  int             `yaml:"websocket_max_bins"  validate:"gte=0"`
+	ReadLimit         int64           `yaml:"read_limit"          validate:"gte=0"`
+	UDPMulticastTTL   int             `yaml:"udp_multicast_ttl"   validate:"gte=0,lte=255"`
+	UDPEnabled        bool            `yaml:"udp_enabled"`
+	WebSocketEnabled  bool            `yaml:"websocket_enabled"`
+	TCPEnabled        bool            `yaml:"tcp_enabled"`
+	MQTTEnabled       bool            `yaml:"mqtt_enabled"`
+	LogEnabled        bool            `yaml:"log_enabled"`
+	Stdout            bool            `yaml:"stdout"`
+	GapDetection      bool            `yaml:"gap_detection"`
+	FailFast          bool            `yaml:"fail_fast"`
+}
+
+// ReconnectConfig is the retry policy shared by outbound transports.
+type ReconnectConfig struct {
+	InitialInterval time.Duration `yaml:"initial_interval" validate:"gte=0"`
+	MaxInterval     time.Duration `yaml:"max_interval"     validate:"gte=0"`
+	Multiplier      float64       `yaml:"multiplier"       validate:"gte=1"`
+	Jitter          float64       `yaml:"jitter"           validate:"gte=0,lte=1"`
 }
 
 type DSPConfig struct {
-	FFTWindow string `yaml:"fft_window" validate:"required_if=Enabled true,oneof='BartlettHann' 'Blackman' 'BlackmanNuttall' 'Hann' 'Hanning' 'Hamming' 'Lanczos' 'Nuttall'"`
-	Enabled   bool   `yaml:"enabled"`
+	FFTWindow              string            `yaml:"fft_window"               validate:"required_if=Enabled true,fftwindow"`
+	FluxMode               string            `yaml:"flux_mode"                validate:"required_if=Enabled true,oneof=linear log"`
+	OnsetMethod            string            `yaml:"onset_method"             validate:"required_if=Enabled true,oneof=flux complex"`
+	BPMMethod              string            `yaml:"bpm_method"               validate:"required_if=Enabled true,oneof=histogram autocorrelation"`
+	BPMConfidence          string            `yaml:"bpm_confidence"           validate:"required_if=Enabled true,oneof=variation peak"`
+	MagnitudeScaling       string            `yaml:"magnitude_scaling"        validate:"required_if=Enabled true,oneof=single_sided raw power"`
+	SpectrumType           string            `yaml:"spectrum_type"            validate:"required_if=Enabled true,oneof=magnitude power psd"`
+	LFOWaveform            string            `yaml:"lfo_waveform"             validate:"required_if=LFO true,omitempty,oneof=sine triangle ramp"`
+	BPMHistogramResolution time.Duration     `yaml:"bpm_histogram_resolution" validate:"gte=0"`
+	BPMOnsetMemory         time.Duration     `yaml:"bpm_onset_memory"         validate:"gte=0,onset_memory"`
+	ConfidenceSmoothing    time.Duration     `yaml:"confidence_smoothing"     validate:"gte=0"`
+	BPMDisplayWindow       time.Duration     `yaml:"bpm_display_window"       validate:"gte=0"`
+	BPMEstimateInterval    time.Duration     `yaml:"bpm_estimate_interval"    validate:"gte=0"`
+	WarmupFrames           int               `yaml:"warmup_frames"            validate:"gte=0,required_if=NoiseReduction true"`
+	OnsetPreWindow         int               `yaml:"onset_pre_window"         validate:"gte=0,lte=10"`
+	OnsetPostWindow        int               `yaml:"onset_post_window"        validate:"gte=0,lte=10"`
+	AnalysisPriority       int               `yaml:"analysis_priority"        validate:"gte=-20,lte=19"`
+	FFTSize                int               `yaml:"fft_size"                 validate:"omitempty,power_of_two"`
+	BPMHintRange           []float64         `yaml:"bpm_hint_range"           validate:"len=0|len=2,dive,gte=60,lte=200"`
+	OnsetBands             []OnsetBandConfig `yaml:"onset_bands"              validate:"required_if=BandOnsets true,dive"`
+	ExtraFFTSizes          []int             `yaml:"extra_fft_sizes"          validate:"dive,power_of_two"`
+	AnalysisCPUs           []int             `yaml:"analysis_cpus"            validate:"dive,gte=0,lt=1024"`
+	OutputFreqMin          float64           `yaml:"output_freq_min"          validate:"gte=0"`
+	OutputFreqMax          float64           `yaml:"output_freq_max"          validate:"omitempty,gtfield=OutputFreqMin"`
+	BPMFreqLow             float64           `yaml:"bpm_freq_low"             validate:"gte=0"`
+	BPMFreqHigh            float64           `yaml:"bpm_freq_high"            validate:"omitempty,gtfield=BPMFreqLow"`
+	Preemphasis            float64           `yaml:"preemphasis"              validate:"gte=0,lt=1"`
+	OnsetIntervalBeats     float64           `yaml:"onset_interval_beats"     validate:"gte=0,lte=1"`
+	NoiseFloor             float64           `yaml:"noise_floor"              validate:"gte=0,lte=1"`
+	FixedBPM               float64           `yaml:"fixed_bpm"                validate:"gte=0,lte=300"`
+	BPMMinConfidence       float64           `yaml:"bpm_min_confidence"       validate:"gte=0,lte=1"`
+	LFOBeats               float64           `yaml:"lfo_beats"                validate:"required_if=LFO true,gte=0"`
+	Enabled                bool              `yaml:"enabled"`
+	SelfTestOnStart        bool              `yaml:"selftest_on_start"`
+	AsyncAnalysis          bool              `yaml:"async_analysis"`
+	SpectralFeatures       bool              `yaml:"spectral_features"`
+	NoiseReduction         bool              `yaml:"noise_reduction"`
+	BPMStats               bool              `yaml:"bpm_stats"`
+	EmitPhase              bool              `yaml:"emit_phase"`
+	FluxNormalize          bool              `yaml:"flux_normalize"`
+	ReportLatency          bool              `yaml:"report_latency"`
+	LFO                    bool              `yaml:"lfo"`
+	BandOnsets             bool              `yaml:"band_onsets"`
+}
+
+// OnsetBandConfig is a frequency range with its own onset detector, see
+// DSPConfig.BandOnsets.
+type OnsetBandConfig struct {
+	Name string  `yaml:"name" validate:"required"`
+	Low  float64 `yaml:"low"  validate:"gte=0"`
+	High float64 `yaml:"high" validate:"gtfield=Low"`
 }
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"phase4/internal/app/errors"
 	"phase4/internal/testutil"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -166,3 +167,122 @@ transport:
 		}
 	}
 }
+
+func TestLoadConfig_InvalidFluxMode(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	yamlContent := `
+dsp:
+  enabled: true
+  flux_mode: "cubic"
+`
+	testutil.CreateTempConfigFile(t, ".", "config.yaml", yamlContent)
+
+	cfg, err := Load()
+
+	assert.Nil(t, cfg, "Config should be nil when validation fails")
+	if assert.Error(t, err, "Expected an error for an unknown flux mode") {
+		assert.Contains(t, err.Error(), "FluxMode", "Error message should mention the invalid field 'FluxMode'")
+	}
+}
+
+func TestLoadConfig_BufferSizeNotPowerOfTwo(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	yamlContent := `
+input:
+  buffer_size: 500
+dsp:
+  enabled: true
+`
+	testutil.CreateTempConfigFile(t, ".", "config.yaml", yamlContent)
+
+	cfg, err := Load()
+
+	assert.Nil(t, cfg, "Config should be nil when validation fails")
+	if assert.Error(t, err, "Expected an error for a buffer size that is not a power of two") {
+		assert.Contains(t, err.Error(), "BufferSize", "Error message should mention the invalid field 'BufferSize'")
+		assert.Contains(t, err.Error(), "try 512", "Error message should suggest the next power of two")
+	}
+}
+
+func TestLoadConfig_BufferSizeWithFFTSize(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	yamlContent := `
+input:
+  buffer_size: 500
+dsp:
+  enabled: true
+  fft_size: 512
+`
+	testutil.CreateTempConfigFile(t, ".", "config.yaml", yamlContent)
+
+	cfg, err := Load()
+
+	require.NoError(t, err, "A separate fft_size should allow any buffer size")
+	assert.Equal(t, 500, cfg.Input.BufferSize)
+}
+
+func TestLoadFrom_Stdin(t *testing.T) {
+	setStdin := func(t *testing.T, content string) {
+		t.Helper()
+		original := stdin
+		stdin = strings.NewReader(content)
+		t.Cleanup(func() { stdin = original })
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		setStdin(t, `{"debug": false, "input": {"channels": 1}}`)
+		t.Setenv("ENV_DEBUG", "true")
+
+		expected := getDefaultConfig()
+		expected.Debug = true
+		expected.Input.Channels = 1
+
+		cfg, err := LoadFrom("-")
+
+		require.NoError(t, err, "LoadFrom should succeed")
+		assert.Equal(t, *expected, *cfg, "Stdin and env overrides should be applied")
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		setStdin(t, "\n")
+
+		_, err := LoadFrom("-")
+
+		var fatalErr *errors.FatalError
+		assert.ErrorAs(t, err, &fatalErr, "Empty stdin should be fatal")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		setStdin(t, "input:\n  channels: 0\n")
+
+		_, err := LoadFrom("-")
+
+		assert.Error(t, err, "Stdin config should be validated")
+	})
+}
+
+func TestLoadFrom_Path(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	testutil.CreateTempConfigFile(t, ".", "custom.yaml", `debug: true`)
+
+	cfg, err := LoadFrom("custom.yaml")
+	require.NoError(t, err, "LoadFrom should succeed")
+	assert.True(t, cfg.Debug, "The given file should be loaded")
+
+	_, err = LoadFrom("missing.yaml")
+	assert.ErrorIs(t, err, os.ErrNotExist, "A missing file should be reported")
+}
+
+func TestLoadFrom_ShippedConfig(t *testing.T) {
+	cfg, err := LoadFrom(filepath.Join("..", "..", "..", "config.yaml"))
+	require.NoError(t, err, "The config.yaml shipped with the repository should load")
+	assert.Empty(t, cfg.DSP.BPMHintRange, "The shipped config should not set a tempo hint")
+}
@@ -27,6 +27,10 @@ func (f *FatalError) Error() string {
 	return fmt.Sprintf("FATAL: %s", f.Message)
 }
 
+func (t *TransportError) Error() string {
+	return fmt.Sprintf("failed to create %s: %v", t.Transport, t.Err)
+}
+
 func (c *CommandCompleted) Error() string {
 	return c.Message
 }
@@ -10,6 +10,14 @@ type FatalError struct {
 	Message string
 }
 
+// TransportError reports a transport endpoint that could not be created, e.g.
+// because its port is taken. Unless transport.fail_fast is set the engine skips
+// the endpoint and continues with the remaining ones.
+type TransportError struct {
+	Err       error
+	Transport string
+}
+
 type CommandCompleted struct {
 	Err     error
 	Message string
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+// NewBandOnsetDetector returns a detector with one flux onset detector per band.
+func NewBandOnsetDetector(bands []OnsetBand, sampleRate float64, framesPerBuffer int) *BandOnsetDetector {
+	d := &BandOnsetDetector{
+		bands:     append([]OnsetBand(nil), bands...),
+		detectors: make([]*BPMDetector, len(bands)),
+		onsets:    make([]BandOnset, len(bands)),
+		lastOnset: make([]uint64, len(bands)),
+		flux:      make([]float64, 1),
+	}
+	for i, band := range bands {
+		// Each detector sees the band's summed flux as a single bin, and only
+		// detects onsets, the tempo is estimated by the main detector.
+		d.detectors[i] = NewBPMDetector(sampleRate, framesPerBuffer)
+		d.detectors[i].SetFluxBand(0, 1)
+		d.detectors[i].SetOnsetOnly(true)
+		d.onsets[i] = BandOnset{Name: band.Name, FramesSinceOnset: -1}
+	}
+	return d
+}
+
+// Configure applies the onset parameters of cfg to every band, see
+// BPMDetector.Configure.
+func (d *BandOnsetDetector) Configure(cfg BPMConfig) {
+	for _, detector := range d.detectors {
+		detector.Configure(cfg)
+	}
+}
+
+// Reset clears the onset history of every band, e.g. when the main detector is
+// reset because the input jumped. It must not be called concurrently with
+// Process.
+func (d *BandOnsetDetector) Reset() {
+	for i, detector := range d.detectors {
+		detector.Reset()
+		d.onsets[i] = BandOnset{Name: d.bands[i].Name, FramesSinceOnset: -1}
+		d.lastOnset[i] = 0
+	}
+}
+
+// Process runs every band's onset detector on the spectral flux of the last
+// frame analyzed by p, see FFTProcessor.GetSpectralFluxInRange. The returned
+// slice is reused by the next call.
+func (d *BandOnsetDetector) Process(p *FFTProcessor, frameCount uint64) []BandOnset {
+	for i, band := range d.bands {
+		d.flux[0] = p.GetSpectralFluxInRange(band.Low, band.High)
+		detector := d.detectors[i]
+		detector.ProcessFlux(d.flux, frameCount)
+
+		onset := &d.onsets[i]
+		onset.FramesSinceOnset = detector.FramesSinceOnset()
+		onset.Strength = detector.OnsetStrength()
+		onset.Onset = false
+		if onset.FramesSinceOnset >= 0 {
+			// With a post window the onset frame lies in the past, so a new
+			// onset is a change of frame rather than FramesSinceOnset of 0.
+			frame := frameCount - uint64(onset.FramesSinceOnset)
+			onset.Onset = frame != d.lastOnset[i]
+			d.lastOnset[i] = frame
+		}
+	}
+	return d.onsets
+}
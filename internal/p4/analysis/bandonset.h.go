@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+// OnsetBand is a frequency range in Hz with its own onset detector, see
+// BandOnsetDetector.
+type OnsetBand struct {
+	Name string
+	Low  float64
+	High float64
+}
+
+// BandOnset is the onset state of one band after a frame.
+type BandOnset struct {
+	Name             string
+	FramesSinceOnset int64   // Buffers since the band's last onset, -1 if none yet.
+	Strength         float64 // Last onset relative to the band's recent onsets, 0 if none yet.
+	Onset            bool    // An onset was detected in this frame.
+}
+
+// BandOnsetDetector runs the flux onset detector of BPMDetector independently
+// in several frequency bands, so e.g. the kick and the hi-hat trigger separately.
+type BandOnsetDetector struct {
+	bands     []OnsetBand
+	detectors []*BPMDetector
+	onsets    []BandOnset
+	lastOnset []uint64 // Frame of each band's last reported onset.
+	flux      []float64
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandOnsetDetector_Process(t *testing.T) {
+	// 256-point FFT at 25.6kHz gives 100 Hz/bin.
+	const sampleRate = 25600.0
+	const size = 256
+
+	p, err := NewFFTProcessor(size, sampleRate, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	d := NewBandOnsetDetector([]OnsetBand{
+		{Name: "low", Low: 0, High: 500},
+		{Name: "high", Low: 3000, High: 6000},
+	}, sampleRate, size)
+
+	silence := make([]int32, size)
+	tone := func(freq float64) []int32 {
+		input := make([]int32, size)
+		for i := range input {
+			input[i] = int32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/sampleRate) * math.MaxInt32)
+		}
+		return input
+	}
+
+	var frame uint64
+	process := func(input []int32) []BandOnset {
+		frame++
+		p.Process(input)
+		return d.Process(p, frame)
+	}
+
+	// Fill the detectors' statistics windows.
+	for range 30 {
+		onsets := process(silence)
+		require.Len(t, onsets, 2)
+		assert.Equal(t, int64(-1), onsets[0].FramesSinceOnset, "No onset should be detected in silence")
+		assert.Equal(t, int64(-1), onsets[1].FramesSinceOnset, "No onset should be detected in silence")
+	}
+
+	onsets := process(tone(4000))
+	assert.Equal(t, "low", onsets[0].Name)
+	assert.False(t, onsets[0].Onset, "A high tone should not trigger the low band")
+	assert.Equal(t, "high", onsets[1].Name)
+	assert.True(t, onsets[1].Onset, "A high tone should trigger the high band")
+	assert.Equal(t, int64(0), onsets[1].FramesSinceOnset)
+
+	onsets = process(tone(4000))
+	assert.False(t, onsets[1].Onset, "A sustained tone should only trigger once")
+	assert.Equal(t, int64(1), onsets[1].FramesSinceOnset)
+
+	for range 30 {
+		process(silence)
+	}
+	onsets = process(tone(200))
+	assert.True(t, onsets[0].Onset, "A low tone should trigger the low band")
+	assert.False(t, onsets[1].Onset, "A low tone should not trigger the high band")
+}
+
+func TestBandOnsetDetector_OnsetOnlyAndReset(t *testing.T) {
+	const sampleRate = 25600.0
+	const size = 256
+
+	p, err := NewFFTProcessor(size, sampleRate, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	d := NewBandOnsetDetector([]OnsetBand{{Name: "all", Low: 0, High: sampleRate / 2}}, sampleRate, size)
+
+	hit := make([]int32, size)
+	for i := range hit {
+		hit[i] = int32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/sampleRate) * math.MaxInt32)
+	}
+	silence := make([]int32, size)
+
+	// A hit every 50 buffers, 120 BPM at 100 buffers per second.
+	var onsets int
+	for frame := uint64(1); frame <= 1000; frame++ {
+		input := silence
+		if frame%50 == 0 {
+			input = hit
+		}
+		p.Process(input)
+		if d.Process(p, frame)[0].Onset {
+			onsets++
+		}
+	}
+	assert.Greater(t, onsets, 10, "The hits should be detected as onsets")
+	bpm, _ := d.detectors[0].GetBPM()
+	assert.Zero(t, bpm, "Band detectors should not estimate a tempo")
+
+	d.Reset()
+	assert.Equal(t, int64(-1), d.detectors[0].FramesSinceOnset(), "Reset should clear the onset history")
+	assert.Equal(t, BandOnset{Name: "all", FramesSinceOnset: -1}, d.onsets[0])
+}
@@ -5,25 +5,86 @@ import (
 	"math"
 	"phase4/pkg/simd"
 	"sort"
+	"time"
+)
+
+const (
+	// Tempo lock: confidence must stay at or above lockConfidence for
+	// lockDuration seconds before IsLocked reports true.
+	lockConfidence = 0.5
+	lockDuration   = 2.0
+
+	// defaultHistogramResolution is the inter-onset interval bin width in
+	// seconds, see SetHistogramResolution.
+	defaultHistogramResolution = 0.005
+
+	// defaultFluxBins is the number of low flux bins summed into the onset
+	// envelope until SetFluxBand is called.
+	defaultFluxBins = 10
+
+	// minOnsetInterval is the default shortest time in seconds between two
+	// onsets, closer detections are treated as double triggers. A tempo-derived
+	// interval (SetOnsetInterval) is never shorter than minBeatOnsetInterval,
+	// and onsetTimesSize, the capacity of the onset time buffer, holds
+	// MaxOnsetMemory even at that rate.
+	minOnsetInterval     = 0.1
+	minBeatOnsetInterval = 0.05
+	onsetTimesSize       = 2048
+
+	// defaultOnsetMemory is how long onsets are kept for tempo estimation, in
+	// seconds, see SetOnsetMemory.
+	defaultOnsetMemory = 10.0
+
+	// MaxOnsetMemory is the longest onset memory the onset time buffer can
+	// hold, at one onset every minBeatOnsetInterval.
+	MaxOnsetMemory = time.Duration(onsetTimesSize * minBeatOnsetInterval * float64(time.Second))
+
+	// onsetStatsWindow is the number of onset envelope values the adaptive
+	// threshold is computed over, and MaxPeakWindow the longest peak-picking
+	// window on either side of a candidate, see SetPeakPicking.
+	onsetStatsWindow = 20
+	MaxPeakWindow    = 10
+
+	// hintBonus is the score multiplier of candidates within the tempo hint,
+	// the largest of the genre range bonuses.
+	hintBonus = 1.4
+
+	// beatAlignTolerance is how far, in beats, an onset may be from the
+	// predicted beat to re-align the beat phase, see BeatPhase.
+	beatAlignTolerance = 0.25
+
+	// displayJumpRatio is the tempo change, as a ratio, at which the display
+	// tempo restarts its average instead of blending, e.g. a half tempo
+	// correction. Blending 60 and 120 BPM would show a tempo that was never
+	// detected.
+	displayJumpRatio = 1.5
 )
 
 func NewBPMDetector(sampleRate float64, framesPerBuffer int) *BPMDetector {
 	const (
 		onsetBufferSize  = 1024
-		onsetTimesSize   = 1024
 		recentWindowSize = 20
 	)
 
+	framesPerSecond := sampleRate / float64(framesPerBuffer)
+
 	return &BPMDetector{
 		sampleRate:       sampleRate,
 		framesPerBuffer:  framesPerBuffer,
+		lockFrames:       uint64(math.Ceil(lockDuration * framesPerSecond)),
+		confidenceAlpha:  1,
+		binWidth:         defaultHistogramResolution,
+		onsetMemory:      defaultOnsetMemory,
+		fluxHi:           defaultFluxBins,
 		onsetThreshold:   0.1,
 		onsetBuffer:      simd.AlignedFloat64(onsetBufferSize),
 		onsetTimes:       simd.AlignedFloat64(onsetTimesSize),
+		onsetFluxes:      simd.AlignedFloat64(onsetTimesSize),
 		recentBuffer:     simd.AlignedFloat64(recentWindowSize),
 		validOnsets:      simd.AlignedFloat64(onsetTimesSize),
+		validFluxes:      simd.AlignedFloat64(onsetTimesSize),
 		intervals:        simd.AlignedFloat64(onsetTimesSize),
-		histogramBins:    make(map[int]int),
+		histogramBins:    make(map[int]binCount),
 		onsetBufferLen:   0,
 		onsetTimesLen:    0,
 		binCounts:        make([]binCount, 0, 100),
@@ -32,52 +93,71 @@ func NewBPMDetector(sampleRate float64, framesPerBuffer int) *BPMDetector {
 	}
 }
 
+// ProcessFFT runs ProcessFlux on the spectral flux of the last frame analyzed by
+// p. The flux is copied into a scratch buffer, so frames are not allocated.
+func (bd *BPMDetector) ProcessFFT(p *FFTProcessor, frameCount uint64) {
+	bins := len(p.frequencyBins)
+	flux := getScratch(bins)
+	*flux = p.spectralFlux.AppendTo((*flux)[:0], 0, bins)
+	bd.ProcessFlux(*flux, frameCount)
+	putScratch(flux)
+}
+
 // ProcessFlux analyzes spectral flux for onset detection and BPM calculation
 func (bd *BPMDetector) ProcessFlux(flux []float64, frameCount uint64) {
-	// Calculate total flux and peak flux from the first 10 bins, this helps
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	// Calculate total flux and peak flux from the onset band only, this helps
 	// reduce noise and emphasizes the most significant spectral changes.
-	// Optimize by limiting loop and bounds check.
+	lo, hi := min(bd.fluxLo, len(flux)), min(bd.fluxHi, len(flux))
 	totalFlux, peakFlux := 0.0, 0.0
-	for i, v := range flux {
-		if i >= 10 {
-			break
-		}
+	for _, v := range flux[lo:hi] {
 		totalFlux += v
 		if v > peakFlux {
 			peakFlux = v
 		}
 	}
 
-	bd.mu.Lock()
-	defer bd.mu.Unlock()
+	bd.lastFrame = frameCount
+	defer bd.recordStats()
+	defer bd.updateDisplayBPM()
+	defer bd.updateLock(frameCount)
+	defer bd.smoothConfidence()
 
-	// Update recent buffer with the latest flux value
+	// A NaN or Inf would poison the onset statistics for the whole window and
+	// from there the tempo. The frame is treated as silence rather than dropped,
+	// so the onset envelope stays aligned with the frame count.
+	if !isFinite(totalFlux) {
+		totalFlux = 0
+	}
+
+	// Update recent buffer with the latest flux value. Once full it is a ring,
+	// the oldest value is overwritten instead of shifting the whole buffer.
 	if bd.onsetBufferLen < len(bd.onsetBuffer) {
-		bd.onsetBuffer[bd.onsetBufferLen] = totalFlux
+		bd.onsetBuffer[bd.onsetIndex(bd.onsetBufferLen)] = totalFlux
 		bd.onsetBufferLen++
 	} else {
-		// If buffer is full, shift left by one and add new value, this keeps
-		// the buffer size constant and allows for continuous analysis.
-		copy(bd.onsetBuffer, bd.onsetBuffer[1:])
-		bd.onsetBuffer[bd.onsetBufferLen-1] = totalFlux
+		bd.onsetBuffer[bd.onsetHead] = totalFlux
+		bd.onsetHead = bd.onsetIndex(1)
 	}
 
-	if bd.onsetBufferLen > 20 {
+	if bd.onsetBufferLen > onsetStatsWindow {
 		// Use a fixed window size for statistics, this can be tuned
 		// to adapt to different music styles, e.g. 20 for breakbeats.
-		windowSize := 20
+		windowSize := onsetStatsWindow
 
 		// Calculate mean and standard deviation of the last `windowSize` values
 
 		mean := 0.0
 		for i := 0; i < windowSize; i++ {
-			mean += bd.onsetBuffer[bd.onsetBufferLen-windowSize+i]
+			mean += bd.onsetAt(bd.onsetBufferLen - windowSize + i)
 		}
 		mean /= float64(windowSize)
 
 		variance := 0.0
 		for i := 0; i < windowSize; i++ {
-			diff := bd.onsetBuffer[bd.onsetBufferLen-windowSize+i] - mean
+			diff := bd.onsetAt(bd.onsetBufferLen-windowSize+i) - mean
 			variance += diff * diff
 		}
 		stdDev := math.Sqrt(variance / float64(windowSize))
@@ -85,49 +165,140 @@ func (bd *BPMDetector) ProcessFlux(flux []float64, frameCount uint64) {
 		// Dynamic threshold based on statistics.
 		threshold := max(mean+1.5*stdDev, bd.onsetThreshold)
 
-		// Check if current flux is a peak.
-		current := bd.onsetBuffer[bd.onsetBufferLen-1]
-		previous := bd.onsetBuffer[bd.onsetBufferLen-2]
+		// The candidate is the latest value, or with a post window the value
+		// postWindow frames back, so the values after it are already known. The
+		// threshold above includes them, which delays the moving average too.
+		candidate := bd.onsetBufferLen - 1 - bd.postWindow
+		current := bd.onsetAt(candidate)
+		onsetFrame := frameCount - uint64(bd.postWindow)
 
-		// Peak detection: current > threshold AND current > previous.
-		if current > threshold && current > previous*1.3 {
-			timeInSeconds := float64(frameCount) * float64(bd.framesPerBuffer) / bd.sampleRate
+		// Peak detection: current > threshold AND current is a peak.
+		if current > threshold && bd.isPeak(candidate) {
+			timeInSeconds := float64(onsetFrame) * float64(bd.framesPerBuffer) / bd.sampleRate
 
-			// Prevent double-triggers (minimum 100ms between onsets).
-			if bd.onsetTimesLen == 0 || timeInSeconds-bd.onsetTimes[bd.onsetTimesLen-1] > 0.1 {
+			// Prevent double-triggers (minimum 100ms between onsets by default).
+			if bd.onsetTimesLen == 0 || timeInSeconds-bd.onsetTimes[bd.onsetTimesLen-1] > bd.minOnsetInterval() {
+				bd.lastOnsetFrame = onsetFrame
+				bd.hasOnset = true
 				if bd.onsetTimesLen < len(bd.onsetTimes) {
 					bd.onsetTimes[bd.onsetTimesLen] = timeInSeconds
+					bd.onsetFluxes[bd.onsetTimesLen] = current
 					bd.onsetTimesLen++
 				} else {
 					// Edge: onset times buffer is full, shift left by one.
 					copy(bd.onsetTimes, bd.onsetTimes[1:])
+					copy(bd.onsetFluxes, bd.onsetFluxes[1:])
 					bd.onsetTimes[bd.onsetTimesLen-1] = timeInSeconds
+					bd.onsetFluxes[bd.onsetTimesLen-1] = current
 				}
 
-				// Keep only recent onsets (last 10 seconds)
+				// Keep only recent onsets (the onset memory, 10 seconds by default)
 				validCount := 0
-				cutoffTime := timeInSeconds - 10.0
+				cutoffTime := timeInSeconds - bd.onsetMemory
 
 				for i := 0; i < bd.onsetTimesLen; i++ {
 					if bd.onsetTimes[i] > cutoffTime {
 						bd.validOnsets[validCount] = bd.onsetTimes[i]
+						bd.validFluxes[validCount] = bd.onsetFluxes[i]
 						validCount++
 					}
 				}
 
 				if validCount < bd.onsetTimesLen {
 					copy(bd.onsetTimes, bd.validOnsets[:validCount]) // Update the onsetTimes buffer.
+					copy(bd.onsetFluxes, bd.validFluxes[:validCount])
 					bd.onsetTimesLen = validCount
 				}
 
-				if bd.onsetTimesLen >= 4 {
+				// The window's threshold scales with the flux itself, so a hit
+				// is only strong or weak relative to the other recent onsets.
+				meanFlux := 0.0
+				for i := 0; i < bd.onsetTimesLen; i++ {
+					meanFlux += bd.onsetFluxes[i]
+				}
+				meanFlux /= float64(bd.onsetTimesLen)
+				bd.lastOnsetStrength = current / meanFlux
+				if bd.onsetOnly {
+					return
+				}
+
+				switch {
+				case bd.fixedBPM > 0:
+					// A fixed tempo is not estimated, onsets only align the phase.
+				case bd.hasEstimate && timeInSeconds-bd.lastEstimate < bd.estimateInterval:
+					// Throttled, the onset is kept for the next estimate.
+				case bd.method == BPMAutocorrelation:
+					bd.calculateBPMAutocorrelation()
+					bd.lastEstimate, bd.hasEstimate = timeInSeconds, true
+				case bd.onsetTimesLen >= 4:
 					bd.calculateBPM()
+					bd.lastEstimate, bd.hasEstimate = timeInSeconds, true
 				}
+				bd.alignBeat(timeInSeconds)
 			}
 		}
 	}
 }
 
+// rangeBonus returns the score multiplier of a candidate tempo. With a tempo
+// hint only the hinted range is preferred, otherwise typical genre ranges are.
+func (bd *BPMDetector) rangeBonus(bpm float64) float64 {
+	if bd.hintHi > 0 {
+		if bpm >= bd.hintLo && bpm <= bd.hintHi {
+			return hintBonus
+		}
+		return 1.0
+	}
+
+	// Prefer certain BPM ranges for breakbeats (90-110).
+	if bpm >= 90 && bpm <= 110 {
+		return 1.3 // 30% bonus for breakbeat range.
+	} else if bpm >= 160 && bpm <= 180 {
+		return 1.4 // 40% bonus for drum & bass range.
+	} else if bpm >= 120 && bpm <= 140 {
+		return 1.2 // 20% bonus for house/techno range.
+	}
+	return 1.0
+}
+
+// isPeak reports whether the onset envelope value at i is a peak. Without a
+// peak-picking window it must rise 30% above the previous value. Otherwise it
+// must exceed the preWindow values before it and be at least the postWindow
+// values after it, so the first frame of a plateau is the peak.
+func (bd *BPMDetector) isPeak(i int) bool {
+	current := bd.onsetAt(i)
+	if bd.preWindow == 0 && bd.postWindow == 0 {
+		return current > bd.onsetAt(i-1)*1.3
+	}
+
+	for j := max(i-bd.preWindow, 0); j < i; j++ {
+		if bd.onsetAt(j) >= current {
+			return false
+		}
+	}
+	for j := i + 1; j <= i+bd.postWindow; j++ {
+		if bd.onsetAt(j) > current {
+			return false
+		}
+	}
+	return true
+}
+
+// onsetIndex returns the position in onsetBuffer of the i-th oldest envelope
+// value, onsetBuffer is a ring starting at onsetHead.
+func (bd *BPMDetector) onsetIndex(i int) int {
+	i += bd.onsetHead
+	if i >= len(bd.onsetBuffer) {
+		i -= len(bd.onsetBuffer)
+	}
+	return i
+}
+
+// onsetAt returns the i-th oldest onset envelope value, 0 <= i < onsetBufferLen.
+func (bd *BPMDetector) onsetAt(i int) float64 {
+	return bd.onsetBuffer[bd.onsetIndex(i)]
+}
+
 func (bd *BPMDetector) calculateBPM() {
 	if bd.onsetTimesLen < 4 {
 		return
@@ -152,16 +323,22 @@ func (bd *BPMDetector) calculateBPM() {
 		delete(bd.histogramBins, k)
 	}
 
-	// Initialize histogram bins for 0.5 BPM resolution (200 bins for 0-100 BPM).
+	// Bin the intervals by the configured width, 5ms by default which is about
+	// 1.2 BPM at 120 BPM. The sums keep the tempo of a bin from being biased
+	// towards its lower edge.
 	for i := 0; i < intervalCount; i++ {
-		bin := int(bd.intervals[i] * 200)
-		bd.histogramBins[bin]++
+		bin := int(bd.intervals[i] / bd.binWidth)
+		b := bd.histogramBins[bin]
+		b.bin = bin
+		b.count++
+		b.sum += bd.intervals[i]
+		bd.histogramBins[bin] = b
 	}
 
 	// Reset binCounts slice to reuse memory.
 	bd.binCounts = bd.binCounts[:0]
-	for bin, count := range bd.histogramBins {
-		bd.binCounts = append(bd.binCounts, binCount{bin: bin, count: count})
+	for _, b := range bd.histogramBins {
+		bd.binCounts = append(bd.binCounts, b)
 	}
 
 	// Sort binCounts by count in descending order to find the most common intervals.
@@ -174,12 +351,20 @@ func (bd *BPMDetector) calculateBPM() {
 	maxBins := min(len(bd.binCounts), 3) // Top 3 most common intervals.
 
 	for i := 0; i < maxBins; i++ {
-		interval := float64(bd.binCounts[i].bin) / 200.0
+		// The mean interval of the bin, rather than its lower edge.
+		interval := bd.binCounts[i].sum / float64(bd.binCounts[i].count)
 		if interval > 0 {
 			// Add the base tempo and related tempos.
 			baseBPM := 60.0 / interval
 			bd.bpmCandidates = append(bd.bpmCandidates, baseBPM)
 
+			// With a tempo hint the genre rules below do not apply, half and
+			// double are always candidates and the hint picks between them.
+			if bd.hintHi > 0 {
+				bd.bpmCandidates = append(bd.bpmCandidates, baseBPM/2, baseBPM*2)
+				continue
+			}
+
 			// For dance music, half-tempo often works better - but not for drum & bass range.
 			if baseBPM > 130 && (baseBPM < 160 || baseBPM > 180) {
 				bd.bpmCandidates = append(bd.bpmCandidates, baseBPM/2)
@@ -260,15 +445,7 @@ func (bd *BPMDetector) calculateBPM() {
 			alignmentScore /= totalWeight
 		}
 
-		// Prefer certain BPM ranges for breakbeats (90-110).
-		rangeBonus := 1.0
-		if candidateBPM >= 90 && candidateBPM <= 110 {
-			rangeBonus = 1.3 // 30% bonus for breakbeat range.
-		} else if candidateBPM >= 160 && candidateBPM <= 180 {
-			rangeBonus = 1.4 // 40% bonus for drum & bass range.
-		} else if candidateBPM >= 120 && candidateBPM <= 140 {
-			rangeBonus = 1.2 // 20% bonus for house/techno range.
-		}
+		rangeBonus := bd.rangeBonus(candidateBPM)
 
 		// Apply hysteresis bonus for stability.
 		stabilityBonus := 1.0
@@ -327,8 +504,7 @@ func (bd *BPMDetector) calculateBPM() {
 		confidenceScore := math.Max(0.1, math.Min(1.0, 1.0/(1.0+stdDev/avgInterval*5)))
 
 		// If we have a strong confidence, update the BPM.
-		bd.currentBPM = bestCandidate.bpm
-		bd.confidence = confidenceScore * bestCandidate.score
+		bd.setTempo(bestCandidate.bpm, confidenceScore*bestCandidate.score, bd.histogramPeakConfidence())
 	}
 
 	// Log with more precision
@@ -336,12 +512,524 @@ func (bd *BPMDetector) calculateBPM() {
 	// 	bd.currentBPM, bd.confidence, bd.onsetTimesLen, bd.intervals[:intervalCount])
 }
 
+// histogramPeakConfidence returns how dominant the top interval bin is over the
+// rest of the histogram, the peak-to-mean ratio of the bin counts mapped to
+// [0, 1]: 0 for a flat histogram, 1 when all intervals fall in one bin. It must
+// be called after bd.binCounts has been sorted. The caller must hold bd.mu.
+func (bd *BPMDetector) histogramPeakConfidence() float64 {
+	bins := len(bd.binCounts)
+	if bins == 0 {
+		return 0
+	}
+	if bins == 1 {
+		return 1
+	}
+
+	total := 0
+	for _, bc := range bd.binCounts {
+		total += bc.count
+	}
+	peakToMean := float64(bd.binCounts[0].count) * float64(bins) / float64(total)
+	return (peakToMean - 1) / float64(bins-1)
+}
+
+// setTempo updates the tempo estimate, keeping the previous one if bpm is not a
+// finite positive value. Both confidence definitions are kept, the configured
+// metric is reported as the confidence. Confidences are clamped to [0, 1], a
+// non-finite confidence counts as 0. The caller must hold bd.mu.
+func (bd *BPMDetector) setTempo(bpm, variationConfidence, peakConfidence float64) {
+	if !isFinite(bpm) || bpm <= 0 {
+		return
+	}
+	bd.currentBPM = bpm
+	bd.variationConfidence = clampConfidence(variationConfidence)
+	bd.peakConfidence = clampConfidence(peakConfidence)
+	bd.confidence = bd.variationConfidence
+	if bd.confidenceMetric == ConfidencePeak {
+		bd.confidence = bd.peakConfidence
+	}
+}
+
+func clampConfidence(confidence float64) float64 {
+	if !isFinite(confidence) {
+		return 0
+	}
+	return math.Max(0, math.Min(1, confidence))
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// Reset discards all onset history and the current tempo estimate, e.g. when
+// the input jumps back to the start of a file. Configuration is kept.
+func (bd *BPMDetector) Reset() {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.onsetBufferLen = 0
+	bd.onsetHead = 0
+	bd.onsetTimesLen = 0
+	bd.currentBPM = 0
+	bd.confidence = 0
+	bd.variationConfidence = 0
+	bd.peakConfidence = 0
+	bd.smoothedConfidence = 0
+	bd.hasOnset = false
+	bd.lastOnsetFrame = 0
+	bd.lastOnsetStrength = 0
+	bd.lockStartFrame = 0
+	bd.locked = false
+	bd.hasBeat = false
+	bd.beatIndex = 0
+	bd.hasEstimate = false
+	bd.displayBPM = 0
+	bd.displayHead, bd.displayLen, bd.displaySum = 0, 0, 0
+	if bd.fixedBPM > 0 {
+		bd.setTempo(bd.fixedBPM, 1, 1)
+	}
+	if bd.stats != nil {
+		bd.stats.lastBPM = 0 // A new estimate is not a correction.
+	}
+}
+
 func (bd *BPMDetector) GetBPM() (bpm float64, confidence float64) {
 	bd.mu.RLock()
 	defer bd.mu.RUnlock()
 	return bd.currentBPM, bd.confidence
 }
 
+// updateLock tracks how long the confidence has been at or above the lock
+// threshold. The caller must hold bd.mu.
+func (bd *BPMDetector) updateLock(frameCount uint64) {
+	if bd.currentBPM == 0 || bd.confidence < lockConfidence {
+		bd.lockStartFrame = 0
+		bd.locked = false
+		return
+	}
+	if bd.lockStartFrame == 0 {
+		bd.lockStartFrame = frameCount
+	}
+	bd.locked = frameCount-bd.lockStartFrame >= bd.lockFrames
+}
+
+// recordStats adds the frame to the session statistics, if enabled. The caller
+// must hold bd.mu.
+func (bd *BPMDetector) recordStats() {
+	if bd.stats != nil {
+		bd.stats.add(bd.currentBPM, bd.locked)
+	}
+}
+
+// EnableStats starts accumulating session statistics of the reported tempo,
+// see Stats. Statistics are kept across Reset.
+func (bd *BPMDetector) EnableStats() {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	if bd.stats == nil {
+		bd.stats = newBPMStats()
+	}
+}
+
+// Stats returns the session statistics of the reported tempo, and false if
+// EnableStats has not been called.
+func (bd *BPMDetector) Stats() (BPMStats, bool) {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+
+	if bd.stats == nil {
+		return BPMStats{}, false
+	}
+	return bd.stats.summary(), true
+}
+
+// smoothConfidence advances the exponentially smoothed confidence by one frame.
+// The caller must hold bd.mu.
+func (bd *BPMDetector) smoothConfidence() {
+	bd.smoothedConfidence = flushDenormal(bd.smoothedConfidence + bd.confidenceAlpha*(bd.confidence-bd.smoothedConfidence))
+}
+
+// SetConfidenceSmoothing sets the time constant of the smoothed confidence
+// returned by GetSmoothedConfidence, a time constant of 0 disables smoothing.
+func (bd *BPMDetector) SetConfidenceSmoothing(timeConstant time.Duration) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	if timeConstant <= 0 {
+		bd.confidenceAlpha = 1
+		return
+	}
+	framePeriod := float64(bd.framesPerBuffer) / bd.sampleRate
+	bd.confidenceAlpha = 1 - math.Exp(-framePeriod/timeConstant.Seconds())
+}
+
+// updateDisplayBPM advances the display tempo by one frame, see
+// SetDisplayWindow. The caller must hold bd.mu.
+func (bd *BPMDetector) updateDisplayBPM() {
+	bpm := bd.currentBPM
+	if bpm <= 0 || (bd.displayBPM > 0 && (bpm > bd.displayBPM*displayJumpRatio || bpm*displayJumpRatio < bd.displayBPM)) {
+		bd.displayHead, bd.displayLen, bd.displaySum = 0, 0, 0
+	}
+	if bpm <= 0 || len(bd.displayWindow) == 0 {
+		bd.displayBPM = bpm
+		return
+	}
+
+	if bd.displayLen == len(bd.displayWindow) {
+		bd.displaySum -= bd.displayWindow[bd.displayHead]
+	} else {
+		bd.displayLen++
+	}
+	bd.displayWindow[bd.displayHead] = bpm
+	bd.displaySum += bpm
+	bd.displayHead = (bd.displayHead + 1) % len(bd.displayWindow)
+	bd.displayBPM = bd.displaySum / float64(bd.displayLen)
+}
+
+// SetDisplayWindow sets the length of the moving average returned by
+// GetDisplayBPM, a stable number for an on-screen readout where GetBPM follows
+// every new estimate. Jumps of more than half or double tempo restart the
+// average. A window of 0 makes the display tempo follow GetBPM.
+func (bd *BPMDetector) SetDisplayWindow(window time.Duration) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	framePeriod := float64(bd.framesPerBuffer) / bd.sampleRate
+	frames := int(math.Ceil(window.Seconds() / framePeriod))
+	bd.displayWindow = nil
+	if frames > 1 {
+		bd.displayWindow = make([]float64, frames)
+	}
+	bd.displayHead, bd.displayLen, bd.displaySum = 0, 0, 0
+}
+
+// GetDisplayBPM returns the tempo averaged over the display window, see
+// SetDisplayWindow, or 0 without a tempo.
+func (bd *BPMDetector) GetDisplayBPM() float64 {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+	return bd.displayBPM
+}
+
+// SetHistogramResolution sets the bin width used to cluster inter-onset
+// intervals in the histogram method, each bin is represented by the mean of its
+// intervals. Narrower bins keep close tempos apart, useful for beat matching
+// steady material, but spread jittery onsets over more bins so a clear winner
+// takes longer to emerge. Wider bins settle faster and hold steadier on loose or
+// noisy material, but average neighbouring tempos together. Candidates are
+// still rounded to 0.5 BPM. A width <= 0 restores the 5ms default.
+func (bd *BPMDetector) SetHistogramResolution(binWidth time.Duration) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	if binWidth <= 0 {
+		bd.binWidth = defaultHistogramResolution
+		return
+	}
+	bd.binWidth = binWidth.Seconds()
+}
+
+// SetOnsetMemory sets how long onsets are kept for tempo estimation. A shorter
+// memory follows tempo changes sooner, a longer one holds enough onsets for a
+// stable estimate on slow or sparse material. A memory <= 0 restores the 10s
+// default, one above MaxOnsetMemory is clamped to it.
+func (bd *BPMDetector) SetOnsetMemory(memory time.Duration) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.setOnsetMemory(memory)
+}
+
+// setOnsetMemory implements SetOnsetMemory, the caller must hold bd.mu.
+func (bd *BPMDetector) setOnsetMemory(memory time.Duration) {
+	if memory <= 0 {
+		bd.onsetMemory = defaultOnsetMemory
+		return
+	}
+	bd.onsetMemory = min(memory, MaxOnsetMemory).Seconds()
+}
+
+// SetEstimateInterval limits tempo estimation to once per interval of audio
+// time, bounding its cost on dense material where onsets arrive faster than the
+// tempo can change. Onsets within the interval are still recorded and used by
+// the next estimate. An interval <= 0 estimates on every onset.
+func (bd *BPMDetector) SetEstimateInterval(interval time.Duration) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.estimateInterval = max(interval.Seconds(), 0)
+}
+
+// SetPeakPicking replaces the causal rise test of onset detection with windowed
+// peak-picking: an onset must be the maximum of the pre frames before and the
+// post frames after it. A post window rejects rising noise that has not peaked
+// yet, at the cost of reporting every onset post frames late, see OnsetLatency.
+// Windows are clamped to [0, MaxPeakWindow], both 0 restores the rise test.
+func (bd *BPMDetector) SetPeakPicking(pre, post int) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.setPeakPicking(pre, post)
+}
+
+// setPeakPicking implements SetPeakPicking, the caller must hold bd.mu.
+func (bd *BPMDetector) setPeakPicking(pre, post int) {
+	bd.preWindow = min(max(pre, 0), MaxPeakWindow)
+	bd.postWindow = min(max(post, 0), MaxPeakWindow)
+}
+
+// OnsetLatency returns how long after an onset it is detected due to the
+// peak-picking post window. Onset times and FramesSinceOnset already account for
+// it.
+func (bd *BPMDetector) OnsetLatency() time.Duration {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+
+	return time.Duration(float64(bd.postWindow) * float64(bd.framesPerBuffer) / bd.sampleRate * float64(time.Second))
+}
+
+// SetTempoHint limits the preferred tempo range to [lo, hi] BPM, replacing
+// the built-in genre ranges, e.g. 120-140 for an installation that only plays
+// house. Half and double tempo candidates are always considered so the hint
+// can correct octave errors. An invalid range, e.g. 0, 0, removes the hint.
+func (bd *BPMDetector) SetTempoHint(lo, hi float64) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.setTempoHint(lo, hi)
+}
+
+// setTempoHint implements SetTempoHint, the caller must hold bd.mu.
+func (bd *BPMDetector) setTempoHint(lo, hi float64) {
+	if lo <= 0 || hi < lo {
+		bd.hintLo, bd.hintHi = 0, 0
+		return
+	}
+	bd.hintLo, bd.hintHi = lo, hi
+}
+
+// SetOnsetInterval derives the minimum time between two onsets from the tempo:
+// once it is locked, detections closer than fraction of a beat are treated as
+// double triggers, e.g. 0.25 admits sixteenth notes but rejects flams. Before
+// the lock, and with a fraction <= 0, the fixed 100ms interval applies. The
+// interval never drops below 50ms, the rate the onset history is sized for.
+func (bd *BPMDetector) SetOnsetInterval(fraction float64) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.onsetFraction = max(fraction, 0)
+}
+
+// minOnsetInterval returns the shortest time in seconds between two onsets, see
+// SetOnsetInterval.
+func (bd *BPMDetector) minOnsetInterval() float64 {
+	if bd.onsetFraction > 0 && bd.locked && bd.currentBPM > 0 {
+		return max(bd.onsetFraction*60/bd.currentBPM, minBeatOnsetInterval)
+	}
+	return minOnsetInterval
+}
+
+// SetOnsetOnly disables tempo estimation and beat tracking, onsets are still
+// detected and reported. It suits detectors that only trigger, such as the
+// bands of BandOnsetDetector.
+func (bd *BPMDetector) SetOnsetOnly(enabled bool) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.onsetOnly = enabled
+}
+
+// SetFixedTempo stops tempo estimation and reports bpm with full confidence,
+// e.g. when the tempo comes from a DJ controller or a fixed-tempo set. Onsets
+// are still detected and align the beat phase. A bpm <= 0 resumes estimation
+// from the next onset.
+func (bd *BPMDetector) SetFixedTempo(bpm float64) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	if bpm <= 0 || !isFinite(bpm) {
+		bd.fixedBPM = 0
+		return
+	}
+	bd.fixedBPM = bpm
+	bd.setTempo(bpm, 1, 1)
+}
+
+// FixedTempo returns the tempo set by SetFixedTempo, or 0 if the tempo is
+// estimated.
+func (bd *BPMDetector) FixedTempo() float64 {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+	return bd.fixedBPM
+}
+
+// alignBeat re-anchors the beat phase to an onset at time t (seconds) that
+// falls within beatAlignTolerance of a predicted beat, so off-beat hits do not
+// shift it. The first onset anchors the phase. The caller must hold bd.mu.
+func (bd *BPMDetector) alignBeat(t float64) {
+	if !bd.hasBeat || bd.currentBPM <= 0 {
+		bd.beatAnchor, bd.beatIndex, bd.hasBeat = t, 0, true
+		return
+	}
+	beats := (t - bd.beatAnchor) * bd.currentBPM / 60
+	if math.Abs(beats-math.Round(beats)) <= beatAlignTolerance {
+		bd.beatAnchor = t
+		bd.beatIndex += math.Round(beats)
+	}
+}
+
+// BeatPhase returns the position within the current beat in [0, 1), 0 on the
+// beat, as of the last processed frame. The phase follows the onsets closest to
+// the beat grid of the current tempo. It is -1 without a tempo or onset.
+func (bd *BPMDetector) BeatPhase() float64 {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+
+	beats, ok := bd.beatPosition()
+	if !ok {
+		return -1
+	}
+	return beats - math.Floor(beats)
+}
+
+// BeatPosition returns the number of beats since the first beat the phase was
+// aligned to, as of the last processed frame. Its fraction is BeatPhase, the
+// whole beats count bars for LFOs longer than a beat. Which beat starts a bar
+// is not detected, beat 0 is the first onset. It is -1 without a tempo or
+// onset.
+func (bd *BPMDetector) BeatPosition() float64 {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+
+	beats, ok := bd.beatPosition()
+	if !ok {
+		return -1
+	}
+	return beats
+}
+
+// beatPosition returns the beats since the first anchor and whether the beat
+// is known. The caller must hold bd.mu.
+func (bd *BPMDetector) beatPosition() (float64, bool) {
+	if !bd.hasBeat || bd.currentBPM <= 0 {
+		return 0, false
+	}
+	now := float64(bd.lastFrame) * float64(bd.framesPerBuffer) / bd.sampleRate
+	return bd.beatIndex + (now-bd.beatAnchor)*bd.currentBPM/60, true
+}
+
+// SetOnsetThreshold sets the minimum onset envelope value of an onset, the
+// adaptive threshold never drops below it. Raise it to ignore quiet passages
+// and background noise. A threshold < 0 is treated as 0.
+func (bd *BPMDetector) SetOnsetThreshold(threshold float64) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.onsetThreshold = max(threshold, 0)
+}
+
+// Config returns the parameters that can be changed with Configure.
+func (bd *BPMDetector) Config() BPMConfig {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+
+	return BPMConfig{
+		OnsetThreshold:     bd.onsetThreshold,
+		OnsetMemory:        time.Duration(bd.onsetMemory * float64(time.Second)),
+		OnsetPreWindow:     bd.preWindow,
+		OnsetPostWindow:    bd.postWindow,
+		OnsetIntervalBeats: bd.onsetFraction,
+		TempoHintLow:       bd.hintLo,
+		TempoHintHigh:      bd.hintHi,
+	}
+}
+
+// Configure applies cfg at once while onsets are being processed, e.g. to tune
+// for a venue without a restart. Each parameter is normalized like its setter.
+// Onset history and the current tempo are kept: the onset buffers are sized for
+// MaxOnsetMemory so a new memory only moves the cutoff, and onsets beyond it are
+// dropped at the next onset.
+func (bd *BPMDetector) Configure(cfg BPMConfig) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.onsetThreshold = max(cfg.OnsetThreshold, 0)
+	bd.setOnsetMemory(cfg.OnsetMemory)
+	bd.setPeakPicking(cfg.OnsetPreWindow, cfg.OnsetPostWindow)
+	bd.onsetFraction = max(cfg.OnsetIntervalBeats, 0)
+	bd.setTempoHint(cfg.TempoHintLow, cfg.TempoHintHigh)
+}
+
+// SetFluxBand sets the half-open range [lo, hi) of flux bins summed into the
+// onset envelope, usually obtained from FFTProcessor.BinRange so the band stays
+// the same in Hz across FFT sizes and sample rates. An empty range restores the
+// default of the first 10 bins.
+func (bd *BPMDetector) SetFluxBand(lo, hi int) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	if lo < 0 || hi <= lo {
+		bd.fluxLo, bd.fluxHi = 0, defaultFluxBins
+		return
+	}
+	bd.fluxLo, bd.fluxHi = lo, hi
+}
+
+// GetConfidences returns both confidence definitions of the current tempo,
+// whichever is selected by SetConfidenceMetric is also returned by GetBPM. With
+// the autocorrelation method both are the normalized autocorrelation.
+func (bd *BPMDetector) GetConfidences() (variation, peak float64) {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+	return bd.variationConfidence, bd.peakConfidence
+}
+
+// SetConfidenceMetric selects the confidence reported by GetBPM, and from there
+// the smoothed confidence and tempo lock.
+func (bd *BPMDetector) SetConfidenceMetric(metric ConfidenceMetric) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	bd.confidenceMetric = metric
+}
+
+// GetSmoothedConfidence returns the exponentially smoothed BPM confidence, which
+// changes gradually where the raw confidence from GetBPM jumps at every onset.
+func (bd *BPMDetector) GetSmoothedConfidence() float64 {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+	return bd.smoothedConfidence
+}
+
+// FramesSinceOnset returns the number of frames (buffers) since the last detected
+// onset, or -1 if no onset has been detected yet.
+func (bd *BPMDetector) FramesSinceOnset() int64 {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+	if !bd.hasOnset {
+		return -1
+	}
+	return int64(bd.lastFrame - bd.lastOnsetFrame)
+}
+
+// OnsetStrength returns the flux of the last detected onset relative to the
+// mean flux of the onsets within the onset memory, 1 is an average hit and 2 one
+// twice as strong. It is 0 if no onset has been detected yet.
+func (bd *BPMDetector) OnsetStrength() float64 {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+	return bd.lastOnsetStrength
+}
+
+// IsLocked reports whether the tempo estimate has held a confidence of at least
+// lockConfidence for lockDuration seconds. An unlocked tempo with regular onsets
+// points at genuinely ambiguous music rather than missed detections.
+func (bd *BPMDetector) IsLocked() bool {
+	bd.mu.RLock()
+	defer bd.mu.RUnlock()
+	return bd.locked
+}
+
 func (bd *BPMDetector) GetOnsetCount() int {
 	bd.mu.RLock()
 	defer bd.mu.RUnlock()
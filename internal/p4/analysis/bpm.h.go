@@ -3,9 +3,24 @@ package analysis
 
 import (
 	"sync"
+	"time"
 )
 
+// BPMConfig holds the BPMDetector parameters that can be changed while onsets
+// are processed, see BPMDetector.Configure. Each field has a setter with the
+// same meaning.
+type BPMConfig struct {
+	OnsetThreshold     float64       // See SetOnsetThreshold.
+	OnsetMemory        time.Duration // See SetOnsetMemory.
+	OnsetPreWindow     int           // See SetPeakPicking.
+	OnsetPostWindow    int
+	OnsetIntervalBeats float64 // See SetOnsetInterval.
+	TempoHintLow       float64 // See SetTempoHint.
+	TempoHintHigh      float64
+}
+
 type binCount struct {
+	sum   float64 // Of the intervals in the bin, their mean is its interval.
 	bin   int
 	count int
 }
@@ -16,21 +31,64 @@ type scoredBPM struct {
 }
 
 type BPMDetector struct {
-	histogramBins    map[int]int
-	validOnsets      []float64
-	scoredCandidates []scoredBPM
-	bpmCandidates    []float64
-	binCounts        []binCount
-	intervals        []float64
-	onsetBuffer      []float64
-	onsetTimes       []float64
-	recentBuffer     []float64
-	confidence       float64
-	onsetBufferLen   int
-	onsetTimesLen    int
-	sampleRate       float64
-	currentBPM       float64
-	onsetThreshold   float64
-	framesPerBuffer  int
-	mu               sync.RWMutex
+	histogramBins       map[int]binCount
+	validOnsets         []float64
+	validFluxes         []float64
+	scoredCandidates    []scoredBPM
+	bpmCandidates       []float64
+	binCounts           []binCount
+	intervals           []float64
+	onsetBuffer         []float64
+	onsetTimes          []float64
+	onsetFluxes         []float64 // Onset-band flux of each onset in onsetTimes.
+	recentBuffer        []float64
+	displayWindow       []float64 // Ring of per-frame tempos averaged into displayBPM.
+	stats               *bpmStats // Session statistics, nil unless EnableStats.
+	envelope            []float64
+	autocorr            []float64
+	confidence          float64 // Selected by confidenceMetric.
+	variationConfidence float64
+	peakConfidence      float64
+	lastOnsetStrength   float64
+	smoothedConfidence  float64
+	confidenceAlpha     float64 // Per-frame smoothing factor, 1 disables smoothing.
+	binWidth            float64 // Histogram interval bin width in seconds.
+	onsetMemory         float64 // Onsets older than this many seconds are dropped.
+	onsetFraction       float64 // Minimum onset interval in beats once locked, 0 for the fixed interval.
+	estimateInterval    float64 // Minimum seconds between tempo estimates, 0 estimates on every onset.
+	lastEstimate        float64 // Time in seconds of the onset the last estimate was made at.
+	hintLo              float64 // Preferred tempo range in BPM, hintHi is 0 without a hint.
+	hintHi              float64
+	displayBPM          float64
+	displaySum          float64
+	displayHead         int
+	displayLen          int
+	fixedBPM            float64 // Tempo set by SetFixedTempo, 0 while estimating.
+	beatAnchor          float64 // Time in seconds of the onset the beat phase is aligned to.
+	beatIndex           float64 // Whole beats from the first beatAnchor to the current one.
+	fluxLo              int     // Onset band, flux bins [fluxLo, fluxHi) are summed.
+	fluxHi              int
+	preWindow           int // Peak-picking windows in frames, see SetPeakPicking.
+	postWindow          int
+	onsetBufferLen      int
+	onsetHead           int // Index of the oldest value in onsetBuffer.
+	onsetTimesLen       int
+	sampleRate          float64
+	currentBPM          float64
+	onsetThreshold      float64
+	framesPerBuffer     int
+	lastFrame           uint64
+	lastOnsetFrame      uint64
+	lockStartFrame      uint64
+	lockFrames          uint64
+	minLag              int
+	maxLag              int
+	method              BPMMethod
+	confidenceMetric    ConfidenceMetric
+	mu                  sync.RWMutex
+	hasOnset            bool
+	hasBeat             bool // beatAnchor is set.
+	hasEstimate         bool // lastEstimate is set.
+	locked              bool
+	onsetOnly           bool // Tempo is not estimated, see SetOnsetOnly.
 }
@@ -0,0 +1,585 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBPMDetector_FramesSinceOnsetAndLock(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		beatFrames      = 43 // ~120 BPM at 86 buffers per second.
+	)
+
+	bd := NewBPMDetector(sampleRate, framesPerBuffer)
+	assert.Equal(t, int64(-1), bd.FramesSinceOnset(), "No onset should be reported before any input")
+	assert.False(t, bd.IsLocked(), "Tempo should not be locked before any input")
+
+	const lastBeat = 20 * beatFrames
+	flux := make([]float64, 1)
+	for frame := uint64(1); frame <= lastBeat; frame++ {
+		flux[0] = 0
+		if frame%beatFrames == 0 {
+			flux[0] = 1
+		}
+		bd.ProcessFlux(flux, frame)
+	}
+
+	// The last frame was an onset, five silent frames later the counter follows.
+	assert.Equal(t, int64(0), bd.FramesSinceOnset(), "Onset frame should reset the counter")
+	flux[0] = 0
+	for frame := uint64(lastBeat + 1); frame <= lastBeat+5; frame++ {
+		bd.ProcessFlux(flux, frame)
+	}
+	assert.Equal(t, int64(5), bd.FramesSinceOnset(), "Counter should advance with each frame")
+	assert.True(t, bd.IsLocked(), "A steady impulse train should lock the tempo")
+}
+
+func TestBPMDetector_SmoothedConfidence(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 441 // 10ms per frame.
+	)
+
+	bd := NewBPMDetector(sampleRate, framesPerBuffer)
+	bd.SetConfidenceSmoothing(100 * time.Millisecond)
+
+	// Step the raw confidence directly, one time constant (10 frames) of
+	// smoothing should cover 1 - 1/e of the step.
+	bd.mu.Lock()
+	bd.confidence = 1
+	for i := 0; i < 10; i++ {
+		bd.smoothConfidence()
+	}
+	bd.mu.Unlock()
+
+	assert.InDelta(t, 1-math.Exp(-1), bd.GetSmoothedConfidence(), 1e-9, "Smoothed confidence should follow the time constant")
+	_, raw := bd.GetBPM()
+	assert.Equal(t, 1.0, raw, "Raw confidence should be unaffected by smoothing")
+
+	bd.SetConfidenceSmoothing(0)
+	bd.mu.Lock()
+	bd.smoothConfidence()
+	bd.mu.Unlock()
+	assert.Equal(t, 1.0, bd.GetSmoothedConfidence(), "Without smoothing the raw confidence is passed through")
+}
+
+func TestBPMDetector_HistogramResolution(t *testing.T) {
+	bd := NewBPMDetector(44100, 256)
+	bd.SetHistogramResolution(time.Millisecond)
+	assert.Equal(t, 0.001, bd.binWidth)
+	bd.SetHistogramResolution(0)
+	assert.Equal(t, defaultHistogramResolution, bd.binWidth, "A zero width should fall back to the default")
+
+	// Onsets every 0.5025s are 119.4 BPM, which lies on the edge of a 5ms bin
+	// and inside a 1ms bin. Neither width should pull the tempo to an edge.
+	bpmAt := func(binWidth time.Duration) float64 {
+		bd := NewBPMDetector(44100, 256)
+		bd.SetHistogramResolution(binWidth)
+		for i := 0; i < 16; i++ {
+			bd.onsetTimes[i] = float64(i) * 0.5025
+		}
+		bd.onsetTimesLen = 16
+		bd.calculateBPM()
+		bpm, _ := bd.GetBPM()
+		return bpm
+	}
+
+	assert.Equal(t, 119.5, bpmAt(5*time.Millisecond), "The default resolution should not floor the interval to 0.5s")
+	assert.Equal(t, 119.5, bpmAt(time.Millisecond))
+}
+
+func TestBPMDetector_HistogramTempo(t *testing.T) {
+	// 128 BPM is a 0.46875s interval, 3.75ms into its 5ms bin.
+	bd := NewBPMDetector(44100, 256)
+	for i := 0; i < 16; i++ {
+		bd.onsetTimes[i] = float64(i) * 0.46875
+	}
+	bd.onsetTimesLen = 16
+	bd.calculateBPM()
+
+	top := bd.binCounts[0]
+	assert.InDelta(t, 0.46875, top.sum/float64(top.count), 1e-12, "The bin should report the mean of its intervals")
+	bpm, _ := bd.GetBPM()
+	assert.Equal(t, 128.0, bpm)
+}
+
+func TestBPMDetector_NonFiniteFlux(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		beatFrames      = 43 // ~120 BPM at 86 buffers per second.
+	)
+
+	for _, method := range []BPMMethod{BPMHistogram, BPMAutocorrelation} {
+		t.Run(method.String(), func(t *testing.T) {
+			bd := NewBPMDetector(sampleRate, framesPerBuffer)
+			bd.SetMethod(method)
+
+			// Silence must not produce a tempo, let alone a NaN one.
+			silence := make([]float64, 4)
+			for frame := uint64(1); frame <= 10*beatFrames; frame++ {
+				bd.ProcessFlux(silence, frame)
+			}
+			bpm, confidence := bd.GetBPM()
+			assert.Zero(t, bpm, "Silence should not produce a tempo")
+			assert.Zero(t, confidence, "Silence should not produce a confidence")
+
+			// A steady beat with NaN and Inf frames mixed in still yields a finite tempo.
+			flux := make([]float64, 4)
+			for frame := uint64(10*beatFrames + 1); frame <= 40*beatFrames; frame++ {
+				flux[0] = 0
+				switch {
+				case frame%beatFrames == 0:
+					flux[0] = 1
+				case frame%7 == 0:
+					flux[0] = math.NaN()
+				case frame%11 == 0:
+					flux[0] = math.Inf(1)
+				}
+				bd.ProcessFlux(flux, frame)
+			}
+			bpm, confidence = bd.GetBPM()
+			assert.InDelta(t, 120, bpm, 2, "Non-finite frames should not poison the tempo")
+			assert.False(t, math.IsNaN(confidence), "Confidence should stay finite")
+			assert.LessOrEqual(t, confidence, 1.0, "Confidence should be clamped to 1")
+			assert.False(t, math.IsNaN(bd.GetSmoothedConfidence()), "Smoothed confidence should stay finite")
+		})
+	}
+}
+
+func TestBPMDetector_FluxBand(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		beatFrames      = 43 // ~120 BPM at 86 buffers per second.
+	)
+
+	// Beats only in bin 12, which the default band of the first 10 bins misses.
+	bpmWithBand := func(lo, hi int) float64 {
+		bd := NewBPMDetector(sampleRate, framesPerBuffer)
+		bd.SetFluxBand(lo, hi)
+		flux := make([]float64, 16)
+		for frame := uint64(1); frame <= 20*beatFrames; frame++ {
+			flux[12] = 0
+			if frame%beatFrames == 0 {
+				flux[12] = 1
+			}
+			bd.ProcessFlux(flux, frame)
+		}
+		bpm, _ := bd.GetBPM()
+		return bpm
+	}
+
+	assert.Zero(t, bpmWithBand(0, 0), "An empty band should fall back to the first 10 bins")
+	assert.InDelta(t, 120, bpmWithBand(10, 14), 2, "Onsets inside the band should drive the tempo")
+	assert.InDelta(t, 120, bpmWithBand(10, 100), 2, "A band past the flux length should be clipped")
+}
+
+func TestBPMDetector_OnsetStrength(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		beatFrames      = 43 // ~120 BPM at 86 buffers per second.
+	)
+
+	bd := NewBPMDetector(sampleRate, framesPerBuffer)
+	assert.Zero(t, bd.OnsetStrength(), "No strength should be reported before the first onset")
+
+	// Eight even hits, then one twice as loud.
+	flux := make([]float64, 1)
+	for frame := uint64(1); frame <= 9*beatFrames; frame++ {
+		flux[0] = 0
+		if frame%beatFrames == 0 {
+			flux[0] = 1
+			if frame == 9*beatFrames {
+				flux[0] = 2
+			}
+		}
+		bd.ProcessFlux(flux, frame)
+		if frame == 8*beatFrames {
+			assert.InDelta(t, 1, bd.OnsetStrength(), 1e-9, "Even hits should have an average strength")
+		}
+	}
+
+	assert.InDelta(t, 2/(10.0/9), bd.OnsetStrength(), 1e-9, "A louder hit should be stronger than the recent average")
+}
+
+func TestBPMDetector_ConfidenceMetric(t *testing.T) {
+	// 16 onsets, 15 intervals, alternating between two 10ms histogram bins when
+	// jittered. Intervals sit mid-bin so rounding cannot move them.
+	confidences := func(metric ConfidenceMetric, jitter float64) (reported, variation, peak float64) {
+		bd := NewBPMDetector(44100, 256)
+		bd.SetHistogramResolution(10 * time.Millisecond)
+		bd.SetConfidenceMetric(metric)
+		onset := 0.0
+		for i := 0; i < 16; i++ {
+			bd.onsetTimes[i] = onset
+			onset += 0.503
+			if i%2 == 1 {
+				onset += jitter
+			}
+		}
+		bd.onsetTimesLen = 16
+		bd.calculateBPM()
+		_, reported = bd.GetBPM()
+		variation, peak = bd.GetConfidences()
+		return reported, variation, peak
+	}
+
+	_, _, peak := confidences(ConfidencePeak, 0)
+	assert.Equal(t, 1.0, peak, "All intervals in one bin should give full peak confidence")
+
+	reported, variation, peak := confidences(ConfidencePeak, 0.02)
+	assert.InDelta(t, 1.0/15, peak, 1e-9, "An 8/7 split over two bins should be barely dominant")
+	assert.Equal(t, peak, reported, "The peak metric should be reported when selected")
+	assert.Greater(t, variation, peak, "The variation metric should still be computed")
+
+	reported, variation, _ = confidences(ConfidenceVariation, 0.02)
+	assert.Equal(t, variation, reported, "The variation metric should be reported by default")
+}
+
+func TestBPMDetector_OnsetMemory(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		beatFrames      = 43 // ~120 BPM at 86 buffers per second, ~0.5s apart.
+	)
+
+	onsetsKept := func(memory time.Duration) int {
+		bd := NewBPMDetector(sampleRate, framesPerBuffer)
+		bd.SetOnsetMemory(memory)
+		flux := make([]float64, 1)
+		for frame := uint64(1); frame <= 20*beatFrames; frame++ {
+			flux[0] = 0
+			if frame%beatFrames == 0 {
+				flux[0] = 1
+			}
+			bd.ProcessFlux(flux, frame)
+		}
+		return bd.GetOnsetCount()
+	}
+
+	assert.Equal(t, 20, onsetsKept(0), "The default memory should keep all 10s of onsets")
+	assert.Equal(t, 4, onsetsKept(1800*time.Millisecond), "A 1.8s memory should keep the last 4 onsets")
+	assert.Equal(t, 20, onsetsKept(time.Hour), "A memory beyond the buffer should be clamped, not rejected")
+}
+
+func TestBPMDetector_EstimateInterval(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		beatFrames      = 43 // ~120 BPM at 86 buffers per second, ~0.5s apart.
+	)
+
+	// estimates returns the onset times tempo estimates were made at.
+	estimates := func(interval time.Duration) (times []float64, onsets int) {
+		bd := NewBPMDetector(sampleRate, framesPerBuffer)
+		bd.SetEstimateInterval(interval)
+		flux := make([]float64, 1)
+		for frame := uint64(1); frame <= 20*beatFrames; frame++ {
+			flux[0] = 0
+			if frame%beatFrames == 0 {
+				flux[0] = 1
+			}
+			bd.ProcessFlux(flux, frame)
+			if bd.hasEstimate && (len(times) == 0 || bd.lastEstimate != times[len(times)-1]) {
+				times = append(times, bd.lastEstimate)
+			}
+		}
+		bpm, _ := bd.GetBPM()
+		assert.InDelta(t, 120, bpm, 1, "The tempo should be estimated")
+		return times, bd.GetOnsetCount()
+	}
+
+	unthrottled, onsets := estimates(0)
+	assert.Equal(t, 20, onsets)
+	assert.Len(t, unthrottled, 17, "Every onset from the 4th should be estimated")
+
+	throttled, onsets := estimates(900 * time.Millisecond)
+	assert.Equal(t, 20, onsets, "Throttled onsets should still be recorded")
+	assert.Len(t, throttled, 9, "Every other onset should be estimated")
+	for i := 1; i < len(throttled); i++ {
+		assert.GreaterOrEqual(t, throttled[i]-throttled[i-1], 0.9, "Estimates should be at least the interval apart")
+	}
+}
+
+func TestBPMDetector_PeakPicking(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		rampStart       = 31
+		peakFrame       = rampStart + 4
+	)
+	envelope := []float64{0.2, 0.3, 0.45, 0.7, 1.0, 0.5, 0.2}
+
+	onsetFrame := func(pre, post int) (uint64, int) {
+		bd := NewBPMDetector(sampleRate, framesPerBuffer)
+		bd.SetPeakPicking(pre, post)
+		flux := make([]float64, 1)
+		for frame := uint64(1); frame <= 60; frame++ {
+			flux[0] = 0.01
+			if i := int(frame) - rampStart; i >= 0 && i < len(envelope) {
+				flux[0] = envelope[i]
+			}
+			bd.ProcessFlux(flux, frame)
+		}
+		return bd.lastOnsetFrame, bd.GetOnsetCount()
+	}
+
+	frame, count := onsetFrame(0, 0)
+	assert.Equal(t, 1, count, "The rise test should detect one onset")
+	assert.Equal(t, uint64(rampStart), frame, "The rise test should fire on the first rising frame")
+
+	frame, count = onsetFrame(2, 2)
+	assert.Equal(t, 1, count, "Peak-picking should detect one onset")
+	assert.Equal(t, uint64(peakFrame), frame, "Peak-picking should place the onset on the envelope peak")
+
+	bd := NewBPMDetector(sampleRate, framesPerBuffer)
+	bd.SetPeakPicking(0, 100)
+	assert.InDelta(t, MaxPeakWindow*framesPerBuffer/sampleRate, bd.OnsetLatency().Seconds(), 1e-6,
+		"The post window should be clamped and reported as latency")
+}
+
+func TestBPMDetector_TempoHint(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		beatFrames      = 43 // ~120 BPM.
+	)
+
+	tempo := func(method BPMMethod, lo, hi float64) float64 {
+		bd := NewBPMDetector(sampleRate, framesPerBuffer)
+		bd.SetMethod(method)
+		bd.SetTempoHint(lo, hi)
+		flux := make([]float64, 1)
+		for frame := uint64(1); frame <= 20*beatFrames; frame++ {
+			flux[0] = 0
+			if frame%beatFrames == 0 {
+				flux[0] = 1
+			}
+			bd.ProcessFlux(flux, frame)
+		}
+		bpm, _ := bd.GetBPM()
+		return bpm
+	}
+
+	for _, method := range []BPMMethod{BPMHistogram, BPMAutocorrelation} {
+		assert.InDelta(t, 120, tempo(method, 0, 0), 1.5, "%s: Without a hint the beat rate should be detected", method)
+		assert.InDelta(t, 60, tempo(method, 55, 65), 1.5, "%s: A hint should select half tempo", method)
+		assert.InDelta(t, 120, tempo(method, 130, 110), 1.5, "%s: An inverted hint should be ignored", method)
+	}
+}
+
+func TestBPMDetector_OnsetInterval(t *testing.T) {
+	bd := NewBPMDetector(44100, 256)
+	assert.Equal(t, minOnsetInterval, bd.minOnsetInterval(), "The fixed interval should apply by default")
+
+	bd.SetOnsetInterval(0.25)
+	bd.currentBPM = 174
+	assert.Equal(t, minOnsetInterval, bd.minOnsetInterval(), "The fixed interval should apply before the tempo is locked")
+
+	bd.locked = true
+	assert.InDelta(t, 0.0862, bd.minOnsetInterval(), 0.0001, "A quarter beat at 174 BPM should admit sixteenth notes")
+
+	bd.currentBPM = 60
+	assert.InDelta(t, 0.25, bd.minOnsetInterval(), 0.0001, "A quarter beat at 60 BPM should reject closer onsets")
+
+	bd.currentBPM = 300
+	bd.SetOnsetInterval(0.1)
+	assert.Equal(t, minBeatOnsetInterval, bd.minOnsetInterval(), "A tenth of a beat at 300 BPM should be clamped")
+
+	bd.SetOnsetInterval(0)
+	assert.Equal(t, minOnsetInterval, bd.minOnsetInterval(), "A fraction of 0 should restore the fixed interval")
+}
+
+func TestBPMDetector_OnsetIntervalKeepsMemory(t *testing.T) {
+	const framesPerBuffer = 256
+	const sampleRate = 44100.0
+
+	// A quarter beat at 300 BPM is 50ms, the shortest interval there is. Hits
+	// just slower than that for longer than the longest onset memory.
+	bd := NewBPMDetector(sampleRate, framesPerBuffer)
+	bd.SetFixedTempo(300)
+	bd.SetOnsetInterval(0.25)
+	bd.SetOnsetMemory(MaxOnsetMemory)
+
+	period := 10 // Buffers between hits, 58ms.
+	flux := make([]float64, 1)
+	frames := uint64(1.2 * MaxOnsetMemory.Seconds() * sampleRate / framesPerBuffer)
+	for frame := uint64(1); frame <= frames; frame++ {
+		flux[0] = 0
+		if frame%uint64(period) == 0 {
+			flux[0] = 1
+		}
+		bd.ProcessFlux(flux, frame)
+	}
+
+	hits := MaxOnsetMemory.Seconds() / (float64(period) * framesPerBuffer / sampleRate)
+	assert.InDelta(t, hits, float64(bd.GetOnsetCount()), 2, "The whole onset memory should be kept at the top of the tempo range")
+}
+
+func TestBPMDetector_Configure(t *testing.T) {
+	bd := NewBPMDetector(44100, 512)
+	flux := make([]float64, 1)
+	for frame := uint64(1); frame <= 10*43; frame++ {
+		flux[0] = 0
+		if frame%43 == 0 {
+			flux[0] = 1
+		}
+		bd.ProcessFlux(flux, frame)
+	}
+	onsets := bd.GetOnsetCount()
+	bpm, _ := bd.GetBPM()
+
+	cfg := bd.Config()
+	cfg.OnsetThreshold = 0.5
+	cfg.OnsetMemory = 5 * time.Second
+	cfg.OnsetPostWindow = 2
+	cfg.TempoHintLow, cfg.TempoHintHigh = 120, 140
+	bd.Configure(cfg)
+
+	assert.Equal(t, cfg, bd.Config(), "The new parameters should be applied")
+	assert.Equal(t, onsets, bd.GetOnsetCount(), "Onset history should be kept")
+	current, _ := bd.GetBPM()
+	assert.Equal(t, bpm, current, "The tempo should be kept")
+
+	bd.Configure(BPMConfig{OnsetThreshold: -1, OnsetMemory: time.Hour, OnsetPreWindow: 99, TempoHintLow: 140, TempoHintHigh: 120})
+	normalized := bd.Config()
+	assert.Zero(t, normalized.OnsetThreshold, "A negative threshold should be clamped")
+	assert.Equal(t, MaxOnsetMemory, normalized.OnsetMemory, "The memory should be clamped")
+	assert.Equal(t, MaxPeakWindow, normalized.OnsetPreWindow, "The window should be clamped")
+	assert.Zero(t, normalized.TempoHintHigh, "An inverted hint should be removed")
+}
+
+func TestBPMDetector_FixedTempo(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 441 // 10ms frames.
+	)
+
+	bd := NewBPMDetector(sampleRate, framesPerBuffer)
+	assert.Equal(t, -1.0, bd.BeatPhase(), "The phase should be unknown before a tempo and onset")
+
+	bd.SetFixedTempo(100) // 60 frames per beat.
+	bpm, confidence := bd.GetBPM()
+	assert.Equal(t, 100.0, bpm, "The fixed tempo should be reported at once")
+	assert.Equal(t, 1.0, confidence, "A fixed tempo should have full confidence")
+
+	// Beats at 120 BPM (50 frames) would otherwise be detected, with an
+	// off-beat hit 20 frames after each beat.
+	flux := make([]float64, 1)
+	for frame := uint64(1); frame <= 600; frame++ {
+		flux[0] = 0
+		if frame%60 == 0 {
+			flux[0] = 1
+		} else if frame%60 == 20 && frame > 300 {
+			flux[0] = 1
+		}
+		bd.ProcessFlux(flux, frame)
+	}
+
+	bpm, _ = bd.GetBPM()
+	assert.Equal(t, 100.0, bpm, "Onsets should not change a fixed tempo")
+	assert.InDelta(t, 0, bd.BeatPhase(), 0.02, "The phase should be on the beat at the last onset")
+	assert.Equal(t, 100.0, bd.FixedTempo())
+
+	bd.ProcessFlux([]float64{0}, 630)
+	assert.InDelta(t, 0.5, bd.BeatPhase(), 0.02, "Half a beat later the phase should be 0.5")
+	// Beat 0 is the first onset at frame 60, the last aligned one is at 600.
+	assert.InDelta(t, 9.5, bd.BeatPosition(), 0.02, "Beats should be counted across re-alignments")
+
+	bd.SetFixedTempo(0)
+	assert.Zero(t, bd.FixedTempo(), "A tempo of 0 should resume estimation")
+}
+
+func TestBPMDetector_DisplayBPM(t *testing.T) {
+	bd := NewBPMDetector(44100, 441) // 10ms frames.
+	bd.SetDisplayWindow(40 * time.Millisecond)
+
+	step := func(bpm float64) float64 {
+		bd.mu.Lock()
+		bd.currentBPM = bpm
+		bd.updateDisplayBPM()
+		bd.mu.Unlock()
+		return bd.GetDisplayBPM()
+	}
+
+	assert.Zero(t, step(0), "No tempo should display 0")
+	assert.Equal(t, 120.0, step(120), "The first tempo should display as is")
+	assert.Equal(t, 121.0, step(122), "Tempos should be averaged")
+	step(122)
+	step(122)
+	assert.Equal(t, 122.0, step(122), "Old tempos should leave the 4 frame window")
+	assert.Equal(t, 61.0, step(61), "A half tempo jump should restart the average")
+	assert.Zero(t, step(0), "Losing the tempo should clear the display")
+
+	bd.SetDisplayWindow(0)
+	step(120)
+	assert.Equal(t, 130.0, step(130), "Without a window the display should follow the tempo")
+}
+
+func TestBPMDetector_OnsetBufferWraps(t *testing.T) {
+	for _, method := range []BPMMethod{BPMHistogram, BPMAutocorrelation} {
+		bd := NewBPMDetector(44100, 512)
+		bd.SetMethod(method)
+		flux := make([]float64, 1)
+		// Three times the envelope capacity, so the ring wraps repeatedly.
+		for frame := uint64(1); frame <= 3*1024; frame++ {
+			flux[0] = 0
+			if frame%43 == 0 {
+				flux[0] = 1
+			}
+			bd.ProcessFlux(flux, frame)
+		}
+
+		bpm, _ := bd.GetBPM()
+		assert.InDelta(t, 120, bpm, 1.5, "%s: The tempo should be detected after the envelope wraps", method)
+		assert.Equal(t, int64(3*1024%43), bd.FramesSinceOnset(), "%s: The last onset should be found", method)
+	}
+}
+
+func BenchmarkBPMDetector_ProcessFluxFull(b *testing.B) {
+	bd := NewBPMDetector(44100, 512)
+	flux := make([]float64, 16)
+	// Fill the onset envelope so every frame overwrites the oldest value.
+	for frame := uint64(1); frame <= 2048; frame++ {
+		bd.ProcessFlux(flux, frame)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bd.ProcessFlux(flux, uint64(2049+i))
+	}
+}
+
+func TestBPMDetector_ProcessFFT(t *testing.T) {
+	const size = 256
+	p, err := NewFFTProcessor(size, 44100, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	viaFFT, viaFlux := NewBPMDetector(44100, size), NewBPMDetector(44100, size)
+
+	input := make([]int32, size)
+	for frame := uint64(1); frame <= 40; frame++ {
+		amplitude := 0.0
+		if frame%10 == 0 {
+			amplitude = 0.5
+		}
+		for i := range input {
+			input[i] = int32(amplitude * math.Sin(2*math.Pi*440*float64(i)/44100) * math.MaxInt32)
+		}
+		p.Process(input)
+		viaFFT.ProcessFFT(p, frame)
+		viaFlux.ProcessFlux(p.GetSpectralFlux(), frame)
+		assert.Equal(t, viaFlux.OnsetStrength(), viaFFT.OnsetStrength(), "Frame %d should see the same flux", frame)
+	}
+	assert.Equal(t, viaFlux.FramesSinceOnset(), viaFFT.FramesSinceOnset())
+	assert.NotEqual(t, int64(-1), viaFFT.FramesSinceOnset(), "The hits should be detected as onsets")
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseConfidenceMetric converts a string name (case-insensitive) to a
+// ConfidenceMetric enum, returns a known default (ConfidenceVariation) and an
+// error if the name is unknown.
+func ParseConfidenceMetric(name string) (ConfidenceMetric, error) {
+	switch strings.ToLower(name) {
+	case "variation":
+		return ConfidenceVariation, nil
+	case "peak":
+		return ConfidencePeak, nil
+	default:
+		return ConfidenceVariation, fmt.Errorf("unknown confidence metric name: '%s'", name)
+	}
+}
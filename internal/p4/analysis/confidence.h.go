@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import "fmt"
+
+// ConfidenceMetric selects how the histogram method rates its tempo estimate.
+type ConfidenceMetric int
+
+const (
+	// ConfidenceVariation is the coefficient of variation of the inter-onset
+	// intervals, weighted by the grid alignment score of the chosen tempo.
+	ConfidenceVariation ConfidenceMetric = iota
+	// ConfidencePeak is the dominance of the top interval bin over the rest of
+	// the histogram, see BPMDetector.histogramPeakConfidence.
+	ConfidencePeak
+)
+
+// String returns the string representation of the ConfidenceMetric.
+func (m ConfidenceMetric) String() string {
+	switch m {
+	case ConfidenceVariation:
+		return "variation"
+	case ConfidencePeak:
+		return "peak"
+	default:
+		return fmt.Sprintf("UnknownConfidenceMetric(%d)", int(m))
+	}
+}
@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+// denormalThreshold is the magnitude below which state carried between frames is
+// flushed to zero. It is far below anything audible (an int32 LSB is ~4.7e-10)
+// but well above the denormal range, where arithmetic on some CPUs is orders of
+// magnitude slower. Decaying recursive state, e.g. the smoothed confidence over
+// a long silence, would otherwise settle there and cause CPU spikes.
+const denormalThreshold = 1e-30
+
+// flushDenormal returns 0 for values too small to matter, v otherwise.
+func flushDenormal(v float64) float64 {
+	if v > -denormalThreshold && v < denormalThreshold {
+		return 0
+	}
+	return v
+}
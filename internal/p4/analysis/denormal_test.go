@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlushDenormal(t *testing.T) {
+	assert.Zero(t, flushDenormal(math.SmallestNonzeroFloat64), "Denormals should be flushed")
+	assert.Zero(t, flushDenormal(-1e-31), "Negative values below the threshold should be flushed")
+	assert.Equal(t, 1e-9, flushDenormal(1e-9), "Values above the threshold should be kept")
+	assert.Equal(t, -0.5, flushDenormal(-0.5), "Values above the threshold should be kept")
+}
+
+func TestBPMDetector_SmoothedConfidenceSettlesToZero(t *testing.T) {
+	bd := NewBPMDetector(44100, 512)
+	bd.SetConfidenceSmoothing(100 * time.Millisecond)
+	bd.smoothedConfidence = 1
+
+	// Without flushing, the decay towards 0 ends in the denormal range.
+	for range 10000 {
+		bd.smoothConfidence()
+	}
+	assert.Zero(t, bd.GetSmoothedConfidence(), "Smoothed confidence should settle to exactly 0")
+}
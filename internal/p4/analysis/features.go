@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import "math"
+
+// flatnessFloor keeps empty bins from collapsing the geometric mean to zero.
+const flatnessFloor = 1e-12
+
+// ComputeSpectralFeatures computes the flatness and crest factor of magnitudes.
+// The geometric mean is computed as a log-sum to avoid underflow, with zeros
+// clamped to flatnessFloor. Both measures are 0 for an empty or silent spectrum.
+func ComputeSpectralFeatures(magnitudes []float64) SpectralFeatures {
+	n := len(magnitudes)
+	if n == 0 {
+		return SpectralFeatures{}
+	}
+
+	var sum, logSum, peak float64
+	for _, mag := range magnitudes {
+		sum += mag
+		logSum += math.Log(max(mag, flatnessFloor))
+		peak = max(peak, mag)
+	}
+
+	mean := sum / float64(n)
+	if mean <= flatnessFloor {
+		return SpectralFeatures{}
+	}
+
+	return SpectralFeatures{
+		Flatness: math.Exp(logSum/float64(n)) / mean,
+		Crest:    peak / mean,
+	}
+}
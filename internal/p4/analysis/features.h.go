@@ -0,0 +1,12 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+// SpectralFeatures holds scalar descriptors of one magnitude spectrum.
+type SpectralFeatures struct {
+	// Flatness is the ratio of the geometric to the arithmetic mean of the
+	// magnitudes, near 1 for noise and near 0 for tonal content.
+	Flatness float64
+	// Crest is the ratio of the peak to the arithmetic mean of the magnitudes,
+	// high for a few dominant partials and 1 for a perfectly flat spectrum.
+	Crest float64
+}
@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeSpectralFeatures(t *testing.T) {
+	testCases := []struct {
+		name           string
+		magnitudes     []float64
+		expectFlatness float64
+		expectCrest    float64
+	}{
+		{"Empty", nil, 0, 0},
+		{"Silence", []float64{0, 0, 0, 0}, 0, 0},
+		{"Flat", []float64{0.5, 0.5, 0.5, 0.5}, 1, 1},
+		{"Single partial", []float64{0, 0, 1, 0}, 0, 4},
+		{"Two levels", []float64{1, 4}, 0.8, 1.6},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			features := ComputeSpectralFeatures(tc.magnitudes)
+			assert.InDelta(t, tc.expectFlatness, features.Flatness, 1e-6, "Flatness mismatch")
+			assert.InDelta(t, tc.expectCrest, features.Crest, 1e-6, "Crest mismatch")
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"phase4/pkg/bitint"
 	"phase4/pkg/buffer"
 	"phase4/pkg/simd"
+	"sort"
 
 	"gonum.org/v1/gonum/dsp/fourier"
 )
@@ -17,6 +18,9 @@ func NewFFTProcessor(size int, sampleRate float64, windowType WindowFunc) (*FFTP
 	if !bitint.IsPowerOfTwo(size) {
 		return nil, fmt.Errorf("fft size must be a power of 2, got %d", size)
 	}
+	if !isFinite(sampleRate) || sampleRate <= 0 {
+		return nil, fmt.Errorf("sample rate must be positive, got %g", sampleRate)
+	}
 
 	fftFunc := fourier.NewFFT(size)
 	windowCoeffs := simd.AlignedFloat64(size)
@@ -35,22 +39,32 @@ func NewFFTProcessor(size int, sampleRate float64, windowType WindowFunc) (*FFTP
 	magnitudeBuffer1 := simd.AlignedFloat64(magnitudeSize)
 	magnitudeBuffer2 := simd.AlignedFloat64(magnitudeSize)
 	prevMagnitudes := simd.AlignedFloat64(magnitudeSize)
-	spectralFlux := simd.AlignedFloat64(magnitudeSize)
+	fluxBuffer1 := simd.AlignedFloat64(magnitudeSize)
+	fluxBuffer2 := simd.AlignedFloat64(magnitudeSize)
+	prevPhases := simd.AlignedFloat64(magnitudeSize)
+	prevPrevPhases := simd.AlignedFloat64(magnitudeSize)
+	prevComplexMags := simd.AlignedFloat64(magnitudeSize)
 
 	p := &FFTProcessor{
-		fftSize:        size,
-		fftFunc:        fftFunc,
-		sampleRate:     sampleRate,
-		inputBuffer:    simd.AlignedFloat64(size),
-		fftOutput:      simd.AlignedComplex128(magnitudeSize),
-		magnitudes:     buffer.NewFloat64DoubleBuffer(magnitudeBuffer1, magnitudeBuffer2),
-		normFactor:     1.0 / float64(0x80000000), // Converts int32 to float64 range [-1,1).
-		window:         windowCoeffs,
-		fftInputScale:  1.0 / float64(size),
-		frequencyBins:  frequencyBins,
-		prevMagnitudes: prevMagnitudes,
-		spectralFlux:   spectralFlux,
-		debugInterval:  100, // Log every 100 frames (~0.58 seconds at 44.1kHz/256)
+		fftSize:         size,
+		fftFunc:         fftFunc,
+		sampleRate:      sampleRate,
+		inputBuffer:     simd.AlignedFloat64(size),
+		normalized:      simd.AlignedFloat64(size),
+		fftOutput:       simd.AlignedComplex128(magnitudeSize),
+		magnitudes:      buffer.NewFloat64DoubleBuffer(magnitudeBuffer1, magnitudeBuffer2),
+		normFactor:      1.0 / float64(0x80000000), // Converts int32 to float64 range [-1,1).
+		gain:            1,
+		window:          windowCoeffs,
+		fftInputScale:   1.0 / float64(size),
+		psdScale:        windowPSDScale(windowCoeffs, sampleRate),
+		frequencyBins:   frequencyBins,
+		prevMagnitudes:  prevMagnitudes,
+		spectralFlux:    buffer.NewFloat64DoubleBuffer(fluxBuffer1, fluxBuffer2),
+		prevPhases:      prevPhases,
+		prevPrevPhases:  prevPrevPhases,
+		prevComplexMags: prevComplexMags,
+		debugInterval:   100, // Log every 100 frames (~0.58 seconds at 44.1kHz/256)
 	}
 
 	log.Printf("FFT Processor initialized: size=%d, sampleRate=%.0f, bins=%d, resolution=%.2f Hz/bin",
@@ -59,69 +73,169 @@ func NewFFTProcessor(size int, sampleRate float64, windowType WindowFunc) (*FFTP
 	return p, nil
 }
 
+// Process analyzes one buffer of samples. Buffers shorter than the FFT size are
+// zero-padded, longer buffers are truncated to their first fftSize samples.
 func (p *FFTProcessor) Process(inputBuffer []int32) {
 	inputLen := len(inputBuffer)
+	n := min(inputLen, p.fftSize)
+
+	// Use direct array indexing instead of range loop for better bounds check elimination
+	for i := 0; i < n; i++ {
+		p.normalized[i] = float64(inputBuffer[i]) * p.normFactor
+	}
+	last := p.prevSample
+	if inputLen > 0 {
+		last = float64(inputBuffer[inputLen-1]) * p.normFactor * p.gain
+	}
+	p.analyze(p.normalized[:n], last)
+}
+
+// ProcessFloat64 analyzes one buffer of samples already in the [-1, 1] range,
+// e.g. from a float file or a generated test signal, without an int32 round-trip.
+// Buffers are zero-padded or truncated as in Process.
+func (p *FFTProcessor) ProcessFloat64(samples []float64) {
+	last := p.prevSample
+	if len(samples) > 0 {
+		last = samples[len(samples)-1] * p.gain
+	}
+	p.analyze(samples[:min(len(samples), p.fftSize)], last)
+}
+
+// ProcessFloat32 is ProcessFloat64 for float32 samples.
+func (p *FFTProcessor) ProcessFloat32(samples []float32) {
+	n := min(len(samples), p.fftSize)
+	for i := 0; i < n; i++ {
+		p.normalized[i] = float64(samples[i])
+	}
+	last := p.prevSample
+	if len(samples) > 0 {
+		last = float64(samples[len(samples)-1]) * p.gain
+	}
+	p.analyze(p.normalized[:n], last)
+}
+
+// analyze runs the analysis on at most fftSize normalized samples. last is the
+// final sample of the whole input buffer with the gain applied, which is
+// carried as the pre-emphasis filter state rather than the last one analyzed,
+// so the filter stays continuous when the buffer is longer than the FFT.
+func (p *FFTProcessor) analyze(samples []float64, last float64) {
+	inputLen := len(samples)
 	magnitudeSize := len(p.frequencyBins)
 
-	// Calculate input RMS for debugging
-	var inputRMS float64
+	// Calculate input RMS for debugging, and the peak level for clip detection.
+	var inputRMS, inputPeak float64
+	clipCount := 0
 
-	// Use direct array indexing instead of range loop for better bounds check elimination
+	prevSample := p.prevSample
 	for i := 0; i < p.fftSize; i++ {
 		if i < inputLen {
-			normalized := float64(inputBuffer[i]) * p.normFactor
+			// Clipping happens in the converter, so it is detected before the
+			// gain, the levels follow the gained samples the analysis sees.
+			raw := flushDenormal(samples[i])
+			if math.Abs(raw) >= clipThreshold {
+				clipCount++
+			}
+			normalized := raw * p.gain
 			inputRMS += normalized * normalized
-			p.inputBuffer[i] = normalized * p.window[i]
+			inputPeak = math.Max(inputPeak, math.Abs(normalized))
+			// Pre-emphasis y[n] = x[n] - a*x[n-1] is applied before windowing,
+			// with a = 0 it reduces to the plain normalized sample.
+			emphasized := normalized - p.preemphasis*prevSample
+			prevSample = normalized
+			p.inputBuffer[i] = emphasized * p.window[i]
 		} else {
 			p.inputBuffer[i] = 0.0
 		}
 	}
+	p.prevSample = flushDenormal(last)
 	inputRMS = math.Sqrt(inputRMS / float64(p.fftSize))
+	p.inputRMS = inputRMS
+	p.inputPeak = inputPeak
+	p.clipCount = clipCount
 
 	p.fftFunc.Coefficients(p.fftOutput, p.inputBuffer)
 
 	var totalFlux float64
 	var maxFlux float64
 	var bassEnergy float64
+	var fluxBase float64 // Sum of the magnitudes the flux is taken from, see normalizeFlux.
+
+	// Magnitudes and flux are both written to their inactive buffers and
+	// published by the swaps, so readers on other goroutines never see a
+	// partially written frame.
+	p.spectralFlux.Swap(func(fluxBuffer *[]float64) {
+		p.magnitudes.Swap(func(currentMagBuffer *[]float64) {
+			// Direct indexing for better performance
+			for i := 0; i < magnitudeSize; i++ {
+				mag := cmplx.Abs(p.fftOutput[i]) * p.fftInputScale
+				if p.noiseProfile != nil {
+					mag = p.subtractNoise(i, mag)
+				}
+				(*currentMagBuffer)[i] = p.scaleMagnitude(i, mag)
 
-	p.magnitudes.Swap(func(currentMagBuffer *[]float64) {
-		// Direct indexing for better performance
-		for i := 0; i < magnitudeSize; i++ {
-			mag := cmplx.Abs(p.fftOutput[i]) * p.fftInputScale
+				// Track bass energy (0-200Hz)
+				if p.frequencyBins[i] < 200 {
+					bassEnergy += (*currentMagBuffer)[i]
+				}
 
-			// Single-sided spectrum energy compensation
-			if i > 0 && i < p.fftSize/2 {
-				(*currentMagBuffer)[i] = mag * 2.0
-			} else {
-				(*currentMagBuffer)[i] = mag
-			}
+				// Calculate spectral flux with emphasis on low frequencies
+				weight := 1.0
+				if p.frequencyBins[i] < 200 {
+					weight = 2.0 // Double weight for bass frequencies
+				}
 
-			// Track bass energy (0-200Hz)
-			if p.frequencyBins[i] < 200 {
-				bassEnergy += (*currentMagBuffer)[i]
-			}
+				// In log mode the flux is computed from log-compressed magnitudes,
+				// which makes quieter high-frequency onsets (hi-hats) stand out
+				// against loud bass transients.
+				fluxMag := (*currentMagBuffer)[i]
+				if p.fluxMode == FluxLog {
+					fluxMag = math.Log1p(fluxMag)
+				}
 
-			// Calculate spectral flux with emphasis on low frequencies
-			weight := 1.0
-			if p.frequencyBins[i] < 200 {
-				weight = 2.0 // Double weight for bass frequencies
+				var diff float64
+				if p.onsetMethod == OnsetComplex {
+					diff = p.complexDeviation(i, (*currentMagBuffer)[i]) * weight
+					fluxBase += (*currentMagBuffer)[i]
+				} else {
+					diff = (fluxMag - p.prevMagnitudes[i]) * weight
+					fluxBase += fluxMag
+				}
+				if diff > 0 {
+					(*fluxBuffer)[i] = diff
+					totalFlux += diff
+					if diff > maxFlux {
+						maxFlux = diff
+					}
+				} else {
+					(*fluxBuffer)[i] = 0.0
+				}
+
+				// Update previous magnitudes for next frame
+				p.prevMagnitudes[i] = flushDenormal(fluxMag)
 			}
+		})
+		if p.fluxNormalize {
+			normalizeFlux(*fluxBuffer, fluxBase, p.fluxFloor())
+		}
+	})
 
-			diff := ((*currentMagBuffer)[i] - p.prevMagnitudes[i]) * weight
-			if diff > 0 {
-				p.spectralFlux[i] = diff
-				totalFlux += diff
-				if diff > maxFlux {
-					maxFlux = diff
-				}
-			} else {
-				p.spectralFlux[i] = 0.0
+	// Phases are published separately, after the magnitudes of the same frame.
+	if p.phases != nil {
+		p.phases.Swap(func(phaseBuffer *[]float64) {
+			for i := range *phaseBuffer {
+				(*phaseBuffer)[i] = cmplx.Phase(p.fftOutput[i])
 			}
+		})
+	}
 
-			// Update previous magnitudes for next frame
-			p.prevMagnitudes[i] = (*currentMagBuffer)[i]
+	if p.noiseFramesLeft > 0 {
+		p.noiseFramesLeft--
+		if p.noiseFramesLeft == 0 {
+			for i, sum := range p.noiseSum {
+				p.noiseProfile[i] = sum / float64(p.noiseFrames)
+			}
 		}
-	})
+	}
 
 	// Debug logging
 	frameCount := p.frameCounter.Add(1)
@@ -141,16 +255,58 @@ func (p *FFTProcessor) GetSpectralFluxInRange(lowFreq, highFreq float64) float64
 	var sum float64
 	magnitudeSize := len(p.frequencyBins)
 
+	p.spectralFlux.View(func(spectralFlux []float64) {
+		for i := 0; i < magnitudeSize; i++ {
+			freq := p.frequencyBins[i]
+			if freq >= lowFreq && freq <= highFreq {
+				sum += spectralFlux[i]
+			}
+			if freq > highFreq {
+				break // Early exit if frequency exceeds highFreq
+			}
+		}
+	})
+	return sum
+}
+
+// GetBandEnergy returns the sum of squared magnitudes of the bins within
+// [lowFreq, highFreq], see BinRange. A highFreq of 0 extends the band to Nyquist.
+func (p *FFTProcessor) GetBandEnergy(lowFreq, highFreq float64) float64 {
+	magnitudes := p.GetMagnitudes()
+	lo, hi := p.BinRange(lowFreq, highFreq)
+
+	var energy float64
+	for _, mag := range magnitudes[lo:hi] {
+		energy += mag * mag
+	}
+	return energy
+}
+
+// BinRange returns the half-open range [lo, hi) of bin indices whose frequency
+// lies within [lowFreq, highFreq]. A highFreq of 0 selects all bins up to Nyquist.
+func (p *FFTProcessor) BinRange(lowFreq, highFreq float64) (lo, hi int) {
+	magnitudeSize := len(p.frequencyBins)
+	if highFreq <= 0 {
+		highFreq = p.frequencyBins[magnitudeSize-1]
+	}
+
+	lo = magnitudeSize
 	for i := 0; i < magnitudeSize; i++ {
 		freq := p.frequencyBins[i]
-		if freq >= lowFreq && freq <= highFreq {
-			sum += p.spectralFlux[i]
-		}
 		if freq > highFreq {
 			break // Early exit if frequency exceeds highFreq
 		}
+		if freq >= lowFreq {
+			if lo == magnitudeSize {
+				lo = i
+			}
+			hi = i + 1
+		}
 	}
-	return sum
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
 }
 
 // FindPeakFrequency returns the frequency bin with the highest magnitude
@@ -171,6 +327,57 @@ func (p *FFTProcessor) FindPeakFrequency() (freq float64, magnitude float64) {
 	return p.frequencyBins[maxIdx], maxMag
 }
 
+// FindPeaks returns up to n spectral peaks ordered by descending magnitude. A peak
+// is a local maximum of at least minMag, peaks closer than minSpacingHz to a
+// louder peak are discarded. Peak frequency and magnitude are refined with
+// parabolic interpolation over the neighbouring bins for sub-bin accuracy.
+func (p *FFTProcessor) FindPeaks(n int, minSpacingHz, minMag float64) []Peak {
+	magnitudes := p.GetMagnitudes()
+	magnitudeSize := len(magnitudes)
+	resolution := p.GetFrequencyResolution()
+
+	candidates := make([]Peak, 0, 16)
+	for i := 1; i < magnitudeSize-1; i++ {
+		prev, curr, next := magnitudes[i-1], magnitudes[i], magnitudes[i+1]
+		if curr < minMag || curr <= prev || curr < next {
+			continue
+		}
+
+		// Fit a parabola through the three bins, offset is in [-0.5, 0.5].
+		offset := 0.0
+		if denom := prev - 2*curr + next; denom != 0 {
+			offset = 0.5 * (prev - next) / denom
+		}
+		candidates = append(candidates, Peak{
+			Freq:      (float64(i) + offset) * resolution,
+			Magnitude: curr - 0.25*(prev-next)*offset,
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Magnitude > candidates[j].Magnitude
+	})
+
+	peaks := make([]Peak, 0, n)
+	for _, candidate := range candidates {
+		if len(peaks) >= n {
+			break
+		}
+		tooClose := false
+		for _, peak := range peaks {
+			if math.Abs(candidate.Freq-peak.Freq) < minSpacingHz {
+				tooClose = true
+				break
+			}
+		}
+		if !tooClose {
+			peaks = append(peaks, candidate)
+		}
+	}
+
+	return peaks
+}
+
 // ValidateFFT tests the FFT with a known sine wave
 // Optimized with direct array access
 func (p *FFTProcessor) ValidateFFT(testFreq float64) (detectedFreq float64, error float64) {
@@ -200,6 +407,39 @@ func (p *FFTProcessor) ValidateFFT(testFreq float64) (detectedFreq float64, erro
 	return detectedFreq, error
 }
 
+// SelfTest runs ValidateFFT at each of the given frequencies and checks that the
+// detected frequency is within one bin of the test frequency. Frequencies at or
+// above Nyquist are skipped. Each result is logged as PASS/FAIL, an error is
+// returned if any frequency fails. It overwrites the internal FFT buffers, so
+// it must not be called while Process is running.
+func (p *FFTProcessor) SelfTest(testFreqs []float64) error {
+	resolution := p.GetFrequencyResolution()
+	nyquist := p.sampleRate / 2
+
+	failed := 0
+	for _, testFreq := range testFreqs {
+		if testFreq >= nyquist {
+			log.Printf("FFT SelfTest ➜ SKIP ➜ %.2f Hz is at or above Nyquist (%.2f Hz)", testFreq, nyquist)
+			continue
+		}
+
+		detectedFreq, freqError := p.ValidateFFT(testFreq)
+		if freqError <= resolution {
+			log.Printf("FFT SelfTest ➜ PASS ➜ %.2f Hz detected as %.2f Hz (error %.2f Hz)",
+				testFreq, detectedFreq, freqError)
+		} else {
+			log.Printf("FFT SelfTest ➜ FAIL ➜ %.2f Hz detected as %.2f Hz (error %.2f Hz > %.2f Hz/bin)",
+				testFreq, detectedFreq, freqError, resolution)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test frequencies outside one bin (%.2f Hz)", failed, len(testFreqs), resolution)
+	}
+	return nil
+}
+
 func (p *FFTProcessor) GetMagnitudes() []float64 {
 	return p.magnitudes.Get()
 }
@@ -208,8 +448,134 @@ func (p *FFTProcessor) GetFrequencyBins() []float64 {
 	return p.frequencyBins
 }
 
+// GetInputRMS returns the RMS level of the last processed input buffer, after
+// the input gain and before windowing, in the normalized [-1, 1) range at unity
+// gain.
+func (p *FFTProcessor) GetInputRMS() float64 {
+	return p.inputRMS
+}
+
+// GetInputPeak returns the largest absolute sample of the last processed input
+// buffer after the input gain, in the normalized [0, 1] range at unity gain.
+func (p *FFTProcessor) GetInputPeak() float64 {
+	return p.inputPeak
+}
+
+// GetClipCount returns how many samples of the last processed input buffer were
+// at or above clipThreshold of full scale, before the input gain.
+func (p *FFTProcessor) GetClipCount() int {
+	return p.clipCount
+}
+
+// GetSpectralFlux returns a copy of the spectral flux of the last processed
+// buffer, it is safe to call from any goroutine.
 func (p *FFTProcessor) GetSpectralFlux() []float64 {
-	return p.spectralFlux
+	return p.spectralFlux.Get()
+}
+
+// AppendSpectralFlux appends the spectral flux of bins [lo, hi) of the last
+// processed buffer to dst, so a frame can be copied into a reused buffer. Like
+// GetSpectralFlux it is safe to call from any goroutine.
+func (p *FFTProcessor) AppendSpectralFlux(dst []float64, lo, hi int) []float64 {
+	return p.spectralFlux.AppendTo(dst, lo, hi)
+}
+
+// SetPhaseOutput enables or disables recording the phase of every bin for
+// GetPhases. It must be called before the first call to Process.
+func (p *FFTProcessor) SetPhaseOutput(enabled bool) {
+	if !enabled {
+		p.phases = nil
+		return
+	}
+	size := len(p.frequencyBins)
+	p.phases = buffer.NewFloat64DoubleBuffer(simd.AlignedFloat64(size), simd.AlignedFloat64(size))
+}
+
+// GetPhases returns a copy of the phase in radians, in (-pi, pi], of every bin
+// of the last processed buffer, or nil unless SetPhaseOutput is enabled. It is
+// safe to call from any goroutine.
+func (p *FFTProcessor) GetPhases() []float64 {
+	if p.phases == nil {
+		return nil
+	}
+	return p.phases.Get()
+}
+
+// SetFluxMode selects how spectral flux is computed. It must be called before
+// the first call to Process, as the previous magnitudes are stored in the
+// selected domain.
+func (p *FFTProcessor) SetFluxMode(mode FluxMode) {
+	p.fluxMode = mode
+}
+
+// SetFluxNormalization divides the spectral flux of each frame by the sum of its
+// magnitudes, log-compressed with FluxLog, see normalizeFlux. The flux then
+// measures the relative change of the spectrum, so onsets no longer depend on
+// the input level. It must be called before the first call to Process.
+func (p *FFTProcessor) SetFluxNormalization(enabled bool) {
+	p.fluxNormalize = enabled
+}
+
+// SetOnsetMethod selects the onset detection function that fills the spectral
+// flux buffer. It must be called before the first call to Process.
+func (p *FFTProcessor) SetOnsetMethod(method OnsetMethod) {
+	p.onsetMethod = method
+}
+
+// SetMagnitudeScaling selects how bin magnitudes are scaled, see
+// scaleMagnitude. It must be called before the first call to Process.
+func (p *FFTProcessor) SetMagnitudeScaling(scaling MagnitudeScaling) {
+	p.magnitudeScaling = scaling
+}
+
+// SetSpectrumType selects the quantity reported per bin, see scaleMagnitude.
+// Any type but SpectrumMagnitude ignores SetMagnitudeScaling. It must be called
+// before the first call to Process.
+func (p *FFTProcessor) SetSpectrumType(spectrumType SpectrumType) {
+	p.spectrumType = spectrumType
+}
+
+// SetPreemphasis sets the pre-emphasis coefficient a of y[n] = x[n] - a*x[n-1],
+// typically 0.95-0.97 for speech. A coefficient of 0 disables the filter.
+func (p *FFTProcessor) SetPreemphasis(coefficient float64) {
+	p.preemphasis = coefficient
+	p.prevSample = 0
+}
+
+// SetInputGain scales the normalized samples by gainDB decibels before
+// pre-emphasis and windowing, to calibrate the level of an interface against
+// the onset thresholds. 0 dB leaves the samples unchanged.
+func (p *FFTProcessor) SetInputGain(gainDB float64) {
+	p.gain = math.Pow(10, gainDB/20)
+}
+
+// SetNoiseReduction enables spectral subtraction. The next calibrationFrames
+// frames pass through unchanged while the average magnitude of every bin is
+// learned as the noise profile, which is then subtracted from each following
+// frame before scaling and flux. floor is the fraction of a magnitude that is
+// always kept, so noisy bins are attenuated rather than zeroed. A
+// calibrationFrames of 0 or less disables noise reduction.
+func (p *FFTProcessor) SetNoiseReduction(calibrationFrames int, floor float64) {
+	if calibrationFrames <= 0 {
+		p.noiseProfile, p.noiseSum = nil, nil
+		p.noiseFrames, p.noiseFramesLeft = 0, 0
+		return
+	}
+
+	p.noiseProfile = make([]float64, len(p.frequencyBins))
+	p.noiseSum = make([]float64, len(p.frequencyBins))
+	p.noiseFrames, p.noiseFramesLeft = calibrationFrames, calibrationFrames
+	p.noiseFloor = floor
+}
+
+// subtractNoise returns the unscaled magnitude of bin i with the noise profile
+// subtracted, or adds it to the profile while calibrating.
+func (p *FFTProcessor) subtractNoise(i int, mag float64) float64 {
+	if p.noiseFramesLeft > 0 {
+		p.noiseSum[i] += mag
+		return mag
+	}
+	return math.Max(mag-p.noiseProfile[i], p.noiseFloor*mag)
 }
 
 func (p *FFTProcessor) GetFrequencyResolution() float64 {
@@ -8,19 +8,52 @@ import (
 	"gonum.org/v1/gonum/dsp/fourier"
 )
 
+// Peak is a spectral peak as returned by FFTProcessor.FindPeaks.
+type Peak struct {
+	Freq      float64
+	Magnitude float64
+}
+
+// clipThreshold is the normalized sample level counted as clipping. It sits just
+// below full scale so converted int16/float32 input, which never reaches the
+// int32 limit exactly, is still detected.
+const clipThreshold = 0.999
+
 type FFTProcessor struct {
-	fftFunc        *fourier.FFT
-	magnitudes     *buffer.Float64DoubleBuffer
-	prevMagnitudes []float64
-	inputBuffer    []float64
-	fftOutput      []complex128
-	window         []float64
-	frequencyBins  []float64
-	spectralFlux   []float64
-	fftInputScale  float64
-	sampleRate     float64
-	fftSize        int
-	normFactor     float64
-	frameCounter   atomic.Uint64
-	debugInterval  int
+	fftFunc          *fourier.FFT
+	magnitudes       *buffer.Float64DoubleBuffer
+	prevMagnitudes   []float64
+	inputBuffer      []float64
+	normalized       []float64 // Scratch for Process and ProcessFloat32.
+	fftOutput        []complex128
+	window           []float64
+	frequencyBins    []float64
+	spectralFlux     *buffer.Float64DoubleBuffer
+	phases           *buffer.Float64DoubleBuffer // Nil unless SetPhaseOutput is enabled.
+	prevPhases       []float64
+	prevPrevPhases   []float64
+	prevComplexMags  []float64
+	inputRMS         float64
+	inputPeak        float64
+	clipCount        int
+	preemphasis      float64
+	gain             float64   // Linear input gain, see SetInputGain.
+	noiseProfile     []float64 // Per-bin noise magnitude, nil when noise reduction is off.
+	noiseSum         []float64 // Magnitude sums while calibrating.
+	noiseFrames      int       // Calibration length in frames.
+	noiseFramesLeft  int
+	noiseFloor       float64
+	prevSample       float64
+	fftInputScale    float64
+	psdScale         float64 // Window power normalization, see windowPSDScale.
+	sampleRate       float64
+	fftSize          int
+	normFactor       float64
+	frameCounter     atomic.Uint64
+	debugInterval    int
+	fluxMode         FluxMode
+	onsetMethod      OnsetMethod
+	magnitudeScaling MagnitudeScaling
+	fluxNormalize    bool // Flux is relative to the frame's magnitudes, see SetFluxNormalization.
+	spectrumType     SpectrumType
 }
@@ -0,0 +1,569 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFFTProcessor_BinRange(t *testing.T) {
+	// 256-point FFT at 25.6kHz gives 100 Hz/bin and 129 bins (0-12.8kHz).
+	p, err := NewFFTProcessor(256, 25600, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+
+	testCases := []struct {
+		name     string
+		lowFreq  float64
+		highFreq float64
+		expectLo int
+		expectHi int
+	}{
+		{"Full range", 0, 0, 0, 129},
+		{"Low cut only", 20, 0, 1, 129},
+		{"Band", 150, 1000, 2, 11},
+		{"Exact bin edges", 100, 200, 1, 3},
+		{"Above Nyquist", 20000, 30000, 129, 129},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lo, hi := p.BinRange(tc.lowFreq, tc.highFreq)
+			assert.Equal(t, tc.expectLo, lo, "lo mismatch")
+			assert.Equal(t, tc.expectHi, hi, "hi mismatch")
+		})
+	}
+}
+
+func TestNewFFTProcessor_InvalidArguments(t *testing.T) {
+	testCases := []struct {
+		name       string
+		size       int
+		sampleRate float64
+	}{
+		{"Size not a power of two", 1000, 44100},
+		{"Zero sample rate", 1024, 0},
+		{"Negative sample rate", 1024, -44100},
+		{"NaN sample rate", 1024, math.NaN()},
+		{"Infinite sample rate", 1024, math.Inf(1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewFFTProcessor(tc.size, tc.sampleRate, Hann)
+			assert.Error(t, err, "NewFFTProcessor should fail")
+			assert.Nil(t, p, "No processor should be returned")
+		})
+	}
+}
+
+func TestFFTProcessor_SelfTest(t *testing.T) {
+	p, err := NewFFTProcessor(1024, 44100, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+
+	assert.NoError(t, p.SelfTest([]float64{110, 440, 1000, 5000}), "SelfTest should pass for a valid configuration")
+	assert.NoError(t, p.SelfTest([]float64{30000}), "Frequencies above Nyquist should be skipped")
+}
+
+func TestFFTProcessor_FindPeaks(t *testing.T) {
+	const sampleRate = 44100.0
+	const size = 1024
+
+	p, err := NewFFTProcessor(size, sampleRate, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+
+	// Two partials, the 3kHz one at half the amplitude of the 1kHz one.
+	input := make([]int32, size)
+	for i := range input {
+		ts := float64(i) / sampleRate
+		sample := 0.5*math.Sin(2*math.Pi*1000*ts) + 0.25*math.Sin(2*math.Pi*3000*ts)
+		input[i] = int32(sample * math.MaxInt32)
+	}
+	p.Process(input)
+
+	resolution := p.GetFrequencyResolution()
+	peaks := p.FindPeaks(2, 100, 0.01)
+
+	require.Len(t, peaks, 2, "Expected two peaks")
+	assert.InDelta(t, 1000, peaks[0].Freq, resolution/2, "Loudest peak should be near 1kHz")
+	assert.InDelta(t, 3000, peaks[1].Freq, resolution/2, "Second peak should be near 3kHz")
+	assert.Greater(t, peaks[0].Magnitude, peaks[1].Magnitude, "Peaks should be ordered by magnitude")
+
+	assert.Len(t, p.FindPeaks(1, 100, 0.01), 1, "Should return at most n peaks")
+	assert.Empty(t, p.FindPeaks(2, 100, 10), "No peaks should exceed an unreachable threshold")
+}
+
+func TestFFTProcessor_Preemphasis(t *testing.T) {
+	const size = 256
+
+	// A constant input is pure DC, which pre-emphasis attenuates by (1 - a)
+	// once the filter state has been carried over from the previous buffer.
+	input := make([]int32, size)
+	for i := range input {
+		input[i] = math.MaxInt32 / 2
+	}
+
+	plain, err := NewFFTProcessor(size, 44100, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	plain.Process(input)
+	dc := plain.GetMagnitudes()[0]
+	require.Greater(t, dc, 0.0, "Unfiltered DC magnitude should be non-zero")
+
+	emphasized, err := NewFFTProcessor(size, 44100, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	emphasized.SetPreemphasis(0.97)
+	emphasized.Process(input)
+	emphasized.Process(input)
+
+	assert.InDelta(t, dc*0.03, emphasized.GetMagnitudes()[0], dc*1e-6, "DC should be attenuated by 1 - a")
+	assert.InDelta(t, 0.5, emphasized.prevSample, 1e-6, "Filter state should hold the last input sample")
+}
+
+func TestFFTProcessor_PreemphasisWithGain(t *testing.T) {
+	const size = 256
+
+	// A tone that does not complete a whole number of cycles per buffer, so
+	// the last sample of the first buffer differs from the first of the next.
+	input := make([]float64, size)
+	for i := range input {
+		input[i] = 0.25 * math.Sin(2*math.Pi*float64(i)/100)
+	}
+	gain := math.Pow(10, 6.0/20)
+	gained := make([]float64, size)
+	for i, v := range input {
+		gained[i] = v * gain
+	}
+
+	// Gaining the input must match gaining the samples before the processor,
+	// including the filter state carried over to the second buffer. Hamming is
+	// non-zero at the first sample, so a wrong state shows in the spectrum.
+	reference, err := NewFFTProcessor(size, 44100, Hamming)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	reference.SetPreemphasis(0.97)
+	reference.ProcessFloat64(gained)
+	reference.ProcessFloat64(gained)
+
+	p, err := NewFFTProcessor(size, 44100, Hamming)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	p.SetPreemphasis(0.97)
+	p.SetInputGain(6)
+	p.ProcessFloat64(input)
+	p.ProcessFloat64(input)
+
+	assert.InDelta(t, reference.prevSample, p.prevSample, 1e-12, "Filter state should hold the gained last sample")
+	assert.InDeltaSlice(t, reference.GetMagnitudes(), p.GetMagnitudes(), 1e-9, "The second buffer should be filtered against the gained state")
+
+	// An empty buffer keeps the gained state as it is.
+	p.ProcessFloat64(nil)
+	assert.InDelta(t, reference.prevSample, p.prevSample, 1e-12, "An empty buffer should not change the filter state")
+}
+
+func TestFFTProcessor_MagnitudeScaling(t *testing.T) {
+	const size = 256
+	const sampleRate = 25600.0 // 100 Hz/bin.
+
+	input := make([]int32, size)
+	for i := range input {
+		input[i] = int32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/sampleRate) * math.MaxInt32)
+	}
+
+	peak := func(scaling MagnitudeScaling) float64 {
+		p, err := NewFFTProcessor(size, sampleRate, Hann)
+		require.NoError(t, err, "NewFFTProcessor should succeed")
+		p.SetMagnitudeScaling(scaling)
+		p.Process(input)
+		return p.GetMagnitudes()[10]
+	}
+
+	raw := peak(ScalingRaw)
+	require.Greater(t, raw, 0.0, "The 1kHz bin should carry energy")
+	assert.InDelta(t, 2*raw, peak(ScalingSingleSided), 1e-12, "Single-sided should double interior bins")
+	assert.InDelta(t, 4*raw*raw, peak(ScalingPower), 1e-12, "Power should square the single-sided magnitude")
+}
+
+func TestFFTProcessor_SpectrumType(t *testing.T) {
+	const size = 256
+	const sampleRate = 25600.0 // 100 Hz/bin.
+	const amplitude = 0.5
+
+	input := make([]int32, size)
+	for i := range input {
+		input[i] = int32(amplitude * math.Sin(2*math.Pi*1000*float64(i)/sampleRate) * math.MaxInt32)
+	}
+
+	spectrum := func(window WindowFunc, spectrumType SpectrumType) []float64 {
+		p, err := NewFFTProcessor(size, sampleRate, window)
+		require.NoError(t, err, "NewFFTProcessor should succeed")
+		p.SetMagnitudeScaling(ScalingPower) // Only applies to SpectrumMagnitude.
+		p.SetSpectrumType(spectrumType)
+		p.Process(input)
+		return p.GetMagnitudes()
+	}
+
+	singleSidedPower := spectrum(Hann, SpectrumMagnitude)[10]
+	assert.InDelta(t, singleSidedPower/4, spectrum(Hann, SpectrumPower)[10], 1e-12, "Power should not double interior bins")
+
+	// Parseval: the PSD summed over the bins is the signal power, A²/2 for a
+	// sine, whatever the window.
+	for _, window := range []WindowFunc{Hann, Hamming, BlackmanNuttall} {
+		var total float64
+		for _, psd := range spectrum(window, SpectrumPSD) {
+			total += psd * sampleRate / size
+		}
+		assert.InDelta(t, amplitude*amplitude/2, total, 1e-3, "The PSD should integrate to the signal power with %s", window)
+	}
+}
+
+func TestFFTProcessor_FluxNormalization(t *testing.T) {
+	const size = 256
+	const sampleRate = 25600.0
+
+	// Silence, then a 1kHz tone: an onset whose flux scales with the level.
+	onsetFlux := func(amplitude float64, normalize bool) float64 {
+		p, err := NewFFTProcessor(size, sampleRate, Hann)
+		require.NoError(t, err, "NewFFTProcessor should succeed")
+		p.SetFluxNormalization(normalize)
+		p.Process(make([]int32, size))
+
+		input := make([]int32, size)
+		for i := range input {
+			input[i] = int32(amplitude * math.Sin(2*math.Pi*1000*float64(i)/sampleRate) * math.MaxInt32)
+		}
+		p.Process(input)
+		return p.GetSpectralFluxInRange(0, sampleRate/2)
+	}
+
+	loud, quiet := onsetFlux(0.5, false), onsetFlux(0.05, false)
+	assert.InDelta(t, 10, loud/quiet, 1e-6, "Without normalization the flux should follow the level")
+
+	loud, quiet = onsetFlux(0.5, true), onsetFlux(0.05, true)
+	assert.Greater(t, loud, 0.0, "The onset should produce flux")
+	assert.InDelta(t, loud, quiet, loud*1e-6, "Normalized flux should not depend on the level")
+
+	p, err := NewFFTProcessor(size, sampleRate, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	p.SetFluxNormalization(true)
+	p.Process(make([]int32, size))
+	p.Process(make([]int32, size))
+	assert.Zero(t, p.GetSpectralFluxInRange(0, sampleRate/2), "Silence should not produce flux")
+}
+
+func TestFFTProcessor_FluxNormalizationLog(t *testing.T) {
+	const size = 256
+	const sampleRate = 25600.0
+
+	// Silence, then a 1kHz tone at a fixed level, with the input gain scaled.
+	onsetFlux := func(gainDB float64) float64 {
+		p, err := NewFFTProcessor(size, sampleRate, Hann)
+		require.NoError(t, err, "NewFFTProcessor should succeed")
+		p.SetFluxMode(FluxLog)
+		p.SetFluxNormalization(true)
+		p.SetInputGain(gainDB)
+		p.Process(make([]int32, size))
+
+		input := make([]int32, size)
+		for i := range input {
+			input[i] = int32(0.05 * math.Sin(2*math.Pi*1000*float64(i)/sampleRate) * math.MaxInt32)
+		}
+		p.Process(input)
+		return p.GetSpectralFluxInRange(0, sampleRate/2)
+	}
+
+	// log1p compresses the loud frame, a linear magnitude sum would not.
+	loud, quiet := onsetFlux(20), onsetFlux(0)
+	assert.Greater(t, loud, 0.0, "The onset should produce flux")
+	assert.InDelta(t, loud, quiet, loud*1e-6, "Normalized log flux should not depend on the input gain")
+}
+
+func TestFFTProcessor_FluxFloor(t *testing.T) {
+	p, err := NewFFTProcessor(256, 25600, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	magnitude := p.fluxFloor()
+	assert.Greater(t, magnitude, 0.0)
+
+	p.SetSpectrumType(SpectrumPower)
+	assert.InDelta(t, fluxNormFloor*fluxNormFloor, p.fluxFloor(), 1e-18, "A power spectrum should square the floor")
+
+	p.SetSpectrumType(SpectrumMagnitude)
+	p.SetFluxMode(FluxLog)
+	assert.InDelta(t, math.Log1p(magnitude), p.fluxFloor(), 1e-15, "Log flux should compress the floor")
+}
+
+func TestFFTProcessor_Clipping(t *testing.T) {
+	const size = 256
+
+	p, err := NewFFTProcessor(size, 44100, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+
+	input := make([]int32, size)
+	for i := range input {
+		input[i] = math.MaxInt32 / 2
+	}
+	p.Process(input)
+	assert.Zero(t, p.GetClipCount(), "Half-scale input should not clip")
+	assert.InDelta(t, 0.5, p.GetInputPeak(), 1e-6, "Peak should track the largest sample")
+
+	input[10] = math.MaxInt32
+	input[20] = math.MinInt32
+	input[30] = math.MaxInt32 >> 16 << 16 // Full-scale int16 converted to int32.
+	p.Process(input)
+	assert.Equal(t, 3, p.GetClipCount(), "Samples at full scale of either sign should clip")
+	assert.InDelta(t, 1.0, p.GetInputPeak(), 1e-6, "Peak should reach full scale")
+}
+
+func TestFFTProcessor_InputGain(t *testing.T) {
+	const size = 256
+
+	samples := make([]float64, size)
+	for i := range samples {
+		samples[i] = 0.25 * math.Sin(2*math.Pi*float64(i)*16/size)
+	}
+	samples[0] = 1 // Clips in the converter, whatever the gain.
+
+	analyze := func(gainDB float64) (*FFTProcessor, []float64) {
+		p, err := NewFFTProcessor(size, 44100, Hann)
+		require.NoError(t, err, "NewFFTProcessor should succeed")
+		p.SetInputGain(gainDB)
+		p.ProcessFloat64(samples)
+		return p, p.GetMagnitudes()
+	}
+
+	unity, unityMags := analyze(0)
+	gained, gainedMags := analyze(20 * math.Log10(2))
+
+	assert.InDelta(t, 2*unityMags[16], gainedMags[16], 1e-9, "+6 dB should double the magnitudes")
+	assert.InDelta(t, 2*unity.GetInputRMS(), gained.GetInputRMS(), 1e-9, "The RMS should include the gain")
+	assert.InDelta(t, 2.0, gained.GetInputPeak(), 1e-9, "The peak should include the gain")
+	assert.Equal(t, 1, unity.GetClipCount())
+	assert.Equal(t, 1, gained.GetClipCount(), "Clipping should be detected before the gain")
+}
+
+func TestFFTProcessor_ProcessFloat(t *testing.T) {
+	const size = 256
+	const sampleRate = 25600.0
+
+	ints := make([]int32, size)
+	floats := make([]float64, size)
+	floats32 := make([]float32, size)
+	for i := range ints {
+		ints[i] = int32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/sampleRate) * math.MaxInt32)
+		floats[i] = float64(ints[i]) / float64(0x80000000)
+		floats32[i] = float32(floats[i])
+	}
+
+	analyze := func(process func(p *FFTProcessor)) []float64 {
+		p, err := NewFFTProcessor(size, sampleRate, Hann)
+		require.NoError(t, err, "NewFFTProcessor should succeed")
+		p.SetPreemphasis(0.97)
+		process(p)
+		process(p)
+		return append([]float64(nil), p.GetMagnitudes()...)
+	}
+
+	expected := analyze(func(p *FFTProcessor) { p.Process(ints) })
+	assert.InDeltaSlice(t, expected, analyze(func(p *FFTProcessor) { p.ProcessFloat64(floats) }), 1e-12,
+		"ProcessFloat64 should match Process on the same normalized signal")
+	assert.InDeltaSlice(t, expected, analyze(func(p *FFTProcessor) { p.ProcessFloat32(floats32) }), 1e-6,
+		"ProcessFloat32 should match Process within float32 precision")
+
+	p, err := NewFFTProcessor(size, sampleRate, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	allocs := testing.AllocsPerRun(10, func() { p.Process(ints) })
+	assert.Zero(t, allocs, "Process should not allocate")
+}
+
+func TestFFTProcessor_SpectralFluxConcurrentRead(t *testing.T) {
+	p, err := NewFFTProcessor(256, 44100, Hann)
+	require.NoError(t, err)
+
+	buffer := make([]int32, 256)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for frame := 0; frame < 200; frame++ {
+			for i := range buffer {
+				buffer[i] = int32((frame % 2) * (i % 7) << 24)
+			}
+			p.Process(buffer)
+		}
+	}()
+
+	// Run with -race: reading while Process writes must not race, and every
+	// copy must be a complete frame.
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			assert.Len(t, p.GetSpectralFlux(), 129, "Readers should get a complete copy of the flux")
+			p.GetSpectralFluxInRange(0, 22050)
+		}
+	}
+}
+
+func TestFFTProcessor_AppendSpectralFlux(t *testing.T) {
+	p, err := NewFFTProcessor(256, 44100, Hann)
+	require.NoError(t, err)
+	p.Process(make([]int32, 256))
+	buffer := make([]int32, 256)
+	for i := range buffer {
+		buffer[i] = int32((i % 7) << 24)
+	}
+	p.Process(buffer)
+
+	dst := make([]float64, 0, 129)
+	dst = p.AppendSpectralFlux(dst, 10, 20)
+	assert.Equal(t, p.GetSpectralFlux()[10:20], dst, "The appended flux should be the requested bins")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = p.AppendSpectralFlux(dst[:0], 0, 129)
+	})
+	assert.Zero(t, allocs, "Appending into a reused buffer should not allocate")
+}
+
+func TestFFTProcessor_NoiseReduction(t *testing.T) {
+	const (
+		size       = 256
+		sampleRate = 44100.0
+		humBin     = 10
+		toneBin    = 40
+	)
+
+	// Bin-centered sines with a whole number of cycles per buffer, so every
+	// buffer has the same spectrum.
+	signal := func(bins ...int) []float64 {
+		samples := make([]float64, size)
+		for i := range samples {
+			for _, bin := range bins {
+				samples[i] += 0.25 * math.Sin(2*math.Pi*float64(bin*i)/size)
+			}
+		}
+		return samples
+	}
+	hum, humAndTone := signal(humBin), signal(humBin, toneBin)
+
+	p, err := NewFFTProcessor(size, sampleRate, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	p.SetNoiseReduction(4, 0.05)
+
+	for range 4 {
+		p.ProcessFloat64(hum)
+	}
+	calibrated := p.GetMagnitudes()[humBin]
+	assert.Greater(t, calibrated, 0.0, "Calibration frames should pass through unchanged")
+
+	p.ProcessFloat64(hum)
+	assert.InDelta(t, calibrated*0.05, p.GetMagnitudes()[humBin], calibrated*1e-6, "The learned noise should be reduced to the floor")
+
+	p.ProcessFloat64(humAndTone)
+	assert.InDelta(t, calibrated, p.GetMagnitudes()[toneBin], calibrated*0.01, "A new tone should not be attenuated")
+
+	p.SetNoiseReduction(0, 0)
+	p.ProcessFloat64(hum)
+	assert.InDelta(t, calibrated, p.GetMagnitudes()[humBin], calibrated*1e-6, "Disabling should restore the plain magnitudes")
+}
+
+func TestFFTProcessor_PhaseOutput(t *testing.T) {
+	const (
+		size = 256
+		bin  = 16
+	)
+
+	p, err := NewFFTProcessor(size, 44100, Hann)
+	require.NoError(t, err, "NewFFTProcessor should succeed")
+	assert.Nil(t, p.GetPhases(), "Phases should be nil unless enabled")
+	p.SetPhaseOutput(true)
+
+	phaseOf := func(offset float64) float64 {
+		samples := make([]float64, size)
+		for i := range samples {
+			samples[i] = 0.5 * math.Cos(2*math.Pi*float64(bin*i)/size+offset)
+		}
+		p.ProcessFloat64(samples)
+		phases := p.GetPhases()
+		require.Len(t, phases, size/2+1)
+		return phases[bin]
+	}
+
+	// The window adds the same phase to both, only the difference is fixed.
+	cosine := phaseOf(0)
+	sine := phaseOf(-math.Pi / 2)
+	assert.InDelta(t, -math.Pi/2, sine-cosine, 1e-3, "A sine should lag a cosine by pi/2")
+}
+
+func TestFFTProcessor_ComplexDeviationPhaseChange(t *testing.T) {
+	const size = 256
+	const sampleRate = 25600.0
+
+	// A 1kHz tone completes 10 cycles per buffer, so its phase is the same at
+	// the start of every buffer. Inverting it changes only the phase, the
+	// magnitudes stay exactly the same.
+	tone := func(sign float64) []int32 {
+		input := make([]int32, size)
+		for i := range input {
+			input[i] = int32(sign * 0.5 * math.Sin(2*math.Pi*1000*float64(i)/sampleRate) * math.MaxInt32)
+		}
+		return input
+	}
+
+	framesSinceOnset := func(method OnsetMethod) int64 {
+		p, err := NewFFTProcessor(size, sampleRate, Hann)
+		require.NoError(t, err, "NewFFTProcessor should succeed")
+		p.SetOnsetMethod(method)
+		bd := NewBPMDetector(sampleRate, size)
+		bd.SetFluxBand(0, size/2+1)
+
+		frame := uint64(0)
+		for range 40 {
+			frame++
+			p.Process(tone(1))
+			bd.ProcessFlux(p.GetSpectralFlux(), frame)
+		}
+		require.Equal(t, int64(-1), bd.FramesSinceOnset(), "A steady tone should not produce an onset with %s", method)
+
+		frame++
+		p.Process(tone(-1))
+		bd.ProcessFlux(p.GetSpectralFlux(), frame)
+		return bd.FramesSinceOnset()
+	}
+
+	assert.Equal(t, int64(-1), framesSinceOnset(OnsetFlux), "Magnitude flux should miss a phase-only change")
+	assert.Equal(t, int64(0), framesSinceOnset(OnsetComplex), "Complex deviation should detect a phase-only change")
+}
+
+func TestFFTProcessor_FluxMode(t *testing.T) {
+	const size = 256
+	const sampleRate = 25600.0
+
+	tone := func(amplitude float64) []int32 {
+		input := make([]int32, size)
+		for i := range input {
+			input[i] = int32(amplitude * math.Sin(2*math.Pi*1000*float64(i)/sampleRate) * math.MaxInt32)
+		}
+		return input
+	}
+
+	// A step from a quiet to a loud 1kHz tone, far above the 200Hz bass
+	// weighting, so the flux of every bin is the unweighted difference.
+	step := func(mode FluxMode) (before, after, flux []float64) {
+		p, err := NewFFTProcessor(size, sampleRate, Hann)
+		require.NoError(t, err, "NewFFTProcessor should succeed")
+		p.SetFluxMode(mode)
+		p.Process(tone(0.1))
+		before = p.GetMagnitudes()
+		p.Process(tone(0.8))
+		return before, p.GetMagnitudes(), p.GetSpectralFlux()
+	}
+
+	before, after, linear := step(FluxLinear)
+	_, _, logFlux := step(FluxLog)
+	const peak = 10 // 1kHz at 100 Hz/bin.
+	require.Greater(t, after[peak], before[peak], "The step should raise the peak bin")
+
+	assert.InDelta(t, after[peak]-before[peak], linear[peak], 1e-12, "Linear flux should be the magnitude difference")
+	assert.InDelta(t, math.Log1p(after[peak])-math.Log1p(before[peak]), logFlux[peak], 1e-12,
+		"Log flux should be the difference of log1p magnitudes")
+	assert.Less(t, logFlux[peak], linear[peak], "Log flux should compress the step")
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ParseFluxMode converts a string name (case-insensitive) to a FluxMode enum,
+// returns a known default (FluxLinear) and an error if the name is unknown.
+func ParseFluxMode(name string) (FluxMode, error) {
+	switch strings.ToLower(name) {
+	case "linear":
+		return FluxLinear, nil
+	case "log":
+		return FluxLog, nil
+	default:
+		return FluxLinear, fmt.Errorf("unknown flux mode name: '%s'", name)
+	}
+}
+
+// normalizeFlux divides flux by base, the summed magnitudes of the same frame in
+// the domain the flux is taken from (log1p-compressed with FluxLog). The sum
+// rather than the energy is used because the flux is a difference of those
+// magnitudes, so both scale with the input gain alike. Below floor the divisor
+// is held at the floor, so the noise of a near-silent input is not amplified
+// into onsets.
+func normalizeFlux(flux []float64, base, floor float64) {
+	scale := 1 / max(base, floor)
+	for i := range flux {
+		flux[i] *= scale
+	}
+}
+
+// fluxFloor returns fluxNormFloor in the units the flux is taken from: it
+// is scaled like the magnitude of an interior bin, so power and PSD spectra get
+// a squared floor, and log1p-compressed with FluxLog.
+func (p *FFTProcessor) fluxFloor() float64 {
+	floor := p.scaleMagnitude(1, fluxNormFloor)
+	if p.fluxMode == FluxLog && p.onsetMethod != OnsetComplex {
+		floor = math.Log1p(floor)
+	}
+	return floor
+}
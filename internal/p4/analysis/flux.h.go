@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import "fmt"
+
+// fluxNormFloor is the smallest magnitude sum normalizeFlux divides by, as the
+// magnitude of a normalized bin, see FFTProcessor.fluxFloor.
+const fluxNormFloor = 1e-3
+
+type FluxMode int
+
+const (
+	FluxLinear FluxMode = iota
+	FluxLog
+)
+
+// String returns the string representation of the FluxMode.
+func (m FluxMode) String() string {
+	switch m {
+	case FluxLinear:
+		return "linear"
+	case FluxLog:
+		return "log"
+	default:
+		return fmt.Sprintf("UnknownFluxMode(%d)", int(m))
+	}
+}
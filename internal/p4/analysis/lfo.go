@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ParseLFOWaveform converts a string name (case-insensitive) to an LFOWaveform
+// enum, returns a known default (LFOSine) and an error if the name is unknown.
+func ParseLFOWaveform(name string) (LFOWaveform, error) {
+	switch strings.ToLower(name) {
+	case "sine":
+		return LFOSine, nil
+	case "triangle":
+		return LFOTriangle, nil
+	case "ramp":
+		return LFORamp, nil
+	default:
+		return LFOSine, fmt.Errorf("unknown LFO waveform name: '%s'", name)
+	}
+}
+
+// Value returns the LFO in [0, 1] at a beat position from
+// BPMDetector.BeatPosition. Every waveform is 0 at the start of a cycle: the
+// sine and triangle peak halfway, the ramp rises to 1 at the end. It is -1 for
+// a negative position, i.e. without a tempo.
+func (l LFO) Value(position float64) float64 {
+	if position < 0 || l.Beats <= 0 {
+		return -1
+	}
+
+	cycle := position / l.Beats
+	phase := cycle - math.Floor(cycle)
+	switch l.Waveform {
+	case LFOTriangle:
+		return 1 - math.Abs(2*phase-1)
+	case LFORamp:
+		return phase
+	default:
+		return 0.5 - 0.5*math.Cos(2*math.Pi*phase)
+	}
+}
@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import "fmt"
+
+type LFOWaveform int
+
+const (
+	LFOSine LFOWaveform = iota
+	LFOTriangle
+	LFORamp
+)
+
+// String returns the string representation of the LFOWaveform.
+func (w LFOWaveform) String() string {
+	switch w {
+	case LFOSine:
+		return "sine"
+	case LFOTriangle:
+		return "triangle"
+	case LFORamp:
+		return "ramp"
+	default:
+		return fmt.Sprintf("UnknownLFOWaveform(%d)", int(w))
+	}
+}
+
+// LFO is a low-frequency oscillator locked to the beat, one cycle lasts Beats
+// beats, e.g. 0.5 for eighth notes or 4 for a bar of 4/4.
+type LFO struct {
+	Waveform LFOWaveform
+	Beats    float64
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLFOWaveform(t *testing.T) {
+	for _, w := range []LFOWaveform{LFOSine, LFOTriangle, LFORamp} {
+		parsed, err := ParseLFOWaveform(w.String())
+		assert.NoError(t, err)
+		assert.Equal(t, w, parsed, "The name should round-trip")
+	}
+
+	parsed, err := ParseLFOWaveform("square")
+	assert.Error(t, err, "An unknown name should be rejected")
+	assert.Equal(t, LFOSine, parsed, "An unknown name should fall back to sine")
+}
+
+func TestLFO_Value(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lfo      LFO
+		position float64
+		expected float64
+	}{
+		{"SineStart", LFO{LFOSine, 1}, 3, 0},
+		{"SinePeak", LFO{LFOSine, 1}, 3.5, 1},
+		{"SineQuarter", LFO{LFOSine, 1}, 0.25, 0.5},
+		{"TrianglePeak", LFO{LFOTriangle, 1}, 0.5, 1},
+		{"TriangleFalling", LFO{LFOTriangle, 1}, 0.75, 0.5},
+		{"Ramp", LFO{LFORamp, 1}, 2.25, 0.25},
+		{"EighthNotes", LFO{LFORamp, 0.5}, 1.25, 0.5},
+		{"Bar", LFO{LFORamp, 4}, 6, 0.5},
+		{"NoTempo", LFO{LFOSine, 1}, -1, -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, tc.lfo.Value(tc.position), 1e-9)
+		})
+	}
+}
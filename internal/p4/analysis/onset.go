@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"fmt"
+	"math/cmplx"
+	"strings"
+)
+
+// ParseOnsetMethod converts a string name (case-insensitive) to an OnsetMethod
+// enum, returns a known default (OnsetFlux) and an error if the name is unknown.
+func ParseOnsetMethod(name string) (OnsetMethod, error) {
+	switch strings.ToLower(name) {
+	case "flux":
+		return OnsetFlux, nil
+	case "complex":
+		return OnsetComplex, nil
+	default:
+		return OnsetFlux, fmt.Errorf("unknown onset method name: '%s'", name)
+	}
+}
+
+// complexDeviation returns the rectified complex-domain onset strength for bin i.
+// The expected value of the bin is predicted from the previous frame's magnitude
+// and a linearly extrapolated phase (2*phi[n-1] - phi[n-2]); the distance between
+// the prediction and the observed value is the deviation. Only rising energy is
+// counted, so decays and note releases do not register as onsets. The phase history
+// for the bin is advanced as a side effect.
+func (p *FFTProcessor) complexDeviation(i int, mag float64) float64 {
+	phase := cmplx.Phase(p.fftOutput[i])
+	prevMag := p.prevComplexMags[i]
+
+	predictedPhase := 2*p.prevPhases[i] - p.prevPrevPhases[i]
+	predicted := cmplx.Rect(prevMag, predictedPhase)
+	observed := cmplx.Rect(mag, phase)
+
+	p.prevPrevPhases[i] = p.prevPhases[i]
+	p.prevPhases[i] = phase
+	p.prevComplexMags[i] = flushDenormal(mag)
+
+	if mag < prevMag {
+		return 0.0
+	}
+	return cmplx.Abs(observed - predicted)
+}
@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import "fmt"
+
+type OnsetMethod int
+
+const (
+	OnsetFlux OnsetMethod = iota
+	OnsetComplex
+)
+
+// String returns the string representation of the OnsetMethod.
+func (m OnsetMethod) String() string {
+	switch m {
+	case OnsetFlux:
+		return "flux"
+	case OnsetComplex:
+		return "complex"
+	default:
+		return fmt.Sprintf("UnknownOnsetMethod(%d)", int(m))
+	}
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseMagnitudeScaling converts a string name (case-insensitive) to a
+// MagnitudeScaling enum, returns a known default (ScalingSingleSided) and an
+// error if the name is unknown.
+func ParseMagnitudeScaling(name string) (MagnitudeScaling, error) {
+	switch strings.ToLower(name) {
+	case "single_sided":
+		return ScalingSingleSided, nil
+	case "raw":
+		return ScalingRaw, nil
+	case "power":
+		return ScalingPower, nil
+	default:
+		return ScalingSingleSided, fmt.Errorf("unknown magnitude scaling name: '%s'", name)
+	}
+}
+
+// ParseSpectrumType converts a string name (case-insensitive) to a
+// SpectrumType enum, returns a known default (SpectrumMagnitude) and an error if
+// the name is unknown.
+func ParseSpectrumType(name string) (SpectrumType, error) {
+	switch strings.ToLower(name) {
+	case "magnitude":
+		return SpectrumMagnitude, nil
+	case "power":
+		return SpectrumPower, nil
+	case "psd":
+		return SpectrumPSD, nil
+	default:
+		return SpectrumMagnitude, fmt.Errorf("unknown spectrum type name: '%s'", name)
+	}
+}
+
+// windowPSDScale returns the factor that turns the squared normalized
+// magnitude of a bin into a two-sided power spectral density in full scale²/Hz:
+// N²/(fs·Σw²). Dividing by the window power Σw² rather than the coherent gain
+// (Σw)² keeps the total power of broadband signals independent of the window.
+func windowPSDScale(window []float64, sampleRate float64) float64 {
+	var power float64
+	for _, w := range window {
+		power += w * w
+	}
+	if power == 0 {
+		return 0
+	}
+	n := float64(len(window))
+	return n * n / (sampleRate * power)
+}
+
+// scaleMagnitude applies the configured scaling to the normalized magnitude of
+// bin i. Single-sided scaling doubles the interior bins (all but DC and Nyquist)
+// to account for the discarded negative frequencies, raw leaves the magnitude
+// as is, and power returns the square of the single-sided magnitude. A spectrum
+// type other than SpectrumMagnitude replaces the scaling: power is the squared
+// magnitude without any correction, PSD the single-sided power spectral density.
+func (p *FFTProcessor) scaleMagnitude(i int, mag float64) float64 {
+	switch p.spectrumType {
+	case SpectrumPower:
+		return mag * mag
+	case SpectrumPSD:
+		psd := mag * mag * p.psdScale
+		if i > 0 && i < p.fftSize/2 {
+			psd *= 2.0
+		}
+		return psd
+	}
+	if p.magnitudeScaling == ScalingRaw {
+		return mag
+	}
+	if i > 0 && i < p.fftSize/2 {
+		mag *= 2.0
+	}
+	if p.magnitudeScaling == ScalingPower {
+		return mag * mag
+	}
+	return mag
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import "fmt"
+
+type MagnitudeScaling int
+
+const (
+	ScalingSingleSided MagnitudeScaling = iota
+	ScalingRaw
+	ScalingPower
+)
+
+// String returns the string representation of the MagnitudeScaling.
+func (s MagnitudeScaling) String() string {
+	switch s {
+	case ScalingSingleSided:
+		return "single_sided"
+	case ScalingRaw:
+		return "raw"
+	case ScalingPower:
+		return "power"
+	default:
+		return fmt.Sprintf("UnknownMagnitudeScaling(%d)", int(s))
+	}
+}
+
+// SpectrumType selects the quantity reported per bin, see scaleMagnitude.
+type SpectrumType int
+
+const (
+	SpectrumMagnitude SpectrumType = iota
+	SpectrumPower
+	SpectrumPSD
+)
+
+// String returns the string representation of the SpectrumType.
+func (t SpectrumType) String() string {
+	switch t {
+	case SpectrumMagnitude:
+		return "magnitude"
+	case SpectrumPower:
+		return "power"
+	case SpectrumPSD:
+		return "psd"
+	default:
+		return fmt.Sprintf("UnknownSpectrumType(%d)", int(t))
+	}
+}
@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"phase4/pkg/simd"
+	"sync"
+)
+
+// scratchPool holds aligned float64 buffers for temporary per-call results, so
+// analysis methods that need a working slice do not allocate on every call.
+// Pointers are pooled rather than slices so Put does not allocate either.
+var scratchPool = sync.Pool{
+	New: func() any {
+		return new([]float64)
+	},
+}
+
+// getScratch borrows a zeroed, aligned scratch buffer of length n. The caller
+// must return it with putScratch and must not retain (*buf) afterwards.
+func getScratch(n int) *[]float64 {
+	buf := scratchPool.Get().(*[]float64)
+	if cap(*buf) < n {
+		*buf = simd.AlignedFloat64(n)
+	}
+	*buf = (*buf)[:n]
+	clear(*buf)
+	return buf
+}
+
+// putScratch returns a buffer borrowed with getScratch to the pool.
+func putScratch(buf *[]float64) {
+	scratchPool.Put(buf)
+}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScratch(t *testing.T) {
+	buf := getScratch(100)
+	assert.Len(t, *buf, 100, "The buffer should have the requested length")
+	assert.Zero(t, uintptr(unsafe.Pointer(&(*buf)[0]))%16, "The buffer should be aligned")
+	(*buf)[0] = 1
+	putScratch(buf)
+
+	buf = getScratch(50)
+	assert.Len(t, *buf, 50, "A smaller request should be served from a larger buffer")
+	assert.Zero(t, (*buf)[0], "A reused buffer should be zeroed")
+	putScratch(buf)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		putScratch(getScratch(100))
+	})
+	assert.Zero(t, allocs, "Borrowing and returning a buffer should not allocate")
+}
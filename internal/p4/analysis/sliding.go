@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"phase4/pkg/simd"
+)
+
+// NewSlidingFFT creates an FFT of size that analyzes the most recent size
+// samples on every Push rather than each buffer on its own. An FFT larger than
+// the audio buffer then gains real frequency resolution instead of zero-padding
+// the buffer, at the cost of a longer analysis window, while still updating at
+// the buffer rate.
+func NewSlidingFFT(size int, sampleRate float64, windowType WindowFunc) (*SlidingFFT, error) {
+	p, err := NewFFTProcessor(size, sampleRate, windowType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SlidingFFT{
+		FFTProcessor: p,
+		history:      simd.AlignedFloat64(size),
+	}, nil
+}
+
+// Push appends inputBuffer to the sample history, dropping the oldest samples,
+// and analyzes the updated window. Until size samples have been pushed the
+// window starts with silence. Pre-emphasis is not supported, as consecutive
+// windows overlap.
+func (s *SlidingFFT) Push(inputBuffer []int32) {
+	n := len(inputBuffer)
+	if n >= len(s.history) {
+		inputBuffer = inputBuffer[n-len(s.history):]
+		n = len(s.history)
+	} else {
+		copy(s.history, s.history[n:])
+	}
+
+	dst := s.history[len(s.history)-n:]
+	for i := 0; i < n; i++ {
+		dst[i] = float64(inputBuffer[i]) * s.normFactor
+	}
+	s.ProcessFloat64(s.history)
+}
@@ -0,0 +1,9 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+// SlidingFFT is an FFTProcessor fed with the most recent fftSize samples, see
+// NewSlidingFFT.
+type SlidingFFT struct {
+	*FFTProcessor
+	history []float64 // Last fftSize samples, normalized, oldest first.
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingFFT_Push(t *testing.T) {
+	const (
+		sampleRate = 44100.0
+		size       = 4096
+		bufferSize = 256
+		freq       = 1000.0
+	)
+
+	s, err := NewSlidingFFT(size, sampleRate, Hann)
+	require.NoError(t, err, "NewSlidingFFT should succeed for a power of two")
+
+	// Feed a continuous tone in small buffers, once the history is full the
+	// spectrum has the resolution of the large FFT.
+	buffer := make([]int32, bufferSize)
+	for frame := 0; frame < size/bufferSize; frame++ {
+		for i := range buffer {
+			n := frame*bufferSize + i
+			buffer[i] = int32(0.5 * math.MaxInt32 * math.Sin(2*math.Pi*freq*float64(n)/sampleRate))
+		}
+		s.Push(buffer)
+	}
+
+	assert.Len(t, s.GetMagnitudes(), size/2+1, "The spectrum should have the bins of the large FFT")
+	peak, _ := s.FindPeakFrequency()
+	assert.InDelta(t, freq, peak, sampleRate/size, "The peak should be within one bin of the large FFT")
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"math"
+)
+
+// octaveTolerance is how far, in octaves, a tempo jump may be from exactly
+// half or double tempo to count as a correction.
+const octaveTolerance = 0.05
+
+func newBPMStats() *bpmStats {
+	return &bpmStats{histogram: make(map[int]uint64)}
+}
+
+// add records one frame with the reported tempo, 0 if there is none yet.
+func (s *bpmStats) add(bpm float64, locked bool) {
+	s.frames++
+	if locked {
+		s.lockedFrames++
+	}
+	if bpm <= 0 {
+		return
+	}
+
+	s.tempoFrames++
+	s.sum += bpm
+	s.sumSquares += bpm * bpm
+	s.histogram[int(math.Round(bpm))]++
+
+	if s.lastBPM > 0 && bpm != s.lastBPM {
+		if math.Abs(math.Abs(math.Log2(bpm/s.lastBPM))-1) <= octaveTolerance {
+			s.corrections++
+		}
+	}
+	s.lastBPM = bpm
+}
+
+func (s *bpmStats) summary() BPMStats {
+	stats := BPMStats{
+		Frames:      s.frames,
+		TempoFrames: s.tempoFrames,
+		Corrections: s.corrections,
+	}
+	if s.frames > 0 {
+		stats.LockedFraction = float64(s.lockedFrames) / float64(s.frames)
+	}
+	if s.tempoFrames == 0 {
+		return stats
+	}
+
+	n := float64(s.tempoFrames)
+	stats.MeanBPM = s.sum / n
+	stats.StdDev = math.Sqrt(max(s.sumSquares/n-stats.MeanBPM*stats.MeanBPM, 0))
+
+	// The lowest tempo wins a tie, so the mode does not depend on map order.
+	var modeFrames uint64
+	for bpm, frames := range s.histogram {
+		if frames > modeFrames || (frames == modeFrames && float64(bpm) < stats.ModeBPM) {
+			stats.ModeBPM, modeFrames = float64(bpm), frames
+		}
+	}
+
+	return stats
+}
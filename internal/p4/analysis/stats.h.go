@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+// BPMStats summarizes the tempo reported by a BPMDetector over a session, see
+// BPMDetector.EnableStats.
+type BPMStats struct {
+	// ModeBPM is the most often reported tempo rounded to whole BPM, and MeanBPM
+	// and StdDev the mean and standard deviation of the reported tempo. All
+	// three only count frames with a tempo estimate.
+	ModeBPM float64
+	MeanBPM float64
+	StdDev  float64
+	// LockedFraction is the fraction of all frames with a locked tempo.
+	LockedFraction float64
+	// Frames is the number of frames accumulated, TempoFrames those with a
+	// tempo estimate.
+	Frames      uint64
+	TempoFrames uint64
+	// Corrections counts half or double tempo jumps between consecutive
+	// estimates, i.e. octave errors being made or corrected.
+	Corrections int
+}
+
+// bpmStats accumulates BPMStats one frame at a time.
+type bpmStats struct {
+	histogram    map[int]uint64 // Frames per tempo rounded to whole BPM.
+	sum          float64
+	sumSquares   float64
+	lastBPM      float64
+	frames       uint64
+	tempoFrames  uint64
+	lockedFrames uint64
+	corrections  int
+}
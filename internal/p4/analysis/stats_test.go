@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBPMStats(t *testing.T) {
+	s := newBPMStats()
+	s.add(0, false) // No estimate yet.
+	for range 6 {
+		s.add(120, true)
+	}
+	s.add(60, false) // Octave error.
+	s.add(120, true) // Corrected.
+	s.add(124, true) // Drift, not a correction.
+
+	stats := s.summary()
+	assert.Equal(t, uint64(10), stats.Frames)
+	assert.Equal(t, uint64(9), stats.TempoFrames)
+	assert.Equal(t, 120.0, stats.ModeBPM, "The most reported tempo should be the mode")
+	assert.InDelta(t, 1024.0/9, stats.MeanBPM, 1e-9)
+	assert.InDelta(t, 19.05, stats.StdDev, 0.01)
+	assert.InDelta(t, 0.8, stats.LockedFraction, 1e-9)
+	assert.Equal(t, 2, stats.Corrections, "The jump to half tempo and back should count as corrections")
+
+	assert.Equal(t, BPMStats{}, newBPMStats().summary(), "No frames should give zero stats")
+}
+
+func TestBPMDetector_Stats(t *testing.T) {
+	bd := NewBPMDetector(44100, 512)
+	_, ok := bd.Stats()
+	assert.False(t, ok, "Stats should be disabled by default")
+
+	bd.EnableStats()
+	flux := make([]float64, 1)
+	for frame := uint64(1); frame <= 20*43; frame++ {
+		flux[0] = 0
+		if frame%43 == 0 {
+			flux[0] = 1
+		}
+		bd.ProcessFlux(flux, frame)
+	}
+
+	stats, ok := bd.Stats()
+	require.True(t, ok, "Stats should be enabled")
+	assert.Equal(t, uint64(20*43), stats.Frames, "Every frame should be counted")
+	assert.InDelta(t, 120, stats.ModeBPM, 1.5, "The mode should be the detected tempo")
+	assert.Greater(t, stats.LockedFraction, 0.0, "A steady beat should lock")
+	assert.Zero(t, stats.Corrections, "A steady beat should not jump octaves")
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"phase4/pkg/simd"
+	"strings"
+)
+
+const (
+	// Tempo range searched by the autocorrelation estimator.
+	autocorrMinBPM = 60.0
+	autocorrMaxBPM = 200.0
+
+	// Centre (BPM) and width (octaves) of the log-Gaussian tempo prior that
+	// weights the autocorrelation, it breaks ties between a tempo and its
+	// half/double in favour of the one closer to a typical beat rate.
+	autocorrPriorBPM    = 120.0
+	autocorrPriorWidth  = 1.0
+	autocorrMinFrames   = 2 // Minimum envelope length, in multiples of the longest lag.
+	autocorrMinStrength = 0.05
+)
+
+// ParseBPMMethod converts a string name (case-insensitive) to a BPMMethod enum,
+// returns a known default (BPMHistogram) and an error if the name is unknown.
+func ParseBPMMethod(name string) (BPMMethod, error) {
+	switch strings.ToLower(name) {
+	case "histogram":
+		return BPMHistogram, nil
+	case "autocorrelation":
+		return BPMAutocorrelation, nil
+	default:
+		return BPMHistogram, fmt.Errorf("unknown bpm method name: '%s'", name)
+	}
+}
+
+// SetMethod selects the tempo estimator. It must be called before the first
+// call to ProcessFlux.
+func (bd *BPMDetector) SetMethod(method BPMMethod) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.method = method
+	if method != BPMAutocorrelation {
+		return
+	}
+
+	// Lags are in frames (buffers) of the onset envelope.
+	framePeriod := float64(bd.framesPerBuffer) / bd.sampleRate
+	bd.minLag = max(1, int(math.Floor(60.0/(autocorrMaxBPM*framePeriod))))
+	bd.maxLag = int(math.Ceil(60.0 / (autocorrMinBPM * framePeriod)))
+	bd.autocorr = make([]float64, bd.maxLag+2)
+
+	// Short buffers need a longer envelope than the default to cover
+	// autocorrMinFrames of the longest lag, without it no estimate is made.
+	if n := autocorrMinFrames * bd.maxLag; n > len(bd.onsetBuffer) {
+		bd.onsetBuffer = simd.AlignedFloat64(n)
+		bd.onsetBufferLen, bd.onsetHead = 0, 0
+	}
+	bd.envelope = make([]float64, len(bd.onsetBuffer))
+}
+
+// calculateBPMAutocorrelation estimates the tempo from the periodicity of the
+// onset-strength envelope held in onsetBuffer. The envelope is mean-removed and
+// half-wave rectified, autocorrelated over the lags covering 60-200 BPM, and the
+// strongest lag after the tempo prior is refined by parabolic interpolation.
+// Confidence is the normalized autocorrelation at that lag.
+func (bd *BPMDetector) calculateBPMAutocorrelation() {
+	n := bd.onsetBufferLen
+	if bd.maxLag == 0 || n < autocorrMinFrames*bd.maxLag {
+		return
+	}
+
+	mean := 0.0
+	for i := 0; i < n; i++ {
+		mean += bd.onsetAt(i)
+	}
+	mean /= float64(n)
+
+	energy := 0.0
+	for i := 0; i < n; i++ {
+		v := max(bd.onsetAt(i)-mean, 0)
+		bd.envelope[i] = v
+		energy += v * v
+	}
+	if energy == 0 {
+		return
+	}
+	energy /= float64(n)
+
+	// Unbiased autocorrelation, normalized by the zero-lag energy.
+	for lag := bd.minLag - 1; lag <= bd.maxLag+1; lag++ {
+		if lag < 1 {
+			continue
+		}
+		sum := 0.0
+		for i := lag; i < n; i++ {
+			sum += bd.envelope[i] * bd.envelope[i-lag]
+		}
+		bd.autocorr[lag] = sum / float64(n-lag) / energy
+	}
+
+	// A tempo hint moves the prior to the centre of the hinted range and
+	// favours the lags within it, see SetTempoHint.
+	priorBPM := autocorrPriorBPM
+	if bd.hintHi > 0 {
+		priorBPM = math.Sqrt(bd.hintLo * bd.hintHi)
+	}
+
+	framePeriod := float64(bd.framesPerBuffer) / bd.sampleRate
+	bestLag, bestScore := 0, 0.0
+	for lag := bd.minLag; lag <= bd.maxLag; lag++ {
+		bpm := 60.0 / (float64(lag) * framePeriod)
+		octaves := math.Log2(bpm / priorBPM)
+		prior := math.Exp(-0.5 * (octaves / autocorrPriorWidth) * (octaves / autocorrPriorWidth))
+		if bd.hintHi > 0 {
+			prior *= bd.rangeBonus(bpm)
+		}
+		if score := bd.autocorr[lag] * prior; score > bestScore {
+			bestLag, bestScore = lag, score
+		}
+	}
+	if bestLag == 0 || bd.autocorr[bestLag] < autocorrMinStrength {
+		return
+	}
+
+	lag := float64(bestLag)
+	if bestLag > 1 {
+		a, b, c := bd.autocorr[bestLag-1], bd.autocorr[bestLag], bd.autocorr[bestLag+1]
+		if denom := a - 2*b + c; denom < 0 {
+			lag += 0.5 * (a - c) / denom
+		}
+	}
+
+	bpm := 60.0 / (lag * framePeriod)
+	bd.setTempo(math.Round(bpm*2)/2, bd.autocorr[bestLag], bd.autocorr[bestLag])
+}
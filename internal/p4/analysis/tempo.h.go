@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import "fmt"
+
+type BPMMethod int
+
+const (
+	BPMHistogram BPMMethod = iota
+	BPMAutocorrelation
+)
+
+// String returns the string representation of the BPMMethod.
+func (m BPMMethod) String() string {
+	switch m {
+	case BPMHistogram:
+		return "histogram"
+	case BPMAutocorrelation:
+		return "autocorrelation"
+	default:
+		return fmt.Sprintf("UnknownBPMMethod(%d)", int(m))
+	}
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBPMMethod(t *testing.T) {
+	testCases := []struct {
+		name      string
+		expected  BPMMethod
+		expectErr bool
+	}{
+		{"histogram", BPMHistogram, false},
+		{"Autocorrelation", BPMAutocorrelation, false},
+		{"comb", BPMHistogram, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			method, err := ParseBPMMethod(tc.name)
+			assert.Equal(t, tc.expected, method, "Method mismatch")
+			if tc.expectErr {
+				assert.Error(t, err, "Expected an error for unknown name")
+			} else {
+				assert.NoError(t, err, "Expected no error")
+			}
+		})
+	}
+}
+
+func TestBPMDetector_Autocorrelation(t *testing.T) {
+	const (
+		sampleRate      = 44100.0
+		framesPerBuffer = 512
+		tempo           = 120.0
+	)
+
+	bd := NewBPMDetector(sampleRate, framesPerBuffer)
+	bd.SetMethod(BPMAutocorrelation)
+
+	// Onset envelope with an impulse on every beat, quantized to the nearest
+	// buffer like a real flux signal.
+	framePeriod := framesPerBuffer / sampleRate
+	beatPeriod := 60.0 / tempo
+	nextBeat := 0.0
+	flux := make([]float64, 1)
+	for frame := uint64(1); frame <= 1000; frame++ {
+		flux[0] = 0
+		if now := float64(frame) * framePeriod; now >= nextBeat {
+			flux[0] = 1
+			nextBeat += beatPeriod
+		}
+		bd.ProcessFlux(flux, frame)
+	}
+
+	bpm, confidence := bd.GetBPM()
+	require.NotZero(t, bpm, "Expected a tempo estimate")
+	assert.InDelta(t, tempo, bpm, 2, "Estimated tempo should match the impulse train")
+	assert.Greater(t, confidence, 0.5, "A regular impulse train should give a confident estimate")
+}
+
+func TestBPMDetector_AutocorrelationShortBuffers(t *testing.T) {
+	const (
+		sampleRate      = 44800.0
+		framesPerBuffer = 64 // 60 BPM is a lag of 700 buffers.
+		beatFrames      = 350
+	)
+
+	bd := NewBPMDetector(sampleRate, framesPerBuffer)
+	bd.SetMethod(BPMAutocorrelation)
+	require.GreaterOrEqual(t, len(bd.onsetBuffer), autocorrMinFrames*bd.maxLag, "The envelope should cover the longest lag")
+
+	flux := make([]float64, 1)
+	for frame := uint64(1); frame <= 3*autocorrMinFrames*700; frame++ {
+		flux[0] = 0
+		if frame%beatFrames == 0 {
+			flux[0] = 1
+		}
+		bd.ProcessFlux(flux, frame)
+	}
+
+	bpm, _ := bd.GetBPM()
+	require.NotZero(t, bpm, "Short buffers should still give a tempo estimate")
+	assert.InDelta(t, 120, bpm, 2, "Estimated tempo should match the impulse train")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"phase4/internal/app/errors"
+	"strings"
 
 	"github.com/gordonklaus/portaudio"
 )
@@ -76,18 +77,19 @@ func selectInputDevice(e *Engine) error {
 
 	// Fallback (if allowed), when the ID is out of range or the selected
 	// device is not an input device. If more input channels have been requested
-	// than are available, fallback to the devices max input channels.
+	// than the selected device has, fallback to the devices max input channels.
 	if deviceID == defaultDeviceID || deviceID >= len(e.audio.devices) {
 		deviceID = defaultDeviceID
 	}
 	if deviceID > defaultDeviceID {
 		device := e.audio.devices[deviceID]
-		if device.MaxInputChannels > 0 {
-			if e.config.Input.Channels > device.MaxInputChannels {
-				log.Printf("Engine ➜ Warning ➜ Requested %d channels but device only supports %d",
-					e.config.Input.Channels, device.MaxInputChannels)
-				e.config.Input.Channels = device.MaxInputChannels
+		if !matchesHostApi(device.HostApi, e.config.Input.HostApi) {
+			log.Printf("Engine ➜ Warning ➜ Device %d (%s) is not on host API %q",
+				deviceID, device.Name, e.config.Input.HostApi)
+			if e.config.Input.UseDefaultDevice {
+				deviceID = defaultDeviceID
 			}
+		} else if device.MaxInputChannels > 0 {
 			e.audio.inputDevice = device
 		} else {
 			if e.config.Input.UseDefaultDevice {
@@ -96,7 +98,15 @@ func selectInputDevice(e *Engine) error {
 		}
 	}
 
-	if deviceID == defaultDeviceID && e.config.Input.UseDefaultDevice {
+	if deviceID == defaultDeviceID && e.config.Input.UseDefaultDevice && e.config.Input.HostApi != "" {
+		// The system default may be on another backend, use the default input
+		// of the requested host API instead.
+		api := findHostApi(e.audio.devices, e.config.Input.HostApi)
+		if api == nil || api.DefaultInputDevice == nil {
+			return fmt.Errorf("no default input device for host API %q", e.config.Input.HostApi)
+		}
+		e.audio.inputDevice = api.DefaultInputDevice
+	} else if deviceID == defaultDeviceID && e.config.Input.UseDefaultDevice {
 		device, err := e.audio.client.DefaultInputDevice()
 		if err != nil {
 			return &errors.FatalError{
@@ -112,9 +122,81 @@ func selectInputDevice(e *Engine) error {
 			deviceID, e.config.Input.UseDefaultDevice)
 	}
 
+	// The clamped count is what the stream is opened with, the analysis and
+	// the payload follow input.channels.
+	if deviceMax := e.audio.inputDevice.MaxInputChannels; deviceMax > 0 && e.config.Input.Channels > deviceMax {
+		log.Printf("Engine ➜ Warning ➜ Requested %d channels but device only supports %d",
+			e.config.Input.Channels, deviceMax)
+		e.config.Input.Channels = deviceMax
+	}
+
+	return nil
+}
+
+// matchesHostApi reports whether api is selected by the input.host_api name,
+// which is compared case-insensitively against the API type (e.g. "WASAPI") and
+// its display name (e.g. "Windows WASAPI"). An empty name matches every API.
+func matchesHostApi(api *portaudio.HostApiInfo, name string) bool {
+	if name == "" {
+		return true
+	}
+	if api == nil {
+		return false
+	}
+	name = strings.ToLower(name)
+	return strings.ToLower(api.Type.String()) == name || strings.Contains(strings.ToLower(api.Name), name)
+}
+
+// findHostApi returns the host API of the first device matching name, or nil.
+func findHostApi(devices []*portaudio.DeviceInfo, name string) *portaudio.HostApiInfo {
+	for _, device := range devices {
+		if matchesHostApi(device.HostApi, name) {
+			return device.HostApi
+		}
+	}
 	return nil
 }
 
+// listDevices formats the available devices, grouped by host API, into a
+// *errors.CommandCompleted and terminates PortAudio, the engine is not usable
+// afterwards.
+func listDevices(e *Engine) error {
+	// Host APIs are listed in the order PortAudio reports their first device.
+	var apis []*portaudio.HostApiInfo
+	byApi := make(map[*portaudio.HostApiInfo][]*portaudio.DeviceInfo)
+	for _, device := range e.audio.devices {
+		if _, ok := byApi[device.HostApi]; !ok {
+			apis = append(apis, device.HostApi)
+		}
+		byApi[device.HostApi] = append(byApi[device.HostApi], device)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d audio device(s) found:\n", len(e.audio.devices))
+	for _, api := range apis {
+		if api != nil {
+			fmt.Fprintf(&sb, "%s (host_api: %q)\n", api.Name, api.Type.String())
+		} else {
+			sb.WriteString("Unknown host API\n")
+		}
+		for _, device := range byApi[api] {
+			marker := ""
+			if api != nil && device == api.DefaultInputDevice {
+				marker = " *default input*"
+			}
+			fmt.Fprintf(&sb, "  [%d] %s (in: %d, out: %d, %.0f Hz)%s\n",
+				device.Index, device.Name,
+				device.MaxInputChannels, device.MaxOutputChannels, device.DefaultSampleRate, marker)
+		}
+	}
+
+	if err := exitPA(e); err != nil {
+		return err
+	}
+
+	return &errors.CommandCompleted{Message: strings.TrimSuffix(sb.String(), "\n")}
+}
+
 func printInputDevice(device *portaudio.DeviceInfo) {
 	if device == nil {
 		log.Print("Engine ➜ No input device selected.")
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"fmt"
+	"phase4/internal/app/config"
+	"phase4/internal/app/errors"
+	"testing"
+
+	"github.com/gordonklaus/portaudio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockEngine(client *mockPaClient, input config.InputConfig) *Engine {
+	return &Engine{
+		audio:  &pa{client: client, devices: client.DevicesResult},
+		config: &config.Config{Input: input},
+	}
+}
+
+func TestInitPA(t *testing.T) {
+	devices := []*portaudio.DeviceInfo{{Name: "Mic", MaxInputChannels: 2}}
+
+	t.Run("Success", func(t *testing.T) {
+		client := &mockPaClient{DevicesResult: devices}
+		e := &Engine{audio: &pa{client: client}}
+
+		require.NoError(t, initPA(e))
+		assert.True(t, e.audio.initialized, "PortAudio should be marked initialized")
+		assert.Equal(t, devices, e.audio.devices, "The devices should be stored")
+
+		client.InitializeCalled = false
+		require.NoError(t, initPA(e))
+		assert.False(t, client.InitializeCalled, "A second call should not initialize again")
+	})
+
+	t.Run("InitializeError", func(t *testing.T) {
+		client := &mockPaClient{InitializeErr: fmt.Errorf("boom")}
+		e := &Engine{audio: &pa{client: client}}
+
+		assert.ErrorIs(t, initPA(e), client.InitializeErr)
+		assert.False(t, e.audio.initialized, "A failed initialization should not be recorded")
+		assert.False(t, client.TerminateCalled, "Nothing should be terminated")
+	})
+
+	testCases := []struct {
+		name         string
+		client       *mockPaClient
+		expectSubstr string
+	}{
+		{"DevicesError", &mockPaClient{DevicesErr: fmt.Errorf("boom")}, "failed to get audio devices"},
+		{"NoDevices", &mockPaClient{}, "no audio devices found"},
+		{"TerminateError", &mockPaClient{DevicesErr: fmt.Errorf("boom"), TerminateErr: fmt.Errorf("stuck")}, "additionally failed to terminate"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &Engine{audio: &pa{client: tc.client}}
+
+			err := initPA(e)
+
+			var fatalErr *errors.FatalError
+			require.ErrorAs(t, err, &fatalErr, "The error should be fatal")
+			assert.Contains(t, fatalErr.Message, tc.expectSubstr)
+			assert.True(t, tc.client.TerminateCalled, "PortAudio should be terminated after a failure")
+			assert.Nil(t, e.audio.devices, "No devices should be stored")
+		})
+	}
+}
+
+func TestSelectInputDevice(t *testing.T) {
+	wasapi := &portaudio.HostApiInfo{Name: "Windows WASAPI"}
+	mme := &portaudio.HostApiInfo{Name: "MME"}
+	mic := &portaudio.DeviceInfo{Name: "Mic", Index: 0, MaxInputChannels: 2, HostApi: mme}
+	speakers := &portaudio.DeviceInfo{Name: "Speakers", Index: 1, MaxOutputChannels: 2, HostApi: mme}
+	line := &portaudio.DeviceInfo{Name: "Line", Index: 2, MaxInputChannels: 8, HostApi: wasapi}
+	fallback := &portaudio.DeviceInfo{Name: "Default", MaxInputChannels: 1, HostApi: mme}
+	wasapi.DefaultInputDevice = line
+	devices := []*portaudio.DeviceInfo{mic, speakers, line}
+
+	testCases := []struct {
+		name           string
+		input          config.InputConfig
+		defaultErr     error
+		expectDevice   *portaudio.DeviceInfo
+		expectChannels int
+		expectDefault  bool
+		expectErr      bool
+		expectFatal    bool
+	}{
+		{
+			name:           "SelectedDevice",
+			input:          config.InputConfig{Device: 0, Channels: 1},
+			expectDevice:   mic,
+			expectChannels: 1,
+		},
+		{
+			name:           "ChannelClamping",
+			input:          config.InputConfig{Device: 0, Channels: 4},
+			expectDevice:   mic,
+			expectChannels: 2,
+		},
+		{
+			name:           "DefaultDevice",
+			input:          config.InputConfig{Device: -1, Channels: 1, UseDefaultDevice: true},
+			expectDevice:   fallback,
+			expectChannels: 1,
+			expectDefault:  true,
+		},
+		{
+			name:           "DefaultDeviceChannelClamping",
+			input:          config.InputConfig{Device: -1, Channels: 2, UseDefaultDevice: true},
+			expectDevice:   fallback,
+			expectChannels: 1,
+			expectDefault:  true,
+		},
+		{
+			name:           "OutOfRangeFallback",
+			input:          config.InputConfig{Device: 7, Channels: 1, UseDefaultDevice: true},
+			expectDevice:   fallback,
+			expectChannels: 1,
+			expectDefault:  true,
+		},
+		{
+			name:      "OutOfRangeNoFallback",
+			input:     config.InputConfig{Device: 7, Channels: 1},
+			expectErr: true,
+		},
+		{
+			name:           "OutputOnlyFallback",
+			input:          config.InputConfig{Device: 1, Channels: 1, UseDefaultDevice: true},
+			expectDevice:   fallback,
+			expectChannels: 1,
+			expectDefault:  true,
+		},
+		{
+			name:      "OutputOnlyNoFallback",
+			input:     config.InputConfig{Device: 1, Channels: 1},
+			expectErr: true,
+		},
+		{
+			name:           "HostApiMismatchFallback",
+			input:          config.InputConfig{Device: 0, Channels: 1, HostApi: "wasapi", UseDefaultDevice: true},
+			expectDevice:   line,
+			expectChannels: 1,
+		},
+		{
+			name:      "HostApiMismatchNoFallback",
+			input:     config.InputConfig{Device: 0, Channels: 1, HostApi: "wasapi"},
+			expectErr: true,
+		},
+		{
+			name:      "UnknownHostApi",
+			input:     config.InputConfig{Device: -1, Channels: 1, HostApi: "asio", UseDefaultDevice: true},
+			expectErr: true,
+		},
+		{
+			name:          "DefaultDeviceError",
+			input:         config.InputConfig{Device: -1, Channels: 1, UseDefaultDevice: true},
+			defaultErr:    fmt.Errorf("no default device"),
+			expectDefault: true,
+			expectErr:     true,
+			expectFatal:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockPaClient{
+				DevicesResult:            devices,
+				DefaultInputDeviceResult: fallback,
+				DefaultInputDeviceErr:    tc.defaultErr,
+			}
+			e := newMockEngine(client, tc.input)
+
+			err := selectInputDevice(e)
+
+			assert.Equal(t, tc.expectDefault, client.DefaultInputDeviceCalled, "Unexpected default device lookup")
+			if tc.expectErr {
+				require.Error(t, err)
+				var fatalErr *errors.FatalError
+				if tc.expectFatal {
+					assert.ErrorAs(t, err, &fatalErr, "The error should be fatal")
+				} else {
+					assert.NotErrorAs(t, err, &fatalErr, "The error should not be fatal")
+				}
+				assert.Nil(t, e.audio.inputDevice, "No device should be selected")
+				return
+			}
+			require.NoError(t, err)
+			assert.Same(t, tc.expectDevice, e.audio.inputDevice, "Unexpected input device")
+			assert.Equal(t, tc.expectChannels, e.config.Input.Channels, "Unexpected channel count")
+		})
+	}
+}
+
+func TestInitialize_ChannelClamping(t *testing.T) {
+	mono := &portaudio.DeviceInfo{Name: "Mono", MaxInputChannels: 1, HostApi: &portaudio.HostApiInfo{Name: "ALSA"}}
+	e := NewEngine(&config.Config{
+		Input: config.InputConfig{Device: 0, Channels: 2, SampleRate: 44100, BufferSize: 256, Source: "device"},
+		DSP:   config.DSPConfig{FFTWindow: "Hann", AsyncAnalysis: true},
+	})
+	e.audio.client = &mockPaClient{DevicesResult: []*portaudio.DeviceInfo{mono}}
+	defer e.Close()
+
+	require.NoError(t, e.Initialize())
+
+	assert.Same(t, mono, e.audio.inputDevice, "The mono device should be selected")
+	assert.Equal(t, 1, e.config.Input.Channels, "The channel count should be clamped to the device")
+	assert.Equal(t, 256, e.analysisRing.FrameSize(), "The analysis should be sized for the clamped channel count")
+	assert.Equal(t, 1, e.streamParameters().Input.Channels, "The stream should be opened with the clamped channel count")
+}
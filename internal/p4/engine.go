@@ -4,6 +4,9 @@ package p4
 import (
 	"context"
 	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
 	"phase4/internal/app/config"
 	"phase4/internal/app/errors"
 	"phase4/internal/p4/analysis"
@@ -11,6 +14,19 @@ import (
 	"phase4/internal/p4/runtime/pipeline"
 	"phase4/internal/p4/runtime/stage"
 	"phase4/internal/p4/transport"
+	"phase4/pkg/buffer"
+	"time"
+)
+
+// analysisRingSlots is the number of audio buffers the async analysis ring can
+// hold before the callback starts dropping frames.
+const analysisRingSlots = 8
+
+// controlSendTimeout bounds how long a control message waits for mailbox space,
+// and controlCapacity is the mailbox size of the control component.
+const (
+	controlSendTimeout = time.Second
+	controlCapacity    = 16
 )
 
 // NewEngine creates a new audio engine instance with the provided configuration.
@@ -28,6 +44,7 @@ func engine(cfg *config.Config) *Engine {
 		closables: make([]interface{ Close() error }, 0),
 		ctx:       ctx,
 		cancel:    cancel,
+		done:      make(chan struct{}),
 		system:    stage.NewSystem(),
 		audio: &pa{
 			client:      newEnginePaClient(),
@@ -36,24 +53,90 @@ func engine(cfg *config.Config) *Engine {
 	}
 }
 
+// SetListDevices makes Initialize list the available audio devices and return an
+// *errors.CommandCompleted instead of initializing the engine.
+func (e *Engine) SetListDevices(enabled bool) {
+	e.command.ListDevices = enabled
+}
+
+// SetTune makes Initialize measure the stream timing at the configured buffer
+// size and return an *errors.CommandCompleted with a buffer size recommendation
+// instead of initializing the engine.
+func (e *Engine) SetTune(enabled bool) {
+	e.command.Tune = enabled
+}
+
+// SetTransform inserts a stage applying fn to every frame between the processor
+// and the router, see pipeline.TransformFunc. It must be called before
+// Initialize, nil removes the stage.
+func (e *Engine) SetTransform(fn pipeline.TransformFunc) {
+	e.transform = fn
+}
+
 func (e *Engine) Initialize() error {
-	if err := e.initializePortAudio(); err != nil {
-		return err
+	// Commands always operate on the audio devices, even with a file or null input.
+	command := e.command.ListDevices || e.command.Tune
+	fileInput := e.config.Input.File != "" && !command
+	replayInput := e.config.Input.Source == "replay" && !fileInput && !command
+	e.nullInput = e.config.Input.Source == "null" && !fileInput && !command
+
+	if fileInput {
+		if err := e.openFileInput(); err != nil {
+			return err
+		}
+	} else if replayInput {
+		if err := e.openReplayInput(); err != nil {
+			return err
+		}
+	} else if !e.nullInput {
+		if err := e.initializePortAudio(); err != nil {
+			if command {
+				return err
+			}
+			if err := e.useNullInput(err); err != nil {
+				return err
+			}
+		} else if e.command.ListDevices {
+			return listDevices(e)
+		} else if e.command.Tune {
+			if err := e.selectAndConfigureDevice(); err != nil {
+				return err
+			}
+			return tuneBufferSize(e)
+		} else if err := e.selectAndConfigureDevice(); err != nil {
+			if err := e.useNullInput(err); err != nil {
+				return err
+			}
+		}
 	}
+	// The device is selected first, so the analysis is sized for the channel
+	// count the device was clamped to.
 	if err := e.initializeAnalysis(); err != nil {
 		return err
 	}
-	if err := e.initializeSystem(); err != nil {
-		return err
+	if e.audio.inputDevice != nil && !e.nullInput {
+		if err := e.initializeSources(); err != nil {
+			return err
+		}
 	}
-	if err := e.selectAndConfigureDevice(); err != nil {
+	if err := e.initializeSystem(); err != nil {
 		return err
 	}
 	return nil
 }
 
+// Done is closed when the input has finished on its own, i.e. a file input
+// reached its end without input.file_loop. Live input never finishes.
+func (e *Engine) Done() <-chan struct{} {
+	return e.done
+}
+
 func (e *Engine) initializePortAudio() error {
-	if err := initPA(e); err != nil {
+	// Devices may not be enumerated yet right after boot, e.g. USB interfaces.
+	err := e.retryOpen(e.ctx, "PortAudio initialization", func(int) error {
+		return initPA(e)
+	})
+	if err != nil {
 		return &errors.FatalError{
 			Message: "failed to initialize PortAudio",
 			Err:     err,
@@ -63,9 +146,15 @@ func (e *Engine) initializePortAudio() error {
 }
 
 func (e *Engine) initializeAnalysis() error {
+	fftSize := e.config.DSP.FFTSize
+	if fftSize == 0 {
+		fftSize = e.config.Input.BufferSize
+	}
+	warnFFTSizeMismatch(fftSize, e.config.Input.BufferSize, e.config.Input.SampleRate)
+
 	fftWindowFunc, _ := analysis.ParseWindowFunc(e.config.DSP.FFTWindow)
 	fftProcessor, err := analysis.NewFFTProcessor(
-		e.config.Input.BufferSize,
+		fftSize,
 		e.config.Input.SampleRate,
 		fftWindowFunc,
 	)
@@ -75,24 +164,170 @@ func (e *Engine) initializeAnalysis() error {
 			Err:     err,
 		}
 	}
+	fluxMode, _ := analysis.ParseFluxMode(e.config.DSP.FluxMode)
+	fftProcessor.SetFluxMode(fluxMode)
+	fftProcessor.SetFluxNormalization(e.config.DSP.FluxNormalize)
+	onsetMethod, _ := analysis.ParseOnsetMethod(e.config.DSP.OnsetMethod)
+	fftProcessor.SetOnsetMethod(onsetMethod)
+	fftProcessor.SetPreemphasis(e.config.DSP.Preemphasis)
+	fftProcessor.SetInputGain(e.config.Input.GainDB)
+	fftProcessor.SetPhaseOutput(e.config.DSP.EmitPhase)
+	magnitudeScaling, _ := analysis.ParseMagnitudeScaling(e.config.DSP.MagnitudeScaling)
+	fftProcessor.SetMagnitudeScaling(magnitudeScaling)
+	spectrumType, _ := analysis.ParseSpectrumType(e.config.DSP.SpectrumType)
+	fftProcessor.SetSpectrumType(spectrumType)
+	if spectrumType != analysis.SpectrumMagnitude && magnitudeScaling != analysis.ScalingSingleSided {
+		log.Printf("Engine ➜ Warning ➜ dsp.magnitude_scaling '%s' is ignored with dsp.spectrum_type '%s'", magnitudeScaling, spectrumType)
+	}
+	e.outputBinLo, e.outputBinHi = fftProcessor.BinRange(e.config.DSP.OutputFreqMin, e.config.DSP.OutputFreqMax)
+	if e.outputBinLo == e.outputBinHi {
+		return &errors.FatalError{
+			Message: "output frequency range contains no FFT bins",
+			Err: fmt.Errorf("range %.2f-%.2f Hz at %.2f Hz/bin",
+				e.config.DSP.OutputFreqMin, e.config.DSP.OutputFreqMax, fftProcessor.GetFrequencyResolution()),
+		}
+	}
+	for _, size := range e.config.DSP.ExtraFFTSizes {
+		extra, err := analysis.NewSlidingFFT(size, e.config.Input.SampleRate, fftWindowFunc)
+		if err != nil {
+			return &errors.FatalError{
+				Message: "failed to create additional FFT resolution",
+				Err:     err,
+			}
+		}
+		extra.SetMagnitudeScaling(magnitudeScaling)
+		extra.SetSpectrumType(spectrumType)
+		extra.SetInputGain(e.config.Input.GainDB)
+		binLo, binHi := extra.BinRange(e.config.DSP.OutputFreqMin, e.config.DSP.OutputFreqMax)
+		e.extraFFTs = append(e.extraFFTs, extraFFT{fft: extra, size: size, binLo: binLo, binHi: binHi})
+		e.closables = append(e.closables, extra)
+	}
+	if e.config.DSP.SelfTestOnStart {
+		if err := fftProcessor.SelfTest([]float64{110, 440, 1000, 5000}); err != nil {
+			return &errors.FatalError{
+				Message: "FFT self-test failed",
+				Err:     err,
+			}
+		}
+	}
+	if e.config.DSP.NoiseReduction {
+		// Enabled after the self-test so its test tones do not become the profile.
+		fftProcessor.SetNoiseReduction(e.config.DSP.WarmupFrames, e.config.DSP.NoiseFloor)
+	}
+
+	e.analysisBudget = time.Duration(float64(e.config.Input.BufferSize) / e.config.Input.SampleRate * float64(time.Second))
+	stage.SetRawMessageCapacity(len(fftProcessor.GetFrequencyBins()))
+	pipeline.SetFFTDataCapacity(len(fftProcessor.GetFrequencyBins()))
 	e.fftProc = fftProcessor
 	e.closables = append(e.closables, fftProcessor)
 
+	if e.config.DSP.AsyncAnalysis {
+		e.analysisRing = buffer.NewInt32FrameRing(analysisRingSlots, e.config.Input.BufferSize*e.config.Input.Channels)
+		e.analysisReady = make(chan struct{}, 1)
+	} else if e.config.DSP.AnalysisPriority != 0 || len(e.config.DSP.AnalysisCPUs) > 0 {
+		log.Print("Engine ➜ Warning ➜ dsp.analysis_priority and dsp.analysis_cpus only apply with dsp.async_analysis")
+	}
+
 	e.bpmDetector = analysis.NewBPMDetector(
 		e.config.Input.SampleRate,
 		e.config.Input.BufferSize,
 	)
+	bpmMethod, _ := analysis.ParseBPMMethod(e.config.DSP.BPMMethod)
+	e.bpmDetector.SetMethod(bpmMethod)
+	confidenceMetric, _ := analysis.ParseConfidenceMetric(e.config.DSP.BPMConfidence)
+	e.bpmDetector.SetConfidenceMetric(confidenceMetric)
+	e.bpmDetector.SetConfidenceSmoothing(e.config.DSP.ConfidenceSmoothing)
+	e.bpmDetector.SetDisplayWindow(e.config.DSP.BPMDisplayWindow)
+	e.bpmDetector.SetHistogramResolution(e.config.DSP.BPMHistogramResolution)
+	e.bpmDetector.SetOnsetMemory(e.config.DSP.BPMOnsetMemory)
+	e.bpmDetector.SetEstimateInterval(e.config.DSP.BPMEstimateInterval)
+	if hint := e.config.DSP.BPMHintRange; len(hint) == 2 {
+		e.bpmDetector.SetTempoHint(hint[0], hint[1])
+	}
+	e.bpmDetector.SetPeakPicking(e.config.DSP.OnsetPreWindow, e.config.DSP.OnsetPostWindow)
+	e.bpmDetector.SetOnsetInterval(e.config.DSP.OnsetIntervalBeats)
+	if e.config.DSP.FixedBPM > 0 {
+		e.bpmDetector.SetFixedTempo(e.config.DSP.FixedBPM)
+		log.Printf("Engine ➜ Tempo fixed at %.2f BPM, onsets only align the beat phase", e.config.DSP.FixedBPM)
+	}
+	if e.config.DSP.BPMStats {
+		e.bpmDetector.EnableStats()
+	}
+	if e.config.DSP.LFO {
+		waveform, _ := analysis.ParseLFOWaveform(e.config.DSP.LFOWaveform)
+		e.lfo = &analysis.LFO{Waveform: waveform, Beats: e.config.DSP.LFOBeats}
+	}
+	if latency := e.bpmDetector.OnsetLatency(); latency > 0 {
+		log.Printf("Engine ➜ Onset peak-picking reports onsets %s late (%d frames)", latency, e.config.DSP.OnsetPostWindow)
+	}
+	fluxLo, fluxHi := fftProcessor.BinRange(e.config.DSP.BPMFreqLow, e.config.DSP.BPMFreqHigh)
+	if fluxLo == fluxHi {
+		return &errors.FatalError{
+			Message: "BPM frequency band contains no FFT bins",
+			Err: fmt.Errorf("range %.2f-%.2f Hz at %.2f Hz/bin",
+				e.config.DSP.BPMFreqLow, e.config.DSP.BPMFreqHigh, fftProcessor.GetFrequencyResolution()),
+		}
+	}
+	e.bpmDetector.SetFluxBand(fluxLo, fluxHi)
+	if e.config.DSP.BandOnsets {
+		bands := make([]analysis.OnsetBand, len(e.config.DSP.OnsetBands))
+		for i, band := range e.config.DSP.OnsetBands {
+			if lo, hi := fftProcessor.BinRange(band.Low, band.High); lo == hi {
+				log.Printf("Engine ➜ Warning ➜ dsp.onset_bands band '%s' (%.0f-%.0f Hz) contains no FFT bins at %.2f Hz/bin, it will not detect onsets",
+					band.Name, band.Low, band.High, fftProcessor.GetFrequencyResolution())
+			}
+			bands[i] = analysis.OnsetBand{Name: band.Name, Low: band.Low, High: band.High}
+		}
+		// The bands share the broadband detector's threshold and peak picking.
+		e.bandOnsets = analysis.NewBandOnsetDetector(bands, e.config.Input.SampleRate, e.config.Input.BufferSize)
+		e.bandOnsets.Configure(e.bpmDetector.Config())
+	}
 
 	return nil
 }
 
+// warnFFTSizeMismatch logs how the FFT relates to the audio buffer when their
+// sizes differ. A larger FFT zero-pads each buffer, which interpolates between
+// bins without adding real resolution. A smaller FFT only analyzes the first
+// fftSize samples of each buffer, the rest are discarded.
+func warnFFTSizeMismatch(fftSize, bufferSize int, sampleRate float64) {
+	switch {
+	case fftSize > bufferSize:
+		log.Printf("Engine ➜ Warning ➜ dsp.fft_size (%d) > input.buffer_size (%d), each buffer is zero-padded: "+
+			"bins are %.2f Hz apart but the effective resolution is %.2f Hz, increase input.buffer_size for real resolution",
+			fftSize, bufferSize, sampleRate/float64(fftSize), sampleRate/float64(bufferSize))
+	case fftSize < bufferSize:
+		log.Printf("Engine ➜ Warning ➜ dsp.fft_size (%d) < input.buffer_size (%d), only the first %d samples "+
+			"of each buffer are analyzed and %d are discarded",
+			fftSize, bufferSize, fftSize, bufferSize-fftSize)
+	}
+}
+
 func (e *Engine) initializeSystem() error {
 	routerTargets := []string{}
 	capacity := 2024
 
-	// Processor -> Router -> Transport
+	// Processor -> (Transform) -> Router -> Transport
 
-	processorComponent, err := pipeline.NewProcessor("processor", capacity, "router", e.system)
+	processorTarget := "router"
+	if e.transform != nil {
+		transformComponent, err := pipeline.NewTransform("transform", capacity, "router", e.transform, e.system)
+		if err != nil {
+			return &errors.FatalError{
+				Message: "failed to create TransformComponent",
+				Err:     err,
+			}
+		}
+		if err := e.system.Register(transformComponent); err != nil {
+			return &errors.FatalError{
+				Message: "failed to register TransformComponent",
+				Err:     err,
+			}
+		}
+		processorTarget = "transform"
+	}
+
+	processorComponent, err := pipeline.NewProcessor("processor", capacity, processorTarget, e.system)
 	if err != nil {
 		return &errors.FatalError{
 			Message: "failed to create ProcessorComponent",
@@ -106,27 +341,120 @@ func (e *Engine) initializeSystem() error {
 		}
 	}
 
-	if e.config.Transport.WebSocketEnabled {
-		wsTransport, err := transport.NewWebSocketTransport(
-			e.config.Transport.WebSocketAddress,
-			e.config.Transport.WebSocketPath,
-		)
+	// Control -> Analysis, runtime changes to the BPM detector.
+	if e.bpmDetector != nil {
+		controlComponent, err := pipeline.NewControl("control", controlCapacity, e.bpmDetector)
 		if err != nil {
 			return &errors.FatalError{
-				Message: "failed to create WebSocketTransport",
+				Message: "failed to create ControlComponent",
 				Err:     err,
 			}
 		}
-		e.closables = append(e.closables, wsTransport)
+		controlComponent.SetBandOnsets(e.bandOnsets)
+		if err := e.system.Register(controlComponent); err != nil {
+			return &errors.FatalError{
+				Message: "failed to register ControlComponent",
+				Err:     err,
+			}
+		}
+	}
+
+	endpoints := []struct {
+		id      string
+		enabled bool
+		init    func(id string, capacity int) error
+	}{
+		{"ws", e.config.Transport.WebSocketEnabled, e.initWebSocketEndpoint},
+		{"tcp", e.config.Transport.TCPEnabled, e.initTCPEndpoint},
+		{"udp", e.config.Transport.UDPEnabled, e.initUDPEndpoint},
+		{"mqtt", e.config.Transport.MQTTEnabled, e.initMQTTEndpoint},
+	}
+	for _, ep := range endpoints {
+		if !ep.enabled {
+			continue
+		}
+		if err := ep.init(ep.id, capacity); err != nil {
+			// A transport that cannot be created only takes down its own
+			// endpoint, analysis and the other transports keep running.
+			if transportErr, ok := err.(*errors.TransportError); ok {
+				if e.config.Transport.FailFast {
+					return &errors.FatalError{
+						Message: "transport initialization failed",
+						Err:     transportErr,
+					}
+				}
+				log.Printf("Engine ➜ Warning ➜ %v, continuing without the %s endpoint", transportErr, ep.id)
+				continue
+			}
+			return err
+		}
+		routerTargets = append(routerTargets, ep.id)
+	}
 
-		wstComponent := endpoint.NewWstComponent("ws", capacity, wsTransport)
-		if err := e.system.Register(wstComponent); err != nil {
+	// The debug sinks below own the pooled messages only when nothing else
+	// consumes them.
+	logEnabled := e.config.Transport.LogEnabled || e.config.Debug
+	sinks := 0
+	recordEnabled := e.config.Transport.RecordFile != ""
+	for _, enabled := range []bool{e.config.Transport.Stdout, logEnabled, e.config.Transport.GapDetection, recordEnabled} {
+		if enabled {
+			sinks++
+		}
+	}
+	terminal := len(routerTargets) == 0 && sinks == 1
+
+	if e.config.Transport.Stdout {
+		stdoutComponent := endpoint.NewStdoutComponent("stdout", capacity, e.config.Transport.StdoutInterval, terminal)
+		if err := e.system.Register(stdoutComponent); err != nil {
 			return &errors.FatalError{
-				Message: "failed to register WstComponent",
+				Message: "failed to register StdoutComponent",
 				Err:     err,
 			}
 		}
-		routerTargets = append(routerTargets, "ws")
+		routerTargets = append(routerTargets, "stdout")
+	}
+
+	if logEnabled {
+		logComponent := endpoint.NewLogComponent("log", capacity, e.config.Transport.LogInterval, terminal)
+		if err := e.system.Register(logComponent); err != nil {
+			return &errors.FatalError{
+				Message: "failed to register LogComponent",
+				Err:     err,
+			}
+		}
+		routerTargets = append(routerTargets, "log")
+	}
+
+	if e.config.Transport.GapDetection {
+		gapComponent := endpoint.NewGapComponent("gaps", capacity, e.config.Transport.GapReportInterval, terminal)
+		if err := e.system.Register(gapComponent); err != nil {
+			return &errors.FatalError{
+				Message: "failed to register GapComponent",
+				Err:     err,
+			}
+		}
+		e.gaps = gapComponent
+		routerTargets = append(routerTargets, "gaps")
+	}
+
+	if recordEnabled {
+		f, err := os.Create(e.config.Transport.RecordFile)
+		if err != nil {
+			return &errors.FatalError{
+				Message: "failed to create record file",
+				Err:     err,
+			}
+		}
+		e.closables = append(e.closables, f)
+
+		recordComponent := endpoint.NewRecordComponent("record", capacity, f, terminal)
+		if err := e.system.Register(recordComponent); err != nil {
+			return &errors.FatalError{
+				Message: "failed to register RecordComponent",
+				Err:     err,
+			}
+		}
+		routerTargets = append(routerTargets, "record")
 	}
 
 	routerComponent, err := pipeline.NewRouter("router", capacity, routerTargets, e.system)
@@ -146,8 +474,195 @@ func (e *Engine) initializeSystem() error {
 	return nil
 }
 
+// initWebSocketEndpoint creates the WebSocket transport and registers its
+// endpoint. A transport construction failure is returned as a TransportError.
+func (e *Engine) initWebSocketEndpoint(id string, capacity int) error {
+	wsTransport, err := transport.NewWebSocketTransport(
+		e.config.Transport.WebSocketAddress,
+		e.config.Transport.WebSocketPath,
+	)
+	if err != nil {
+		return &errors.TransportError{Transport: "WebSocketTransport", Err: err}
+	}
+	wsTransport.SetReadLimit(e.config.Transport.ReadLimit)
+	wsTransport.SetShutdownTimeout(e.config.ShutdownTimeout)
+	wsTransport.SetBroadcastWorkers(e.config.Transport.WebSocketWorkers)
+	e.closables = append(e.closables, wsTransport)
+
+	wstComponent := endpoint.NewWstComponent(id, capacity, wsTransport)
+	wstComponent.SetHeartbeat(e.config.Transport.HeartbeatInterval)
+	wstComponent.SetMaxBins(e.config.Transport.WebSocketMaxBins)
+	if err := e.system.Register(wstComponent); err != nil {
+		return &errors.FatalError{
+			Message: "failed to register WstComponent",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// initTCPEndpoint creates the TCP transport and registers its endpoint. A
+// transport construction failure is returned as a TransportError.
+func (e *Engine) initTCPEndpoint(id string, capacity int) error {
+	tcpTransport, err := transport.NewTCPTransport(e.config.Transport.TCPAddress)
+	if err != nil {
+		return &errors.TransportError{Transport: "TCPTransport", Err: err}
+	}
+	tcpTransport.SetReadLimit(e.config.Transport.ReadLimit)
+	e.closables = append(e.closables, tcpTransport)
+
+	tcpComponent := endpoint.NewTcpComponent(id, capacity, tcpTransport)
+	tcpComponent.SetHeartbeat(e.config.Transport.HeartbeatInterval)
+	if err := e.system.Register(tcpComponent); err != nil {
+		return &errors.FatalError{
+			Message: "failed to register TcpComponent",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// initUDPEndpoint creates the UDP transport and registers its endpoint. A
+// transport construction failure is returned as a TransportError.
+func (e *Engine) initUDPEndpoint(id string, capacity int) error {
+	udpTransport, err := transport.NewUdpTransport(
+		e.config.Transport.UDPSendAddress,
+		e.config.Transport.UDPInterface,
+		e.config.Transport.UDPMulticastTTL,
+		e.newBackoff(),
+	)
+	if err != nil {
+		return &errors.TransportError{Transport: "UdpTransport", Err: err}
+	}
+	e.closables = append(e.closables, udpTransport)
+
+	udpComponent := endpoint.NewUdpComponent(id, capacity, e.config.Transport.UDPSendInterval, udpTransport)
+	udpFormat, _ := endpoint.ParsePayloadFormat(e.config.Transport.UDPFormat)
+	udpComponent.SetFormat(udpFormat)
+	udpComponent.SetHeartbeat(e.config.Transport.HeartbeatInterval)
+	if bins := e.outputBinHi - e.outputBinLo; udpFormat == endpoint.FormatBinary && endpoint.BinaryPayloadSize(bins) > endpoint.MaxUDPPayload {
+		log.Printf("Engine ➜ Warning ➜ Binary UDP payload of %d bins is %d bytes, above the %d bytes of an Ethernet MTU, "+
+			"packets will be fragmented: narrow dsp.output_freq_min/max or reduce the FFT size",
+			bins, endpoint.BinaryPayloadSize(bins), endpoint.MaxUDPPayload)
+	}
+	if err := e.system.Register(udpComponent); err != nil {
+		return &errors.FatalError{
+			Message: "failed to register UdpComponent",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// mqttClientID returns transport.mqtt_client_id or, if unset, the hostname with
+// a random suffix. A broker disconnects a client when another connects with the
+// same ID, so instances on one host, or in containers that all run as PID 1,
+// must not share one.
+func (e *Engine) mqttClientID() string {
+	if id := e.config.Transport.MQTTClientID; id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "phase4"
+	}
+	return fmt.Sprintf("%s-%08x", host, rand.Uint32())
+}
+
+// initMQTTEndpoint connects to the MQTT broker and registers its endpoint. A
+// broker that can not be reached is returned as a TransportError.
+func (e *Engine) initMQTTEndpoint(id string, capacity int) error {
+	mqttTransport, err := transport.NewMqttTransport(
+		e.config.Transport.MQTTBroker,
+		e.config.Transport.MQTTTopic,
+		e.mqttClientID(),
+		e.newBackoff(),
+	)
+	if err != nil {
+		return &errors.TransportError{Transport: "MqttTransport", Err: err}
+	}
+	e.closables = append(e.closables, mqttTransport)
+
+	mqttComponent := endpoint.NewMqttComponent(id, capacity, e.config.Transport.MQTTSendInterval, mqttTransport)
+	mqttFormat, _ := endpoint.ParsePayloadFormat(e.config.Transport.MQTTFormat)
+	mqttComponent.SetFormat(mqttFormat)
+	mqttComponent.SetHeartbeat(e.config.Transport.HeartbeatInterval)
+	if err := e.system.Register(mqttComponent); err != nil {
+		return &errors.FatalError{
+			Message: "failed to register MqttComponent",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// SetEndpointEnabled starts or stops forwarding analysis data to the endpoint
+// with the given ID without restarting the engine. Any configured router
+// target can be toggled: "ws", "tcp", "udp", "mqtt", "stdout", "log", "gaps" or
+// "record". The change is applied asynchronously by the router.
+func (e *Engine) SetEndpointEnabled(id string, enabled bool) error {
+	ctx, cancel := context.WithTimeout(e.ctx, controlSendTimeout)
+	defer cancel()
+
+	return e.system.SendContext(ctx, "router", &stage.ControlMessage{
+		Command: stage.CommandEndpointEnable,
+		Params:  map[string]any{"id": id, "enabled": enabled},
+	})
+}
+
+// ConfigureBPM changes BPM detector parameters without restarting the engine,
+// params are those of stage.CommandBPMConfigure. The change is applied
+// asynchronously by the control component, invalid params are logged and
+// ignored.
+func (e *Engine) ConfigureBPM(params map[string]any) error {
+	ctx, cancel := context.WithTimeout(e.ctx, controlSendTimeout)
+	defer cancel()
+
+	return e.system.SendContext(ctx, "control", &stage.ControlMessage{
+		Command: stage.CommandBPMConfigure,
+		Params:  params,
+	})
+}
+
+// SetBPM fixes the reported tempo at bpm, or resumes tempo estimation for a
+// bpm of 0, see stage.CommandSetBPM. The change is applied asynchronously by
+// the control component.
+func (e *Engine) SetBPM(bpm float64) error {
+	ctx, cancel := context.WithTimeout(e.ctx, controlSendTimeout)
+	defer cancel()
+
+	return e.system.SendContext(ctx, "control", &stage.ControlMessage{
+		Command: stage.CommandSetBPM,
+		Params:  map[string]any{"bpm": bpm},
+	})
+}
+
+// newBackoff creates a reconnect backoff for an outbound transport from the
+// shared transport.reconnect policy.
+func (e *Engine) newBackoff() *transport.Backoff {
+	reconnect := e.config.Transport.Reconnect
+	return transport.NewBackoff(
+		reconnect.InitialInterval,
+		reconnect.MaxInterval,
+		reconnect.Multiplier,
+		reconnect.Jitter,
+	)
+}
+
 func (e *Engine) selectAndConfigureDevice() error {
-	if err := selectInputDevice(e); err != nil {
+	err := e.retryOpen(e.ctx, "Input device selection", func(attempt int) error {
+		if attempt > 0 {
+			// PortAudio only enumerates devices on initialization.
+			if err := exitPA(e); err != nil {
+				return err
+			}
+			if err := initPA(e); err != nil {
+				return err
+			}
+		}
+		return selectInputDevice(e)
+	})
+	if err != nil {
 		return &errors.FatalError{
 			Message: "failed to select input device",
 			Err:     err,
@@ -161,6 +676,15 @@ func (e *Engine) Run(ctx context.Context) error {
 	if err := e.system.StartAll(); err != nil {
 		return fmt.Errorf("failed to start actor system: %v", err)
 	}
+	if e.fileInput != nil {
+		return e.runFileInput(ctx)
+	}
+	if e.replayInput != nil {
+		return e.runReplayInput(ctx)
+	}
+	if e.nullInput {
+		return e.runNullInput(ctx)
+	}
 	return e.startStream(ctx)
 }
 
@@ -175,12 +699,19 @@ func (e *Engine) Close() error {
 
 	var errs []error
 
-	// 1. Stop audio stream first (most critical)
+	// Session statistics, once no more frames are analyzed.
+	defer e.logBPMStats()
+	defer e.logGapStats()
+
+	// 1. Stop audio streams first (most critical)
 	if e.audio.stream != nil {
 		if err := e.stopAudioStream(); err != nil {
 			errs = append(errs, fmt.Errorf("audio stream: %w", err))
 		}
 	}
+	if err := e.stopSources(); err != nil {
+		errs = append(errs, fmt.Errorf("input sources: %w", err))
+	}
 
 	// 2. Stop actor system (may depend on other components)
 	if e.system != nil {
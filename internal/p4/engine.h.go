@@ -3,32 +3,72 @@ package p4
 
 import (
 	"context"
+	"os"
 	"phase4/internal/app/config"
 	"phase4/internal/p4/analysis"
+	"phase4/internal/p4/runtime/endpoint"
+	"phase4/internal/p4/runtime/pipeline"
 	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/buffer"
+	"phase4/pkg/wav"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gordonklaus/portaudio"
 )
 
 type Engine struct {
-	ctx         context.Context
-	audio       *pa
-	command     *cmd
-	config      *config.Config
-	system      *stage.System
-	cancel      context.CancelFunc
-	fftProc     *analysis.FFTProcessor
-	bpmDetector *analysis.BPMDetector
-	closables   []interface{ Close() error }
-	frameCount  atomic.Uint64
-	mu          sync.Mutex
-	closed      bool
+	ctx              context.Context
+	audio            *pa
+	command          *cmd
+	config           *config.Config
+	system           *stage.System
+	cancel           context.CancelFunc
+	fftProc          *analysis.FFTProcessor
+	bpmDetector      *analysis.BPMDetector
+	lfo              *analysis.LFO               // Beat-synced oscillator, nil unless dsp.lfo.
+	bandOnsets       *analysis.BandOnsetDetector // Per-band onsets, nil unless dsp.band_onsets.
+	extraFFTs        []extraFFT                  // Additional resolutions, see dsp.extra_fft_sizes.
+	sources          []*inputSource              // Additional capture devices, see input.extra_sources.
+	mixBuffer        []int32                     // Primary input with the mixed sources added.
+	sourceID         string                      // Labels emitted frames when sources are analyzed separately.
+	transform        pipeline.TransformFunc
+	closables        []interface{ Close() error }
+	gaps             *endpoint.GapComponent // Frames lost before the endpoints, nil unless transport.gap_detection.
+	analysisRing     *buffer.Int32FrameRing
+	fileInput        *wav.Reader
+	replayInput      *os.File // Recording fed to the router, see input.source.
+	done             chan struct{}
+	analysisReady    chan struct{}
+	analysisWg       sync.WaitGroup
+	lastBudgetWarn   time.Time
+	analysisBudget   time.Duration // Wall-clock duration of one audio buffer.
+	budgetOverruns   int
+	lastLengthWarn   time.Time
+	lengthMismatches int
+	frameCount       atomic.Uint64
+	lastLatency      atomic.Int64 // Latency of the last emitted frame in nanoseconds, see dsp.report_latency.
+	analyzedFrame    uint64       // Frame count of the last analyzed buffer, guarded by analysisMu.
+	outputBinLo      int
+	outputBinHi      int
+	mu               sync.Mutex
+	analysisMu       sync.Mutex // Held while a frame is analyzed, see Snapshot.
+	nullInput        bool       // Silence instead of an audio device, see input.source.
+	closed           bool
+}
+
+// extraFFT is one additional FFT resolution and its cropped output bin range.
+type extraFFT struct {
+	fft   *analysis.SlidingFFT
+	size  int
+	binLo int
+	binHi int
 }
 
 type cmd struct {
 	ListDevices bool
+	Tune        bool
 }
 
 type pa struct {
@@ -46,7 +86,7 @@ type paClient interface {
 	Terminate() error
 	Devices() ([]*portaudio.DeviceInfo, error)
 	DefaultInputDevice() (*portaudio.DeviceInfo, error)
-	OpenStream(params portaudio.StreamParameters, callback func([]int32)) (paStream, error)
+	OpenStream(params portaudio.StreamParameters, format SampleFormat, callback func([]int32)) (paStream, error)
 }
 
 // paStream abstracts the PortAudio stream to allow for easier testing and mocking,
@@ -55,6 +95,7 @@ type paStream interface {
 	Start() error
 	Stop() error
 	Close() error
+	InputLatency() time.Duration
 }
 
 // This is an implementation of the paClient interface that uses the PortAudio library.
@@ -82,8 +123,37 @@ func (c *livePaClient) DefaultInputDevice() (*portaudio.DeviceInfo, error) {
 	return portaudio.DefaultInputDevice()
 }
 
-func (c *livePaClient) OpenStream(params portaudio.StreamParameters, callback func([]int32)) (paStream, error) {
-	stream, err := portaudio.OpenStream(params, callback)
+// OpenStream opens an input stream in the requested sample format. Samples in
+// formats other than int32 are converted to full-scale int32 in a buffer that is
+// reused across callbacks, callback must not retain the slice.
+func (c *livePaClient) OpenStream(params portaudio.StreamParameters, format SampleFormat, callback func([]int32)) (paStream, error) {
+	converted := make([]int32, params.FramesPerBuffer*params.Input.Channels)
+	convertBuffer := func(n int) []int32 {
+		if cap(converted) < n {
+			converted = make([]int32, n)
+		}
+		return converted[:n]
+	}
+
+	var paCallback any
+	switch format {
+	case SampleInt16:
+		paCallback = func(in []int16) {
+			dst := convertBuffer(len(in))
+			int16ToInt32(dst, in)
+			callback(dst)
+		}
+	case SampleFloat32:
+		paCallback = func(in []float32) {
+			dst := convertBuffer(len(in))
+			float32ToInt32(dst, in)
+			callback(dst)
+		}
+	default:
+		paCallback = callback
+	}
+
+	stream, err := portaudio.OpenStream(params, paCallback)
 	if err != nil {
 		return nil, err
 	}
@@ -91,28 +161,6 @@ func (c *livePaClient) OpenStream(params portaudio.StreamParameters, callback fu
 	return &livePaStream{stream: stream}, nil
 }
 
-// mockPaClient is a mock implementation of the paClient interface for testing purposes.
-// It allows for tracking whether the Initialize, Terminate, Devices, DefaultInputDevice,
-// and OpenStream methods were called, and allows for simulating errors in those methods.
-/*
-type mockPaClient struct {
-	InitializeCalled         bool
-	InitializeErr            error
-	TerminateCalled          bool
-	TerminateErr             error
-	DevicesCalled            bool
-	DevicesErr               error
-	DefaultInputDeviceCalled bool
-	DefaultInputDeviceErr    error
-	DefaultInputDeviceResult *portaudio.DeviceInfo
-	DevicesResult            []*portaudio.DeviceInfo
-	OpenStreamCalled         bool
-	OpenStreamParams         portaudio.StreamParameters
-	OpenStreamResult         paStream
-	OpenStreamErr            error
-}
-*/
-
 // livePaStream is an implementation of the paStream interface that uses the PortAudio
 // library. It provides methods to start, stop, and close the stream. Allows for easier
 // testing and mocking of the PortAudio library.
@@ -132,16 +180,11 @@ func (s *livePaStream) Close() error {
 	return s.stream.Close()
 }
 
-// mockPAStream is a mock implementation of the paStream interface for testing purposes.
-// It allows for tracking whether the Start, Stop, and Close methods were called, and allows
-// for simulating errors in those methods.
-/*
-type mockPaStream struct {
-	StartCalled bool
-	StopCalled  bool
-	CloseCalled bool
-	StartErr    error
-	StopErr     error
-	CloseErr    error
+// InputLatency returns the input latency negotiated by the host API, or 0 if the
+// stream info is unavailable.
+func (s *livePaStream) InputLatency() time.Duration {
+	if info := s.stream.Info(); info != nil {
+		return info.InputLatency
+	}
+	return 0
 }
-*/
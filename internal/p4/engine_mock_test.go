@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// mockPaClient is a mock implementation of the paClient interface for testing purposes.
+// It allows for tracking whether the Initialize, Terminate, Devices, DefaultInputDevice,
+// and OpenStream methods were called, and allows for simulating errors in those methods.
+type mockPaClient struct {
+	InitializeCalled         bool
+	InitializeErr            error
+	TerminateCalled          bool
+	TerminateErr             error
+	DevicesCalled            bool
+	DevicesErr               error
+	DefaultInputDeviceCalled bool
+	DefaultInputDeviceErr    error
+	DefaultInputDeviceResult *portaudio.DeviceInfo
+	DevicesResult            []*portaudio.DeviceInfo
+	OpenStreamCalled         bool
+	OpenStreamCalls          int
+	OpenStreamParams         portaudio.StreamParameters
+	OpenStreamCallback       func([]int32)
+	OpenStreamResult         paStream
+	OpenStreamErr            error
+}
+
+func (c *mockPaClient) Initialize() error {
+	c.InitializeCalled = true
+	return c.InitializeErr
+}
+
+func (c *mockPaClient) Terminate() error {
+	c.TerminateCalled = true
+	return c.TerminateErr
+}
+
+func (c *mockPaClient) Devices() ([]*portaudio.DeviceInfo, error) {
+	c.DevicesCalled = true
+	if c.DevicesErr != nil {
+		return nil, c.DevicesErr
+	}
+	return c.DevicesResult, nil
+}
+
+func (c *mockPaClient) DefaultInputDevice() (*portaudio.DeviceInfo, error) {
+	c.DefaultInputDeviceCalled = true
+	if c.DefaultInputDeviceErr != nil {
+		return nil, c.DefaultInputDeviceErr
+	}
+	return c.DefaultInputDeviceResult, nil
+}
+
+func (c *mockPaClient) OpenStream(params portaudio.StreamParameters, format SampleFormat, callback func([]int32)) (paStream, error) {
+	c.OpenStreamCalled = true
+	c.OpenStreamCalls++
+	c.OpenStreamParams = params
+	c.OpenStreamCallback = callback
+	if c.OpenStreamErr != nil {
+		return nil, c.OpenStreamErr
+	}
+	return c.OpenStreamResult, nil
+}
+
+// mockPaStream is a mock implementation of the paStream interface for testing purposes.
+// It allows for tracking whether the Start, Stop, and Close methods were called, and allows
+// for simulating errors in those methods.
+type mockPaStream struct {
+	StartCalled bool
+	StopCalled  bool
+	CloseCalled bool
+	StartErr    error
+	StopErr     error
+	CloseErr    error
+	Latency     time.Duration
+}
+
+func (s *mockPaStream) Start() error {
+	s.StartCalled = true
+	return s.StartErr
+}
+
+func (s *mockPaStream) Stop() error {
+	s.StopCalled = true
+	return s.StopErr
+}
+
+func (s *mockPaStream) Close() error {
+	s.CloseCalled = true
+	return s.CloseErr
+}
+
+func (s *mockPaStream) InputLatency() time.Duration {
+	return s.Latency
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"phase4/internal/app/config"
+	"phase4/internal/app/errors"
+	"phase4/internal/p4/runtime/pipeline"
+	"phase4/internal/p4/runtime/stage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitializeSystem_TransportFailure(t *testing.T) {
+	// Hold the port so the TCP transport cannot listen on it.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup failed: could not reserve a port")
+	defer listener.Close()
+
+	newEngine := func(failFast bool) *Engine {
+		return &Engine{
+			config: &config.Config{
+				Transport: config.TransportConfig{
+					TCPEnabled: true,
+					TCPAddress: listener.Addr().String(),
+					LogEnabled: true,
+					FailFast:   failFast,
+				},
+			},
+			system: stage.NewSystem(),
+		}
+	}
+
+	t.Run("Skip", func(t *testing.T) {
+		e := newEngine(false)
+		defer e.system.Close()
+
+		require.NoError(t, e.initializeSystem(), "A failed transport should not abort startup")
+
+		_, ok := e.system.Get("tcp")
+		assert.False(t, ok, "The failed endpoint should not be registered")
+		router, ok := e.system.Get("router")
+		require.True(t, ok, "The router should be registered")
+		assert.Equal(t, []string{"log"}, router.(*pipeline.RouterComponent).Targets(), "Only the remaining endpoints should be routed to")
+	})
+
+	t.Run("FailFast", func(t *testing.T) {
+		e := newEngine(true)
+		defer e.system.Close()
+
+		err := e.initializeSystem()
+
+		var fatalErr *errors.FatalError
+		require.ErrorAs(t, err, &fatalErr, "fail_fast should abort startup")
+		transportErr, ok := fatalErr.Err.(*errors.TransportError)
+		require.True(t, ok, "The cause should be the transport failure")
+		assert.Equal(t, "TCPTransport", transportErr.Transport)
+	})
+}
+
+// noDevicePaClient is a paClient for a host without audio hardware.
+type noDevicePaClient struct{}
+
+func (noDevicePaClient) Initialize() error { return nil }
+func (noDevicePaClient) Terminate() error  { return nil }
+func (noDevicePaClient) Devices() ([]*portaudio.DeviceInfo, error) {
+	return nil, nil
+}
+func (noDevicePaClient) DefaultInputDevice() (*portaudio.DeviceInfo, error) {
+	return nil, fmt.Errorf("no default input device")
+}
+func (noDevicePaClient) OpenStream(portaudio.StreamParameters, SampleFormat, func([]int32)) (paStream, error) {
+	return nil, fmt.Errorf("no devices")
+}
+
+func TestInitialize_NoDevice(t *testing.T) {
+	newEngine := func(allowNoDevice bool) *Engine {
+		e := NewEngine(&config.Config{
+			Input: config.InputConfig{
+				Channels:      1,
+				SampleRate:    44100,
+				BufferSize:    256,
+				Source:        "device",
+				AllowNoDevice: allowNoDevice,
+			},
+			DSP: config.DSPConfig{FFTWindow: "Hann"},
+		})
+		e.audio.client = noDevicePaClient{}
+		return e
+	}
+
+	t.Run("Fatal", func(t *testing.T) {
+		e := newEngine(false)
+		defer e.Close()
+
+		var fatalErr *errors.FatalError
+		assert.ErrorAs(t, e.Initialize(), &fatalErr, "Without allow_no_device a missing device should be fatal")
+	})
+
+	t.Run("NullFallback", func(t *testing.T) {
+		e := newEngine(true)
+		defer e.Close()
+
+		require.NoError(t, e.Initialize(), "allow_no_device should fall back to the null source")
+		assert.True(t, e.nullInput, "The null source should be selected")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- e.Run(ctx) }()
+
+		assert.Eventually(t, func() bool { return e.frameCount.Load() >= 2 }, time.Second, 5*time.Millisecond,
+			"The null source should deliver buffers")
+		cancel()
+		assert.NoError(t, <-done, "Run should return cleanly when cancelled")
+	})
+}
+
+func TestMqttClientID(t *testing.T) {
+	e := &Engine{config: &config.Config{}}
+	id := e.mqttClientID()
+	host, _ := os.Hostname()
+	assert.True(t, strings.HasPrefix(id, host+"-"), "The default client ID should start with the hostname")
+	assert.NotEqual(t, id, e.mqttClientID(), "Default client IDs should be unique")
+
+	e.config.Transport.MQTTClientID = "stage-left"
+	assert.Equal(t, "stage-left", e.mqttClientID(), "A configured client ID should be used as is")
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"phase4/internal/app/errors"
+	"phase4/pkg/wav"
+	"time"
+)
+
+// openFileInput opens input.file and checks that its layout matches the input
+// configuration the analysis is set up for.
+func (e *Engine) openFileInput() error {
+	f, err := os.Open(e.config.Input.File)
+	if err != nil {
+		return &errors.FatalError{
+			Message: "failed to open input file",
+			Err:     err,
+		}
+	}
+
+	reader, err := wav.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return &errors.FatalError{
+			Message: "failed to read input file",
+			Err:     fmt.Errorf("%s: %w", e.config.Input.File, err),
+		}
+	}
+
+	format := reader.Format()
+	if float64(format.SampleRate) != e.config.Input.SampleRate || format.Channels != e.config.Input.Channels {
+		_ = f.Close()
+		return &errors.FatalError{
+			Message: "input file does not match the input configuration",
+			Err: fmt.Errorf("%s is %d Hz, %d channel(s), configured %.0f Hz, %d channel(s)",
+				e.config.Input.File, format.SampleRate, format.Channels, e.config.Input.SampleRate, e.config.Input.Channels),
+		}
+	}
+
+	e.fileInput = reader
+	e.closables = append(e.closables, f)
+	log.Printf("Engine ➜ File ➜ %s (%d Hz, %d channel(s), %d-bit)",
+		e.config.Input.File, format.SampleRate, format.Channels, format.BitsPerSample)
+
+	return nil
+}
+
+// runFileInput feeds the file to the analysis one buffer per buffer period, so
+// downstream consumers see the same timing as with a live device. At the end of
+// the file it either rewinds (input.file_loop) or closes Done and returns.
+func (e *Engine) runFileInput(ctx context.Context) error {
+	// The analysis goroutine must also stop when the file ends, not only when
+	// ctx is cancelled.
+	analysisCtx, cancelAnalysis := context.WithCancel(ctx)
+	if e.analysisRing != nil {
+		e.analysisWg.Add(1)
+		go e.runAnalysis(analysisCtx)
+	}
+	defer e.analysisWg.Wait()
+	defer cancelAnalysis()
+
+	frame := make([]int32, e.config.Input.BufferSize*e.config.Input.Channels)
+	ticker := time.NewTicker(e.analysisBudget)
+	defer ticker.Stop()
+
+	log.Print("Engine ➜ File ➜ Started. (Ctrl+C) or (SigTerm) to stop.")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Print("Engine ➜ run() terminated")
+			return nil
+		case <-ticker.C:
+		}
+
+		n, err := e.fileInput.Read(frame)
+		if err != nil && err != io.EOF {
+			return &errors.FatalError{
+				Message: "failed to read input file",
+				Err:     err,
+			}
+		}
+		if n > 0 {
+			clear(frame[n:]) // Zero-pad the final partial buffer.
+			e.processInputStream(frame)
+		}
+		if err != io.EOF {
+			continue
+		}
+
+		if !e.config.Input.FileLoop {
+			log.Print("Engine ➜ File ➜ End of file")
+			close(e.done)
+			return nil
+		}
+		if err := e.fileInput.Rewind(); err != nil {
+			return &errors.FatalError{
+				Message: "failed to rewind input file",
+				Err:     err,
+			}
+		}
+		if e.bpmDetector != nil {
+			e.bpmDetector.Reset()
+		}
+		if e.bandOnsets != nil {
+			e.bandOnsets.Reset()
+		}
+		log.Print("Engine ➜ File ➜ Looping to start")
+	}
+}
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ParseSampleFormat converts a string name (case-insensitive) to a SampleFormat
+// enum, returns a known default (SampleInt32) and an error if the name is unknown.
+func ParseSampleFormat(name string) (SampleFormat, error) {
+	switch strings.ToLower(name) {
+	case "int32":
+		return SampleInt32, nil
+	case "int16":
+		return SampleInt16, nil
+	case "float32":
+		return SampleFloat32, nil
+	default:
+		return SampleInt32, fmt.Errorf("unknown sample format name: '%s'", name)
+	}
+}
+
+// int16ToInt32 widens src into dst at full int32 scale, so the analysis keeps a
+// single normalization factor for all formats.
+func int16ToInt32(dst []int32, src []int16) {
+	for i, s := range src {
+		dst[i] = int32(s) << 16
+	}
+}
+
+// float32ToInt32 converts src, nominally in [-1, 1], into dst at full int32
+// scale. Out-of-range samples are clipped.
+func float32ToInt32(dst []int32, src []float32) {
+	for i, s := range src {
+		v := float64(s) * (math.MaxInt32 + 1)
+		switch {
+		case v >= math.MaxInt32:
+			dst[i] = math.MaxInt32
+		case v <= math.MinInt32:
+			dst[i] = math.MinInt32
+		default:
+			dst[i] = int32(v)
+		}
+	}
+}
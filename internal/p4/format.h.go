@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import "fmt"
+
+// SampleFormat is the sample type requested from PortAudio for the input
+// stream. Samples are always delivered to the engine as full-scale int32.
+type SampleFormat int
+
+const (
+	SampleInt32 SampleFormat = iota
+	SampleInt16
+	SampleFloat32
+)
+
+// String returns the string representation of the SampleFormat.
+func (f SampleFormat) String() string {
+	switch f {
+	case SampleInt32:
+		return "int32"
+	case SampleInt16:
+		return "int16"
+	case SampleFloat32:
+		return "float32"
+	default:
+		return fmt.Sprintf("UnknownSampleFormat(%d)", int(f))
+	}
+}
@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSampleFormat(t *testing.T) {
+	for _, format := range []SampleFormat{SampleInt32, SampleInt16, SampleFloat32} {
+		parsed, err := ParseSampleFormat(format.String())
+		assert.NoError(t, err, "Known format %s should parse", format)
+		assert.Equal(t, format, parsed)
+	}
+
+	parsed, err := ParseSampleFormat("int24")
+	assert.Error(t, err, "Unknown format should return an error")
+	assert.Equal(t, SampleInt32, parsed, "Unknown format should fall back to int32")
+}
+
+func TestSampleConversion(t *testing.T) {
+	dst := make([]int32, 3)
+
+	int16ToInt32(dst, []int16{math.MinInt16, 0, math.MaxInt16})
+	assert.Equal(t, []int32{math.MinInt32, 0, math.MaxInt16 << 16}, dst)
+
+	float32ToInt32(dst, []float32{-1, 0.5, 2})
+	assert.Equal(t, []int32{math.MinInt32, 1 << 30, math.MaxInt32}, dst, "Out-of-range samples should clip")
+}
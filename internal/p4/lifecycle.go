@@ -15,6 +15,8 @@ type LifecycleManager struct {
 	done   chan struct{}
 	mu     sync.Mutex
 	state  LifecycleState
+
+	onStateChange func(old, new LifecycleState)
 }
 
 type LifecycleState int
@@ -27,6 +29,23 @@ const (
 	StateClosed
 )
 
+func (s LifecycleState) String() string {
+	switch s {
+	case StateUninitialized:
+		return "uninitialized"
+	case StateInitialized:
+		return "initialized"
+	case StateRunning:
+		return "running"
+	case StateShuttingDown:
+		return "shutting down"
+	case StateClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("LifecycleState(%d)", int(s))
+	}
+}
+
 func NewLifecycleManager(engine *Engine) *LifecycleManager {
 	return &LifecycleManager{
 		engine: engine,
@@ -34,19 +53,55 @@ func NewLifecycleManager(engine *Engine) *LifecycleManager {
 	}
 }
 
-func (lm *LifecycleManager) Start() error {
+// OnStateChange calls fn after every state transition, with the state before
+// and after it. fn runs on the goroutine that made the transition once the
+// state lock is released, so it may call State, Start or Shutdown, but it
+// delays the transition's caller and should hand slow work to a goroutine.
+func (lm *LifecycleManager) OnStateChange(fn func(old, new LifecycleState)) {
+	lm.mu.Lock()
+	lm.onStateChange = fn
+	lm.mu.Unlock()
+}
+
+// State returns the current lifecycle state.
+func (lm *LifecycleManager) State() LifecycleState {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
+	return lm.state
+}
+
+// setState moves to state and returns a function that reports the transition
+// to the OnStateChange callback, to be called once lm.mu is released. The
+// caller must hold lm.mu.
+func (lm *LifecycleManager) setState(state LifecycleState) (notify func()) {
+	old := lm.state
+	lm.state = state
+
+	fn := lm.onStateChange
+	return func() {
+		if fn != nil {
+			fn(old, state)
+		}
+	}
+}
+
+func (lm *LifecycleManager) Start() error {
+	lm.mu.Lock()
 
 	if lm.state != StateInitialized {
-		return fmt.Errorf("invalid state for start: %v", lm.state)
+		state := lm.state
+		lm.mu.Unlock()
+		return fmt.Errorf("invalid state for start: %v", state)
 	}
 
-	lm.state = StateRunning
+	notify := lm.setState(StateRunning)
 	lm.ctx, lm.cancel = context.WithCancel(context.Background())
 	lm.done = make(chan struct{})
 
 	go lm.run()
+	lm.mu.Unlock()
+
+	notify()
 	return nil
 }
 
@@ -64,8 +119,9 @@ func (lm *LifecycleManager) Shutdown() {
 		lm.mu.Unlock()
 		return
 	}
-	lm.state = StateShuttingDown
+	notify := lm.setState(StateShuttingDown)
 	lm.mu.Unlock()
+	notify()
 
 	log.Print("Starting graceful shutdown...")
 
@@ -85,6 +141,7 @@ func (lm *LifecycleManager) Shutdown() {
 	}
 
 	lm.mu.Lock()
-	lm.state = StateClosed
+	notify = lm.setState(StateClosed)
 	lm.mu.Unlock()
+	notify()
 }
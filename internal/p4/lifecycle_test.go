@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"phase4/internal/app/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleManager_OnStateChange(t *testing.T) {
+	e := engine(&config.Config{})
+	e.audio.stream = &mockPaStream{} // An open stream makes Run return at once.
+	lm := NewLifecycleManager(e)
+
+	type transition struct{ old, new LifecycleState }
+	var transitions []transition
+	lm.OnStateChange(func(old, new LifecycleState) {
+		// The state lock is released, so the callback may query the manager.
+		assert.Equal(t, new, lm.State(), "The callback should see the new state")
+		transitions = append(transitions, transition{old, new})
+	})
+
+	require.NoError(t, lm.Start())
+	assert.Error(t, lm.Start(), "A running manager should not start again")
+	lm.Shutdown()
+	lm.Shutdown()
+
+	assert.Equal(t, []transition{
+		{StateInitialized, StateRunning},
+		{StateRunning, StateShuttingDown},
+		{StateShuttingDown, StateClosed},
+	}, transitions, "Every transition should be reported once, in order")
+	assert.Equal(t, "closed", lm.State().String())
+}
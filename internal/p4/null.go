@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// useNullInput switches to the null source when the audio device can not be
+// opened and input.allow_no_device is set, e.g. on CI or a headless server. It
+// returns cause unchanged otherwise.
+func (e *Engine) useNullInput(cause error) error {
+	if !e.config.Input.AllowNoDevice {
+		return cause
+	}
+
+	if err := exitPA(e); err != nil {
+		log.Printf("Engine ➜ Warning ➜ %v", err)
+	}
+	log.Printf("Engine ➜ Warning ➜ %v, input.allow_no_device is set, falling back to the null source", cause)
+	e.nullInput = true
+
+	return nil
+}
+
+// runNullInput feeds silence to the analysis one buffer per buffer period until
+// ctx is cancelled, so the pipeline and transports run without audio hardware.
+func (e *Engine) runNullInput(ctx context.Context) error {
+	if e.analysisRing != nil {
+		e.analysisWg.Add(1)
+		go e.runAnalysis(ctx)
+	}
+	defer e.analysisWg.Wait()
+
+	frame := make([]int32, e.config.Input.BufferSize*e.config.Input.Channels)
+	ticker := time.NewTicker(e.analysisBudget)
+	defer ticker.Stop()
+
+	log.Print("Engine ➜ Null ➜ Started, analyzing silence. (Ctrl+C) or (SigTerm) to stop.")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Print("Engine ➜ run() terminated")
+			return nil
+		case <-ticker.C:
+			e.processInputStream(frame)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"log"
+	"runtime"
+)
+
+// tuneAnalysisThread applies dsp.analysis_priority and dsp.analysis_cpus to the
+// calling goroutine, which is locked to its OS thread for the rest of its life.
+// Tuning is best effort, e.g. raising the priority usually needs CAP_SYS_NICE,
+// failures are logged and analysis continues untuned.
+func (e *Engine) tuneAnalysisThread() {
+	nice, cpus := e.config.DSP.AnalysisPriority, e.config.DSP.AnalysisCPUs
+	if nice == 0 && len(cpus) == 0 {
+		return
+	}
+
+	// The thread is not unlocked, so it exits with the goroutine instead of
+	// returning to the scheduler with its priority and affinity.
+	runtime.LockOSThread()
+
+	if nice != 0 {
+		if err := setThreadPriority(nice); err != nil {
+			log.Printf("Engine ➜ Warning ➜ Could not set analysis thread priority %d: %v", nice, err)
+		} else {
+			log.Printf("Engine ➜ Analysis thread priority set to %d", nice)
+		}
+	}
+	if len(cpus) > 0 {
+		if err := setThreadAffinity(cpus); err != nil {
+			log.Printf("Engine ➜ Warning ➜ Could not pin analysis thread to CPUs %v: %v", cpus, err)
+		} else {
+			log.Printf("Engine ➜ Analysis thread pinned to CPUs %v", cpus)
+		}
+	}
+}
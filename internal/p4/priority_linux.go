@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+
+package p4
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// maxAffinityCPU bounds the CPU numbers of an affinity mask, the kernel's
+// default CONFIG_NR_CPUS.
+const maxAffinityCPU = 1024
+
+// setThreadPriority sets the nice value of the calling thread, -20 (highest)
+// to 19. The caller must be locked to its OS thread.
+func setThreadPriority(nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), nice)
+}
+
+// setThreadAffinity restricts the calling thread to cpus. The caller must be
+// locked to its OS thread.
+func setThreadAffinity(cpus []int) error {
+	var mask [maxAffinityCPU / 64]uint64
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= maxAffinityCPU {
+			return fmt.Errorf("CPU %d out of range", cpu)
+		}
+		mask[cpu/64] |= 1 << (cpu % 64)
+	}
+
+	// A pid of 0 is the calling thread.
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
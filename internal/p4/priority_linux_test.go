@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+
+package p4
+
+import (
+	"phase4/internal/app/config"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuneAnalysisThread(t *testing.T) {
+	e := &Engine{config: &config.Config{DSP: config.DSPConfig{
+		AnalysisPriority: 5, // Lowering the priority needs no privileges.
+		AnalysisCPUs:     []int{maxAffinityCPU - 1},
+	}}}
+
+	done := make(chan int)
+	go func() {
+		e.tuneAnalysisThread()
+		// The raw syscall returns 20 - nice.
+		prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+		if err != nil {
+			prio = -1
+		}
+		done <- 20 - prio
+	}()
+
+	assert.Equal(t, 5, <-done, "The analysis thread should run at the configured nice value")
+}
+
+func TestSetThreadAffinity(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	assert.Error(t, setThreadAffinity([]int{maxAffinityCPU}), "CPUs beyond the mask should be rejected")
+	assert.Error(t, setThreadAffinity([]int{maxAffinityCPU - 1}), "A mask without available CPUs should be rejected")
+}
@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+//go:build !linux
+
+package p4
+
+import (
+	"errors"
+)
+
+// errThreadTuning is returned where thread priority and affinity are not
+// implemented.
+var errThreadTuning = errors.New("not supported on this platform")
+
+func setThreadPriority(nice int) error {
+	return errThreadTuning
+}
+
+func setThreadAffinity(cpus []int) error {
+	return errThreadTuning
+}
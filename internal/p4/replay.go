@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"phase4/internal/app/errors"
+	"phase4/internal/p4/runtime/endpoint"
+	"phase4/internal/p4/runtime/pipeline"
+	"phase4/internal/p4/runtime/stage"
+	"time"
+)
+
+// openReplayInput opens input.replay_file, a recording written with
+// transport.record_file, as the source of the router.
+func (e *Engine) openReplayInput() error {
+	f, err := os.Open(e.config.Input.ReplayFile)
+	if err != nil {
+		return &errors.FatalError{
+			Message: "failed to open replay file",
+			Err:     err,
+		}
+	}
+
+	e.replayInput = f
+	e.closables = append(e.closables, f)
+	log.Printf("Engine ➜ Replay ➜ %s at %gx speed", e.config.Input.ReplayFile, e.config.Input.ReplaySpeed)
+
+	return nil
+}
+
+// runReplayInput feeds the recorded frames to the router, bypassing the audio
+// input and the analysis. At the end of the recording it either rewinds
+// (input.file_loop) or closes Done and returns.
+func (e *Engine) runReplayInput(ctx context.Context) error {
+	log.Print("Engine ➜ Replay ➜ Started. (Ctrl+C) or (SigTerm) to stop.")
+	for {
+		if err := e.replayOnce(ctx, e.config.Input.ReplaySpeed); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			log.Print("Engine ➜ run() terminated")
+			return nil
+		}
+
+		if !e.config.Input.FileLoop {
+			log.Print("Engine ➜ Replay ➜ End of recording")
+			close(e.done)
+			return nil
+		}
+		if _, err := e.replayInput.Seek(0, io.SeekStart); err != nil {
+			return &errors.FatalError{
+				Message: "failed to rewind replay file",
+				Err:     err,
+			}
+		}
+	}
+}
+
+// replayOnce sends every frame of the recording to the router at its recorded
+// offset divided by speed, or as fast as the router accepts them with a speed
+// of 0. Frames are sent with back pressure instead of being dropped, so a
+// replay delivers the whole recording. It returns at the end of the recording
+// or when ctx is cancelled.
+func (e *Engine) replayOnce(ctx context.Context, speed float64) error {
+	decoder := json.NewDecoder(e.replayInput)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	start := time.Now()
+
+	for {
+		// Decoding into a pooled message reuses its slices.
+		fftMsg := pipeline.FftDataPool.Get().(*stage.FFTData)
+		frame := endpoint.RecordedFrame{Frame: fftMsg}
+		if err := decoder.Decode(&frame); err != nil {
+			pipeline.FftDataPool.Put(fftMsg)
+			if err == io.EOF {
+				return nil
+			}
+			return &errors.FatalError{
+				Message: "failed to read replay file",
+				Err:     err,
+			}
+		}
+
+		if speed > 0 {
+			timer.Reset(time.Until(start.Add(time.Duration(float64(frame.Offset) / speed))))
+			select {
+			case <-ctx.Done():
+				pipeline.FftDataPool.Put(fftMsg)
+				return nil
+			case <-timer.C:
+			}
+		}
+
+		if err := e.system.SendContext(ctx, "router", fftMsg); err != nil {
+			pipeline.FftDataPool.Put(fftMsg)
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Engine ➜ Replay ➜ Error ➜ Failed to send frame %d to the router: %v", fftMsg.FrameCount, err)
+		}
+	}
+}
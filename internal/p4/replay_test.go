@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"phase4/internal/app/config"
+	"phase4/internal/p4/runtime/endpoint"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordFrames writes frames with the RecordComponent and returns the file.
+func recordFrames(t *testing.T, frames ...*stage.FFTData) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	system := stage.NewSystem()
+	recorder := endpoint.NewRecordComponent("record", len(frames), f, false)
+	require.NoError(t, system.Register(recorder))
+	require.Nil(t, system.StartAll())
+	for _, frame := range frames {
+		require.NoError(t, system.Send("record", frame))
+	}
+	// Stop drains the mailbox, StopAll would cancel the pending writes.
+	require.NoError(t, recorder.Stop())
+
+	return path
+}
+
+func TestEngine_Replay(t *testing.T) {
+	start := time.Unix(1000, 0)
+	path := recordFrames(t,
+		&stage.FFTData{FrameCount: 1, StartTime: start, Magnitudes: []float64{0.25, 0.5}},
+		&stage.FFTData{FrameCount: 2, StartTime: start.Add(20 * time.Millisecond), BPM: 128},
+	)
+
+	e := engine(&config.Config{Input: config.InputConfig{ReplayFile: path, ReplaySpeed: 1}})
+	defer e.Close()
+	require.NoError(t, e.openReplayInput())
+
+	received := make(chan stage.FFTData, 2)
+	router := stage.NewBaseActor("router", 2, func(ctx context.Context, msg stage.Message) {
+		received <- *msg.(*stage.FFTData)
+	})
+	require.NoError(t, e.system.Register(router))
+	require.Nil(t, e.system.StartAll())
+
+	began := time.Now()
+	require.NoError(t, e.runReplayInput(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(began), 20*time.Millisecond, "The recorded timing should be kept")
+
+	select {
+	case <-e.Done():
+	default:
+		t.Fatal("Done should be closed at the end of the recording")
+	}
+
+	frames := make([]stage.FFTData, 2)
+	for i := range frames {
+		select {
+		case frames[i] = <-received:
+		case <-time.After(time.Second):
+			t.Fatal("Every recorded frame should be sent to the router")
+		}
+	}
+	first, second := frames[0], frames[1]
+	assert.Equal(t, uint64(1), first.FrameCount)
+	assert.Equal(t, []float64{0.25, 0.5}, first.Magnitudes)
+	assert.True(t, start.Equal(first.StartTime), "The recorded start time should be kept")
+	assert.Equal(t, uint64(2), second.FrameCount)
+	assert.Equal(t, 128.0, second.BPM)
+}
+
+func TestEngine_ReplayMissingFile(t *testing.T) {
+	e := engine(&config.Config{Input: config.InputConfig{ReplayFile: filepath.Join(t.TempDir(), "missing.jsonl")}})
+
+	assert.Error(t, e.openReplayInput(), "A missing recording should fail")
+}
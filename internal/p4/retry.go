@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// retryOpen calls open until it succeeds, up to input.open_retries additional
+// times with input.open_retry_interval between attempts. It gives up early when
+// ctx is cancelled and returns the last error.
+func (e *Engine) retryOpen(ctx context.Context, op string, open func(attempt int) error) error {
+	retries := e.config.Input.OpenRetries
+	interval := e.config.Input.OpenRetryInterval
+
+	for attempt := 0; ; attempt++ {
+		err := open(attempt)
+		if err == nil || attempt >= retries {
+			return err
+		}
+
+		log.Printf("Engine ➜ Warning ➜ %s failed (attempt %d/%d), retrying in %s: %v",
+			op, attempt+1, retries+1, interval, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import "phase4/internal/p4/transport"
+
+// sendJSON serializes v like json.Marshal and passes it to sender, using a
+// pooled encoder. The data is only valid until SendData returns, so it must
+// only be used with senders that do not retain it.
+func sendJSON(sender transport.Component, v any) error {
+	e := encoderPool.Get().(*payloadEncoder)
+	defer putEncoder(e)
+
+	data, err := e.encode(v)
+	if err != nil {
+		return err
+	}
+	return sender.SendData(data)
+}
+
+// encode returns v as JSON in the encoder's buffer, without the newline that
+// json.Encoder appends. The result is overwritten by the next call.
+func (e *payloadEncoder) encode(v any) ([]byte, error) {
+	e.buf.Reset()
+	if err := e.enc.Encode(v); err != nil {
+		return nil, err
+	}
+	data := e.buf.Bytes()
+	return data[:len(data)-1], nil
+}
+
+// putEncoder returns e to encoderPool unless its buffer grew too large.
+func putEncoder(e *payloadEncoder) {
+	if e.buf.Cap() > maxPooledEncoderSize {
+		return
+	}
+	encoderPool.Put(e)
+}
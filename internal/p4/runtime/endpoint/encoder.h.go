@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledEncoderSize is the largest buffer returned to encoderPool, so one
+// unusually large payload does not stay allocated for good.
+const maxPooledEncoderSize = 1 << 20
+
+// payloadEncoder serializes payloads into a buffer that is reused across
+// frames, instead of the fresh slice json.Marshal allocates for each one.
+type payloadEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+var encoderPool = sync.Pool{
+	New: func() any {
+		e := &payloadEncoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		return e
+	},
+}
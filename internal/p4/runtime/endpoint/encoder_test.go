@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"encoding/json"
+	"math"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/internal/p4/transport"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// benchmarkFrame is a frame of 513 bins, a 1024-point FFT.
+func benchmarkFrame() *stage.FFTData {
+	m := &stage.FFTData{
+		FrameCount:          1000,
+		StartTime:           time.Unix(1700000000, 0),
+		BPM:                 128,
+		BPMConfidence:       0.8,
+		FrequencyResolution: 43.07,
+	}
+	for i := range 513 {
+		m.Magnitudes = append(m.Magnitudes, math.Abs(math.Sin(float64(i))))
+		m.SpectralFlux = append(m.SpectralFlux, math.Abs(math.Cos(float64(i))))
+	}
+	return m
+}
+
+func TestSendJSON(t *testing.T) {
+	var sent [][]byte
+	sender := &transport.MockTransportComponent{SendDataFunc: func(data []byte) error {
+		sent = append(sent, append([]byte(nil), data...))
+		return nil
+	}}
+	m := benchmarkFrame()
+
+	require.NoError(t, sendJSON(sender, fftPayload(m)))
+	expected, err := json.Marshal(fftPayload(m))
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(sent[0]), "The output should match json.Marshal")
+
+	m.FrameCount++
+	require.NoError(t, sendJSON(sender, fftPayload(m)))
+	expected, err = json.Marshal(fftPayload(m))
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(sent[1]), "A reused encoder should not keep the previous frame")
+
+	assert.Error(t, sendJSON(sender, math.NaN()), "Encoding errors should be returned")
+	assert.Len(t, sent, 2, "Nothing should be sent after an encoding error")
+}
+
+func BenchmarkFFTPayload_Marshal(b *testing.B) {
+	sender := &transport.MockTransportComponent{}
+	m := benchmarkFrame()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		data, err := json.Marshal(fftPayload(m))
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = sender.SendData(data)
+	}
+}
+
+func BenchmarkFFTPayload_PooledEncoder(b *testing.B) {
+	sender := &transport.MockTransportComponent{}
+	m := benchmarkFrame()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := sendJSON(sender, fftPayload(m)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"log"
+	"phase4/internal/p4/runtime/pipeline"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"time"
+)
+
+// NewGapComponent creates a sink that follows the FrameCount of the FFTData it
+// receives and reports skipped frames at most once per interval. As a router
+// target it sees what a real endpoint sees, so every frame dropped on the way
+// from the audio callback to an endpoint mailbox shows up as a gap. terminal
+// has the same meaning as for NewLogComponent.
+func NewGapComponent(id string, capacity int, interval time.Duration, terminal bool) *GapComponent {
+	a := &GapComponent{
+		interval: interval,
+		terminal: terminal,
+		clock:    clock.Real{},
+	}
+	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
+
+	return a
+}
+
+// SetClock replaces the clock used to pace reports, it must be called before
+// the component is started.
+func (a *GapComponent) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// Stats returns the totals since the component started, it is safe to call from
+// any goroutine.
+func (a *GapComponent) Stats() GapStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
+
+func (a *GapComponent) processMessage(ctx context.Context, msg stage.Message) {
+	m, ok := msg.(*stage.FFTData)
+	if !ok {
+		log.Printf("Gaps[%s] ➜ Warning ➜ Received unexpected message type: %T", a.ID(), msg)
+		return
+	}
+	if a.terminal {
+		defer pipeline.FftDataPool.Put(m)
+	}
+
+	a.mu.Lock()
+	// The first frame only sets the baseline, frames withheld during warmup are
+	// not lost. A frame count that goes backwards is treated as a new baseline.
+	// Sources analyzed separately count their frames independently, each is
+	// followed on its own.
+	if a.lastFrame == nil {
+		a.lastFrame = make(map[string]uint64)
+	}
+	if last := a.lastFrame[m.SourceID]; last != 0 && m.FrameCount > last+1 {
+		missed := m.FrameCount - last - 1
+		a.stats.Missed += missed
+		a.stats.Gaps++
+		a.stats.Largest = max(a.stats.Largest, missed)
+	}
+	a.stats.Frames++
+	a.lastFrame[m.SourceID] = m.FrameCount
+	stats := a.stats
+	a.mu.Unlock()
+
+	now := a.clock.Now()
+	if a.lastReport.IsZero() {
+		a.lastReport = now
+	}
+	if now.Sub(a.lastReport) < a.interval || stats.Missed == a.reported.Missed {
+		return
+	}
+
+	missed := stats.Missed - a.reported.Missed
+	frames := stats.Frames - a.reported.Frames
+	log.Printf("Gaps[%s] ➜ Warning ➜ %d frames missed in %d gaps over %s (%.1f%% lost, largest gap %d, total %d of %d)",
+		a.ID(), missed, stats.Gaps-a.reported.Gaps, now.Sub(a.lastReport).Round(time.Millisecond),
+		100*float64(missed)/float64(missed+frames), stats.Largest, stats.Missed, stats.Missed+stats.Frames)
+	a.lastReport = now
+	a.reported = stats
+}
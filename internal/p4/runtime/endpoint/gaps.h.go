@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"sync"
+	"time"
+)
+
+// GapStats counts the frames a GapComponent received and the frames missing
+// between them, since it started.
+type GapStats struct {
+	Frames  uint64 // Frames received.
+	Missed  uint64 // Frames skipped in the frame count sequence.
+	Gaps    uint64 // Runs of consecutive missed frames.
+	Largest uint64 // Longest run of missed frames.
+}
+
+type GapComponent struct {
+	lastReport time.Time
+	clock      clock.Clock
+	stage.BaseActor
+	stats     GapStats
+	reported  GapStats          // Stats at the last report.
+	lastFrame map[string]uint64 // Per input source.
+	interval  time.Duration
+	mu        sync.Mutex // Guards stats for Stats.
+	terminal  bool
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGapComponent_CountsSkippedFrames(t *testing.T) {
+	clk := clock.NewManual(time.Unix(1000, 0))
+	a := NewGapComponent("gaps", 1, time.Second, false)
+	a.SetClock(clk)
+
+	receive := func(frames ...uint64) {
+		for _, frame := range frames {
+			a.processMessage(context.Background(), &stage.FFTData{FrameCount: frame})
+		}
+	}
+
+	receive(9, 10, 11) // The first frame after warmup is the baseline.
+	assert.Equal(t, GapStats{Frames: 3}, a.Stats(), "Consecutive frames should not be gaps")
+
+	receive(14, 15, 20)
+	assert.Equal(t, GapStats{Frames: 6, Missed: 6, Gaps: 2, Largest: 4}, a.Stats())
+
+	clk.Advance(time.Second)
+	receive(21)
+	assert.Equal(t, uint64(6), a.reported.Missed, "Gaps should be reported after the interval")
+
+	receive(1, 2)
+	assert.Equal(t, uint64(6), a.Stats().Missed, "A frame count going backwards should start a new baseline")
+}
+
+func TestGapComponent_InterleavedSources(t *testing.T) {
+	a := NewGapComponent("gaps", 1, time.Second, false)
+	a.SetClock(clock.NewManual(time.Unix(1000, 0)))
+
+	receive := func(source string, frames ...uint64) {
+		for _, frame := range frames {
+			a.processMessage(context.Background(), &stage.FFTData{FrameCount: frame, SourceID: source})
+		}
+	}
+
+	// Each source counts its own frames, at its own offset.
+	for frame := uint64(1); frame <= 3; frame++ {
+		receive("stage", 100+frame)
+		receive("bar", frame)
+	}
+	assert.Equal(t, GapStats{Frames: 6}, a.Stats(), "Interleaved sources should not be gaps")
+
+	receive("bar", 6)
+	receive("stage", 104)
+	assert.Equal(t, GapStats{Frames: 8, Missed: 2, Gaps: 1, Largest: 2}, a.Stats(), "A gap should be counted for its source only")
+}
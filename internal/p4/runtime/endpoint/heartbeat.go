@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/internal/p4/transport"
+	"time"
+)
+
+// start runs the ticker that asks actor to check for silence, it does nothing
+// when the interval is zero. The ticks go through the mailbox so the heartbeat
+// is sent from the process loop, never concurrently with a frame. The ticker
+// stops with ctx or once the actor no longer accepts messages.
+func (h *heartbeat) start(ctx context.Context, actor *stage.BaseActor) {
+	if h.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(max(h.interval/heartbeatTicks, time.Millisecond))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// A full mailbox means frames are flowing, the tick is not needed.
+				if err := actor.SendNonBlocking(heartbeatTick{}); err == stage.ErrActorClosed {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// sent records that a frame was sent at now, which resets the interval.
+func (h *heartbeat) sent(now time.Time) {
+	h.last = now
+}
+
+// beat sends a heartbeat through sender if nothing was sent for the interval.
+// The first tick only starts the interval.
+func (h *heartbeat) beat(sender transport.Component, now time.Time) {
+	if h.last.IsZero() {
+		h.last = now
+		return
+	}
+	if now.Sub(h.last) < h.interval {
+		return
+	}
+	h.last = now
+
+	jsonData, err := json.Marshal(heartbeatPayload{Type: "heartbeat", Timestamp: now.UnixMilli()})
+	if err != nil {
+		return
+	}
+	_ = sender.SendData(jsonData)
+}
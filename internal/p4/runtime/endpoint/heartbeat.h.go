@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import "time"
+
+// heartbeatTicks is how many times per interval the ticker checks for
+// silence, a heartbeat is therefore late by at most a quarter interval.
+const heartbeatTicks = 4
+
+// heartbeat sends a lightweight message through an endpoint while no frames
+// are emitted, so clients can tell a silent input from a dead connection. It is
+// only touched from the endpoint's process loop.
+type heartbeat struct {
+	last     time.Time // Last frame or heartbeat sent.
+	interval time.Duration
+}
+
+// heartbeatPayload is the JSON sent when the interval passed without a frame.
+type heartbeatPayload struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"ts"` // Unix milliseconds.
+}
+
+/*
+Mesage types
+- heartbeatTick: Asks the endpoint to check for silence. "endpoint.heartbeat"
+*/
+
+type heartbeatTick struct{}
+
+func (m heartbeatTick) Type() string {
+	return "endpoint.heartbeat"
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUdpComponent_Heartbeat(t *testing.T) {
+	sender := &recordingSender{}
+	clk := clock.NewManual(time.Unix(1000, 0))
+
+	a := NewUdpComponent("udp", 1, time.Millisecond, sender)
+	a.SetClock(clk)
+	a.SetHeartbeat(time.Second)
+
+	tick := func() {
+		a.processMessage(context.Background(), heartbeatTick{})
+	}
+
+	tick()
+	assert.Empty(t, sender.sent, "The first tick should only start the interval")
+
+	clk.Advance(500 * time.Millisecond)
+	a.processMessage(context.Background(), &stage.FFTData{})
+	sent := len(sender.sent)
+	require.NotZero(t, sent, "The frame should be sent")
+
+	clk.Advance(900 * time.Millisecond)
+	tick()
+	assert.Len(t, sender.sent, sent, "A frame should reset the interval")
+
+	clk.Advance(100 * time.Millisecond)
+	tick()
+	require.Len(t, sender.sent, sent+1, "A heartbeat should be sent after a silent interval")
+
+	var payload heartbeatPayload
+	require.NoError(t, json.Unmarshal(sender.sent[sent], &payload))
+	assert.Equal(t, "heartbeat", payload.Type)
+	assert.Equal(t, clk.Now().UnixMilli(), payload.Timestamp, "The timestamp should be the send time")
+
+	clk.Advance(500 * time.Millisecond)
+	tick()
+	assert.Len(t, sender.sent, sent+1, "The heartbeat should reset the interval")
+}
+
+// syncSender is a recordingSender that can be read while the actor runs.
+type syncSender struct {
+	mu   sync.Mutex
+	sent [][]byte
+}
+
+func (s *syncSender) SendData(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, data)
+	return nil
+}
+
+func (s *syncSender) Close() error {
+	return nil
+}
+
+func (s *syncSender) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sent)
+}
+
+func TestWstComponent_HeartbeatTicker(t *testing.T) {
+	sender := &syncSender{}
+	a := NewWstComponent("ws", 4, sender)
+	a.SetHeartbeat(20 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, a.Start(ctx))
+
+	assert.Eventually(t, func() bool { return sender.count() >= 2 }, time.Second, 5*time.Millisecond,
+		"An idle endpoint should keep sending heartbeats")
+	require.NoError(t, a.Stop())
+}
+
+func TestUdpComponent_NoHeartbeatInBinary(t *testing.T) {
+	sender := &syncSender{}
+	a := NewUdpComponent("udp", 4, time.Millisecond, sender)
+	a.SetFormat(FormatBinary)
+	a.SetHeartbeat(time.Millisecond)
+
+	require.NoError(t, a.Start(context.Background()))
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, a.Stop())
+
+	assert.Zero(t, sender.count(), "No JSON heartbeat should be sent in binary mode")
+}
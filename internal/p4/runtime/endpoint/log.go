@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"log"
+	"phase4/internal/p4/runtime/pipeline"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"time"
+)
+
+// NewLogComponent creates a debug sink that logs a summary of the FFTData it
+// receives at most once per interval. If terminal is true the component is the
+// only consumer of the messages and returns them to pipeline.FftDataPool, it must
+// be false when the router fans out to other endpoints.
+func NewLogComponent(id string, capacity int, interval time.Duration, terminal bool) *LogComponent {
+	a := &LogComponent{
+		interval: interval,
+		terminal: terminal,
+		clock:    clock.Real{},
+	}
+	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
+
+	return a
+}
+
+// SetClock replaces the clock used to throttle logging, it must be called before
+// the component is started.
+func (a *LogComponent) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+func (a *LogComponent) processMessage(ctx context.Context, msg stage.Message) {
+	m, ok := msg.(*stage.FFTData)
+	if !ok {
+		log.Printf("Log[%s] ➜ Warning ➜ Received unexpected message type: %T", a.ID(), msg)
+		return
+	}
+	if a.terminal {
+		defer pipeline.FftDataPool.Put(m)
+	}
+
+	if !a.lastLogged.allow(m.SourceID, a.clock.Now(), a.interval) {
+		return
+	}
+
+	peakIdx, peakMag := 0, 0.0
+	for i, mag := range m.Magnitudes {
+		if mag > peakMag {
+			peakIdx, peakMag = i, mag
+		}
+	}
+	peakFreq := m.FrequencyStart + float64(peakIdx)*m.FrequencyResolution
+
+	log.Printf("Log[%s] ➜ frame %d ➜ BPM %.1f (confidence %.2f) ➜ peak %.1f Hz (%.4f) ➜ RMS %.4f",
+		a.ID(), m.FrameCount, m.BPM, m.BPMConfidence, peakFreq, peakMag, m.RMS)
+}
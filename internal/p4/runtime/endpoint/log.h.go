@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"time"
+)
+
+type LogComponent struct {
+	lastLogged sourceThrottle // Per input source, see sourceThrottle.allow.
+	clock      clock.Clock
+	stage.BaseActor
+	interval time.Duration
+	terminal bool
+}
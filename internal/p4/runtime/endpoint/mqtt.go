@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/internal/p4/transport"
+	"phase4/pkg/clock"
+	"time"
+)
+
+// NewMqttComponent creates an endpoint publishing FFTData to a broker, at most
+// once per interval. Like UDP, a broker is a fan-out point for many small
+// subscribers, so frames arriving faster than the interval are dropped.
+func NewMqttComponent(id string, capacity int, interval time.Duration, sender transport.Component) *MqttComponent {
+	if sender == nil {
+		log.Panicf("MqttComponent requires a non-nil DataSender")
+	}
+
+	a := &MqttComponent{
+		sender:   sender,
+		interval: interval,
+		clock:    clock.Real{},
+	}
+	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
+
+	return a
+}
+
+// SetFormat selects how FFTData messages are serialized, it must be called
+// before the component is started. In FormatBinary no frequency axis is sent,
+// as for UDP.
+func (a *MqttComponent) SetFormat(format PayloadFormat) {
+	a.format = format
+}
+
+// SetClock replaces the clock used to pace sends, it must be called before the
+// component is started.
+func (a *MqttComponent) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// SetHeartbeat makes the endpoint send a heartbeat whenever no frame was sent
+// for interval, zero disables it. Heartbeats are JSON, so none are sent in
+// FormatBinary. It must be called before the component is started.
+func (a *MqttComponent) SetHeartbeat(interval time.Duration) {
+	a.heartbeat.interval = interval
+}
+
+// Start starts the process loop and, if enabled, the heartbeat ticker.
+func (a *MqttComponent) Start(ctx context.Context) error {
+	if err := a.BaseActor.Start(ctx); err != nil {
+		return err
+	}
+	if a.format != FormatBinary {
+		a.heartbeat.start(ctx, &a.BaseActor)
+	}
+	return nil
+}
+
+func (a *MqttComponent) processMessage(ctx context.Context, msg stage.Message) {
+	if _, ok := msg.(heartbeatTick); ok {
+		a.heartbeat.beat(a.sender, a.clock.Now())
+		return
+	}
+	m, ok := msg.(*stage.FFTData)
+	if !ok {
+		return
+	}
+
+	now := a.clock.Now()
+	if !a.lastSent.allow(m.SourceID, now, a.interval) {
+		return
+	}
+
+	if a.format == FormatBinary {
+		// The transport writes synchronously, so the buffer can be reused.
+		a.buf = appendBinaryPayload(a.buf[:0], m)
+		_ = a.sender.SendData(a.buf)
+		return
+	}
+
+	// The axis is the retained message of the topic, so subscribers that
+	// join late receive it first.
+	a.axis.sendAxis(a.sender, m)
+
+	jsonData, err := json.Marshal(fftPayload(m))
+	if err != nil {
+		return
+	}
+	_ = a.sender.SendData(jsonData)
+	a.heartbeat.sent(now)
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"phase4/internal/p4/runtime/stage"
+	"phase4/internal/p4/transport"
+	"phase4/pkg/clock"
+	"time"
+)
+
+type MqttComponent struct {
+	axis      frequencyAxis
+	heartbeat heartbeat
+	buf       []byte         // Binary payload, reused across frames.
+	lastSent  sourceThrottle // Per input source, see sourceThrottle.allow.
+	clock     clock.Clock
+	sender    transport.Component
+	stage.BaseActor
+	interval time.Duration
+	format   PayloadFormat
+}
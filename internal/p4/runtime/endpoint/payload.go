@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/internal/p4/transport"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ParsePayloadFormat converts a string name (case-insensitive) to a PayloadFormat
+// enum, returns a known default (FormatJSON) and an error if the name is unknown.
+func ParsePayloadFormat(name string) (PayloadFormat, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return FormatJSON, nil
+	case "binary":
+		return FormatBinary, nil
+	default:
+		return FormatJSON, fmt.Errorf("unknown payload format name: '%s'", name)
+	}
+}
+
+// BinaryPayloadSize returns the size in bytes of a binary payload carrying bins
+// magnitudes.
+func BinaryPayloadSize(bins int) int {
+	return binaryHeaderSize + bins*binaryMagnitudeSize
+}
+
+// appendBinaryPayload appends the binary encoding of m to dst, see
+// binaryHeaderSize for the layout. Passing a reused dst[:0] avoids allocating
+// once its capacity has grown to the payload size.
+func appendBinaryPayload(dst []byte, m *stage.FFTData) []byte {
+	dst = binary.LittleEndian.AppendUint64(dst, m.FrameCount)
+	dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(m.BPM)))
+	dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(m.BPMConfidence)))
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(m.Magnitudes)))
+	for _, mag := range m.Magnitudes {
+		dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(mag)))
+	}
+	return dst
+}
+
+// appendMaxPooled appends the maximum of every factor adjacent values of src to
+// dst, the last group may be shorter. Value i of the result starts at value
+// i*factor of src, so a frequency axis keeps its start and its resolution is
+// multiplied by factor.
+func appendMaxPooled(dst, src []float64, factor int) []float64 {
+	for lo := 0; lo < len(src); lo += factor {
+		dst = append(dst, slices.Max(src[lo:min(lo+factor, len(src))]))
+	}
+	return dst
+}
+
+// fftPayload builds the JSON payload shared by all endpoints that serialize
+// FFTData messages.
+func fftPayload(m *stage.FFTData) map[string]any {
+	payload := map[string]any{
+		"type":                  "fft_magnitudes",
+		"frameCount":            m.FrameCount,
+		"startTime":             m.StartTime.Format(time.RFC3339Nano),
+		"audioTime":             m.AudioTime, // Seconds on the audio clock, derived from the frame count.
+		"magnitudes":            m.Magnitudes,
+		"spectralFlux":          m.SpectralFlux,
+		"bpm":                   m.BPM,    // 0 below dsp.bpm_min_confidence, see bpmValid.
+		"bpmInstant":            m.RawBPM, // Follows every estimate, for tight sync.
+		"bpmRaw":                m.RawBPM, // The estimate regardless of its confidence.
+		"bpmValid":              m.BPMValid,
+		"bpmDisplay":            m.DisplayBPM, // Averaged over dsp.bpm_display_window, for a readout.
+		"bpmConfidence":         m.BPMConfidence,
+		"bpmConfidenceSmoothed": m.SmoothedConfidence,
+		// Both confidence definitions, bpmConfidence is the one selected by dsp.bpm_confidence.
+		"bpmConfidenceVariation": m.VariationConfidence,
+		"bpmConfidencePeak":      m.PeakConfidence,
+		// Detection diagnostics, framesSinceOnset is -1 until the first onset.
+		"framesSinceOnset": m.FramesSinceOnset,
+		"onsetStrength":    m.OnsetStrength,
+		"beatPhase":        m.BeatPhase,
+		"tempoLocked":      m.TempoLocked,
+		// Input level, clipCount samples of the buffer were at or near full scale.
+		"peak":      m.Peak,
+		"clipping":  m.Clipping,
+		"clipCount": m.ClipCount,
+		"channels":  m.Channels, // Captured channels, fewer than input.channels on a smaller device.
+		// Frequency axis, bin i is at frequencyStart + i*frequencyResolution Hz.
+		"frequencyStart":      m.FrequencyStart,
+		"frequencyResolution": m.FrequencyResolution,
+	}
+	if m.SourceID != "" {
+		// Input source of the frame when several are analyzed separately.
+		payload["source"] = m.SourceID
+	}
+	if !m.CapturedAt.IsZero() {
+		// Milliseconds from capture until now, as the endpoint serializes the
+		// frame, onsets are reported onsetLatencyMs late.
+		latency := time.Since(m.CapturedAt)
+		payload["latencyMs"] = float64(latency) / float64(time.Millisecond)
+		payload["onsetLatencyMs"] = float64(latency+m.OnsetDelay) / float64(time.Millisecond)
+	}
+	if m.HasFeatures {
+		payload["spectralFlatness"] = m.SpectralFlatness
+		payload["spectralCrest"] = m.SpectralCrest
+	}
+	if m.HasLFO {
+		// Beat-synced oscillator in [0, 1], -1 without a tempo.
+		payload["lfo"] = m.LFO
+	}
+	if len(m.BandOnsets) > 0 {
+		// One entry per dsp.onset_bands band, onset is true on the frame it is detected.
+		bands := make([]map[string]any, len(m.BandOnsets))
+		for i, band := range m.BandOnsets {
+			bands[i] = map[string]any{
+				"name":             band.Name,
+				"onset":            band.Onset,
+				"framesSinceOnset": band.FramesSinceOnset,
+				"onsetStrength":    band.Strength,
+			}
+		}
+		payload["bandOnsets"] = bands
+	}
+	if len(m.Phases) > 0 {
+		// Bin for bin with magnitudes, in radians.
+		payload["phases"] = m.Phases
+	}
+	if len(m.Spectra) > 0 {
+		spectra := make([]map[string]any, len(m.Spectra))
+		for i, spectrum := range m.Spectra {
+			spectra[i] = map[string]any{
+				"fftSize":             spectrum.FFTSize,
+				"frequencyStart":      spectrum.FrequencyStart,
+				"frequencyResolution": spectrum.FrequencyResolution,
+				"magnitudes":          spectrum.Magnitudes,
+			}
+		}
+		payload["spectra"] = spectra
+	}
+
+	return payload
+}
+
+// axisPayload builds the JSON payload describing the frequency of every bin in
+// m.Magnitudes, so clients do not have to reconstruct it from the analysis
+// settings.
+func axisPayload(m *stage.FFTData) map[string]any {
+	frequencies := make([]float64, len(m.Magnitudes))
+	for i := range frequencies {
+		frequencies[i] = m.FrequencyStart + float64(i)*m.FrequencyResolution
+	}
+
+	return map[string]any{
+		"type":        "frequency_axis",
+		"frequencies": frequencies,
+	}
+}
+
+// sendAxis sends the frequency axis when m's bins differ from the last axis
+// sent, i.e. on the first frame and after a change. Connection-oriented senders
+// keep it as their greeting, so clients that connect later receive it before
+// their first frame.
+func (f *frequencyAxis) sendAxis(sender transport.Component, m *stage.FFTData) {
+	if f.bins == len(m.Magnitudes) && f.start == m.FrequencyStart && f.resolution == m.FrequencyResolution {
+		return
+	}
+
+	jsonData, err := json.Marshal(axisPayload(m))
+	if err != nil {
+		return
+	}
+	f.start, f.resolution, f.bins = m.FrequencyStart, m.FrequencyResolution, len(m.Magnitudes)
+
+	if greeter, ok := sender.(transport.Greeter); ok {
+		greeter.SetGreeting(jsonData)
+		return
+	}
+	_ = sender.SendData(jsonData)
+}
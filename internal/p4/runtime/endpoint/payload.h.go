@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import "fmt"
+
+// frequencyAxis is the last frequency axis an endpoint sent, so the explicit bin
+// frequencies only go out again when the emitted bins change.
+type frequencyAxis struct {
+	start      float64
+	resolution float64
+	bins       int
+}
+
+// PayloadFormat selects how an endpoint serializes FFTData messages.
+type PayloadFormat int
+
+const (
+	FormatJSON PayloadFormat = iota
+	FormatBinary
+)
+
+// String returns the string representation of the PayloadFormat.
+func (f PayloadFormat) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatBinary:
+		return "binary"
+	default:
+		return fmt.Sprintf("UnknownPayloadFormat(%d)", int(f))
+	}
+}
+
+// Binary payload layout, all fields little-endian:
+//
+//	offset  size  field
+//	0       8     frameCount, uint64
+//	8       4     bpm, float32
+//	12      4     bpmConfidence, float32
+//	16      4     magnitude count n, uint32
+//	20      4*n   magnitudes, float32
+const (
+	binaryHeaderSize    = 20
+	binaryMagnitudeSize = 4
+)
+
+// MaxUDPPayload is the largest UDP payload that fits an Ethernet MTU of 1500
+// bytes without IP fragmentation (1500 - 20 IPv4 - 8 UDP header bytes).
+const MaxUDPPayload = 1472
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"encoding/json"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type greetingSender struct {
+	recordingSender
+	greeting []byte
+}
+
+func (s *greetingSender) SetGreeting(data []byte) {
+	s.greeting = data
+	s.sent = append(s.sent, data)
+}
+
+func TestFrequencyAxis_SendAxis(t *testing.T) {
+	sender := &greetingSender{}
+	var axis frequencyAxis
+
+	frame := &stage.FFTData{
+		Magnitudes:          make([]float64, 3),
+		FrequencyStart:      20,
+		FrequencyResolution: 10,
+	}
+
+	axis.sendAxis(sender, frame)
+	require.Len(t, sender.sent, 1, "The first frame should send the axis")
+	assert.Equal(t, sender.sent[0], sender.greeting, "The axis should become the greeting")
+
+	var payload struct {
+		Type        string    `json:"type"`
+		Frequencies []float64 `json:"frequencies"`
+	}
+	require.NoError(t, json.Unmarshal(sender.sent[0], &payload))
+	assert.Equal(t, "frequency_axis", payload.Type)
+	assert.Equal(t, []float64{20, 30, 40}, payload.Frequencies, "Frequencies should follow the emitted bins")
+
+	axis.sendAxis(sender, frame)
+	assert.Len(t, sender.sent, 1, "An unchanged axis should not be sent again")
+
+	frame.Magnitudes = make([]float64, 2)
+	axis.sendAxis(sender, frame)
+	assert.Len(t, sender.sent, 2, "A changed axis should be sent")
+}
+
+func TestFFTPayload_Spectra(t *testing.T) {
+	frame := &stage.FFTData{Magnitudes: []float64{1}}
+	_, ok := fftPayload(frame)["spectra"]
+	assert.False(t, ok, "Spectra should be omitted without additional resolutions")
+
+	frame.Spectra = []stage.Spectrum{{Magnitudes: []float64{2, 3}, FrequencyStart: 0, FrequencyResolution: 10.8, FFTSize: 4096}}
+	data, err := json.Marshal(fftPayload(frame))
+	require.NoError(t, err)
+
+	var payload struct {
+		Spectra []struct {
+			FFTSize    int       `json:"fftSize"`
+			Magnitudes []float64 `json:"magnitudes"`
+		} `json:"spectra"`
+	}
+	require.NoError(t, json.Unmarshal(data, &payload))
+	require.Len(t, payload.Spectra, 1)
+	assert.Equal(t, 4096, payload.Spectra[0].FFTSize, "Each spectrum should be labeled with its FFT size")
+	assert.Equal(t, []float64{2, 3}, payload.Spectra[0].Magnitudes)
+}
+
+func TestFFTPayload_Phases(t *testing.T) {
+	frame := &stage.FFTData{Magnitudes: []float64{1, 2}}
+	_, ok := fftPayload(frame)["phases"]
+	assert.False(t, ok, "Phases should be omitted unless emitted")
+
+	frame.Phases = []float64{0.5, -1.5}
+	assert.Equal(t, []float64{0.5, -1.5}, fftPayload(frame)["phases"], "Phases should be sent bin for bin")
+}
+
+func TestFFTPayload_BPMBelowMinConfidence(t *testing.T) {
+	// Below dsp.bpm_min_confidence the engine zeroes BPM and keeps RawBPM.
+	frame := &stage.FFTData{Magnitudes: []float64{1}, RawBPM: 126, BPMConfidence: 0.2}
+	payload := fftPayload(frame)
+
+	assert.Equal(t, false, payload["bpmValid"])
+	assert.Equal(t, 0.0, payload["bpm"], "bpm should be gated by the confidence")
+	assert.Equal(t, 126.0, payload["bpmRaw"])
+	assert.Equal(t, 126.0, payload["bpmInstant"], "bpmInstant should follow every estimate for sync")
+}
+
+func TestFFTPayload_Latency(t *testing.T) {
+	frame := &stage.FFTData{Magnitudes: []float64{1}}
+	_, ok := fftPayload(frame)["latencyMs"]
+	assert.False(t, ok, "The latency should be omitted unless reported")
+
+	frame.CapturedAt = time.Now().Add(-20 * time.Millisecond)
+	frame.OnsetDelay = 30 * time.Millisecond
+	payload := fftPayload(frame)
+	latency := payload["latencyMs"].(float64)
+	assert.GreaterOrEqual(t, latency, 20.0, "The latency should be measured up to serialization")
+	assert.Less(t, latency, 1000.0)
+	assert.InDelta(t, latency+30, payload["onsetLatencyMs"], 1e-9, "Onsets should add the peak-picking delay")
+}
+
+func TestFFTPayload_BandOnsets(t *testing.T) {
+	frame := &stage.FFTData{Magnitudes: []float64{1}}
+	_, ok := fftPayload(frame)["bandOnsets"]
+	assert.False(t, ok, "Band onsets should be omitted unless enabled")
+
+	frame.BandOnsets = []stage.BandOnset{
+		{Name: "low", FramesSinceOnset: 0, Strength: 1.5, Onset: true},
+		{Name: "high", FramesSinceOnset: -1},
+	}
+	data, err := json.Marshal(fftPayload(frame))
+	require.NoError(t, err)
+
+	var payload struct {
+		BandOnsets []struct {
+			Name             string  `json:"name"`
+			Onset            bool    `json:"onset"`
+			FramesSinceOnset int64   `json:"framesSinceOnset"`
+			OnsetStrength    float64 `json:"onsetStrength"`
+		} `json:"bandOnsets"`
+	}
+	require.NoError(t, json.Unmarshal(data, &payload))
+	require.Len(t, payload.BandOnsets, 2)
+	assert.Equal(t, "low", payload.BandOnsets[0].Name)
+	assert.True(t, payload.BandOnsets[0].Onset, "The low band should report its onset")
+	assert.Equal(t, 1.5, payload.BandOnsets[0].OnsetStrength)
+	assert.False(t, payload.BandOnsets[1].Onset, "The high band should not report an onset")
+	assert.Equal(t, int64(-1), payload.BandOnsets[1].FramesSinceOnset)
+}
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"phase4/internal/p4/runtime/pipeline"
+	"phase4/internal/p4/runtime/stage"
+)
+
+// NewRecordComponent creates a sink that writes every FFTData it receives to w
+// as a JSON line holding a RecordedFrame, for a later replay through the
+// router. The first write error is logged and ends the recording. terminal has
+// the same meaning as for NewLogComponent.
+func NewRecordComponent(id string, capacity int, w io.Writer, terminal bool) *RecordComponent {
+	a := &RecordComponent{
+		encoder:  json.NewEncoder(w),
+		terminal: terminal,
+	}
+	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
+
+	return a
+}
+
+func (a *RecordComponent) processMessage(ctx context.Context, msg stage.Message) {
+	m, ok := msg.(*stage.FFTData)
+	if !ok {
+		log.Printf("Record[%s] ➜ Warning ➜ Received unexpected message type: %T", a.ID(), msg)
+		return
+	}
+	if a.terminal {
+		defer pipeline.FftDataPool.Put(m)
+	}
+	if a.failed {
+		return
+	}
+
+	if a.first.IsZero() {
+		a.first = m.StartTime
+	}
+	if err := a.encoder.Encode(RecordedFrame{Frame: m, Offset: m.StartTime.Sub(a.first)}); err != nil {
+		log.Printf("Record[%s] ➜ Error ➜ Recording stopped: %v", a.ID(), err)
+		a.failed = true
+	}
+}
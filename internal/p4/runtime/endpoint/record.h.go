@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"encoding/json"
+	"phase4/internal/p4/runtime/stage"
+	"time"
+)
+
+// RecordedFrame is one line of a recording, see NewRecordComponent. Offset is
+// the capture time of the frame relative to the first recorded frame.
+type RecordedFrame struct {
+	Frame  *stage.FFTData `json:"frame"`
+	Offset time.Duration  `json:"offset"`
+}
+
+type RecordComponent struct {
+	first   time.Time // Capture time of the first recorded frame.
+	encoder *json.Encoder
+	stage.BaseActor
+	failed   bool // A write failed, the recording stopped.
+	terminal bool
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordComponent(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewRecordComponent("record", 1, &buf, false)
+
+	start := time.Unix(1000, 0)
+	a.processMessage(context.Background(), &stage.FFTData{FrameCount: 1, StartTime: start, Magnitudes: []float64{0.5}})
+	a.processMessage(context.Background(), &stage.FFTData{FrameCount: 2, StartTime: start.Add(20 * time.Millisecond), BPM: 120})
+
+	decoder := json.NewDecoder(&buf)
+	var frames []RecordedFrame
+	for decoder.More() {
+		var frame RecordedFrame
+		require.NoError(t, decoder.Decode(&frame))
+		frames = append(frames, frame)
+	}
+
+	require.Len(t, frames, 2, "Every frame should be recorded on its own line")
+	assert.Equal(t, time.Duration(0), frames[0].Offset, "The first frame starts the recording")
+	assert.Equal(t, []float64{0.5}, frames[0].Frame.Magnitudes)
+	assert.Equal(t, 20*time.Millisecond, frames[1].Offset, "Offsets should follow the capture time")
+	assert.Equal(t, uint64(2), frames[1].Frame.FrameCount)
+	assert.Equal(t, 120.0, frames[1].Frame.BPM)
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"phase4/internal/p4/runtime/pipeline"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"time"
+)
+
+// NewStdoutComponent creates a dry-run sink that prints one compact line per
+// FFTData frame to stdout, at most once per interval. It stands in for a network
+// transport while developing analysis features. terminal has the same meaning as
+// for NewLogComponent.
+func NewStdoutComponent(id string, capacity int, interval time.Duration, terminal bool) *StdoutComponent {
+	a := &StdoutComponent{
+		out:      os.Stdout,
+		interval: interval,
+		terminal: terminal,
+		clock:    clock.Real{},
+	}
+	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
+
+	return a
+}
+
+// SetClock replaces the clock used to throttle printing, it must be called
+// before the component is started.
+func (a *StdoutComponent) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+func (a *StdoutComponent) processMessage(ctx context.Context, msg stage.Message) {
+	m, ok := msg.(*stage.FFTData)
+	if !ok {
+		log.Printf("Stdout[%s] ➜ Warning ➜ Received unexpected message type: %T", a.ID(), msg)
+		return
+	}
+	if a.terminal {
+		defer pipeline.FftDataPool.Put(m)
+	}
+
+	if !a.lastPrinted.allow(m.SourceID, a.clock.Now(), a.interval) {
+		return
+	}
+
+	peakIdx, peakMag := 0, 0.0
+	for i, mag := range m.Magnitudes {
+		if mag > peakMag {
+			peakIdx, peakMag = i, mag
+		}
+	}
+	peakFreq := m.FrequencyStart + float64(peakIdx)*m.FrequencyResolution
+
+	locked := "-"
+	if m.TempoLocked {
+		locked = "L"
+	}
+
+	// One whitespace separated key=value line per frame, easy to grep or pipe
+	// into awk while iterating on detection.
+	fmt.Fprintf(a.out, "frame=%d t=%.3f bpm=%.1f conf=%.2f lock=%s onset=%d strength=%.2f peak=%.1fHz mag=%.4f rms=%.4f clip=%d\n",
+		m.FrameCount, m.AudioTime, m.BPM, m.BPMConfidence, locked, m.FramesSinceOnset,
+		m.OnsetStrength, peakFreq, peakMag, m.RMS, m.ClipCount)
+}
@@ -0,0 +1,18 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"io"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"time"
+)
+
+type StdoutComponent struct {
+	out         io.Writer
+	lastPrinted sourceThrottle // Per input source, see sourceThrottle.allow.
+	clock       clock.Clock
+	stage.BaseActor
+	interval time.Duration
+	terminal bool
+}
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdoutComponent_PrintsThrottledSummary(t *testing.T) {
+	var out bytes.Buffer
+	clk := clock.NewManual(time.Unix(1000, 0))
+
+	a := NewStdoutComponent("stdout", 1, 100*time.Millisecond, false)
+	a.SetClock(clk)
+	a.out = &out
+
+	frame := &stage.FFTData{
+		FrameCount:          7,
+		BPM:                 128,
+		BPMConfidence:       0.5,
+		TempoLocked:         true,
+		Magnitudes:          []float64{0.1, 0.9, 0.2},
+		FrequencyStart:      100,
+		FrequencyResolution: 50,
+	}
+
+	a.processMessage(context.Background(), frame)
+	clk.Advance(50 * time.Millisecond)
+	a.processMessage(context.Background(), frame)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 1, "A frame within the interval should not be printed")
+	assert.Contains(t, lines[0], "frame=7 ")
+	assert.Contains(t, lines[0], "bpm=128.0 conf=0.50 lock=L ")
+	assert.Contains(t, lines[0], "peak=150.0Hz mag=0.9000 ")
+
+	clk.Advance(50 * time.Millisecond)
+	a.processMessage(context.Background(), frame)
+	assert.Equal(t, 2, strings.Count(out.String(), "\n"), "A frame after the interval should be printed")
+}
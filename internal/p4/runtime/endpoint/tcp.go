@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/internal/p4/transport"
+	"time"
+)
+
+func NewTcpComponent(id string, capacity int, sender transport.Component) *TcpComponent {
+	if sender == nil {
+		log.Panicf("NewTcpComponent requires a non-nil DataSender")
+	}
+
+	a := &TcpComponent{
+		sender: sender,
+	}
+	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
+
+	return a
+}
+
+// SetHeartbeat makes the endpoint send a heartbeat whenever no frame was sent
+// for interval, zero disables it. It must be called before the component is
+// started.
+func (a *TcpComponent) SetHeartbeat(interval time.Duration) {
+	a.heartbeat.interval = interval
+}
+
+// Start starts the process loop and, if enabled, the heartbeat ticker.
+func (a *TcpComponent) Start(ctx context.Context) error {
+	if err := a.BaseActor.Start(ctx); err != nil {
+		return err
+	}
+	a.heartbeat.start(ctx, &a.BaseActor)
+	return nil
+}
+
+func (a *TcpComponent) processMessage(ctx context.Context, msg stage.Message) {
+	switch m := msg.(type) {
+	case *stage.FFTData:
+		a.axis.sendAxis(a.sender, m)
+
+		jsonData, err := json.Marshal(fftPayload(m))
+		if err != nil {
+			return
+		}
+
+		// Send the JSON line to the TCP sender, ignore the error
+		_ = a.sender.SendData(jsonData)
+		a.heartbeat.sent(time.Now())
+
+	case heartbeatTick:
+		a.heartbeat.beat(a.sender, time.Now())
+
+	default:
+		// log something about unexpected message type
+	}
+}
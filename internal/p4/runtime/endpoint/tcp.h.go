@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"phase4/internal/p4/runtime/stage"
+	"phase4/internal/p4/transport"
+)
+
+type TcpComponent struct {
+	axis      frequencyAxis
+	heartbeat heartbeat
+	sender    transport.Component
+	stage.BaseActor
+}
@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import "time"
+
+// allow reports whether a frame of source may pass at now, at most one per
+// interval and source, and records it if so. With input.extra_sources analyzed
+// separately the sources take turns, a shared interval would let one source
+// take every slot and starve the others.
+func (t *sourceThrottle) allow(source string, now time.Time, interval time.Duration) bool {
+	if *t == nil {
+		*t = make(sourceThrottle)
+	}
+	if last, ok := (*t)[source]; ok && now.Sub(last) < interval {
+		return false
+	}
+	(*t)[source] = now
+	return true
+}
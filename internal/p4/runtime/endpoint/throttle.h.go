@@ -0,0 +1,8 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import "time"
+
+// sourceThrottle is the time of the last frame passed per input source, see
+// allow. Frames without a source share the empty key.
+type sourceThrottle map[string]time.Time
@@ -3,30 +3,99 @@ package endpoint
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"phase4/internal/p4/runtime/stage"
 	"phase4/internal/p4/transport"
+	"phase4/pkg/clock"
+	"time"
 )
 
-func NewUdpComponent(id string, capacity int, sender transport.Component) *UdpComponent {
+func NewUdpComponent(id string, capacity int, interval time.Duration, sender transport.Component) *UdpComponent {
 	if sender == nil {
 		log.Panicf("UdpComponent requires a non-nil DataSender")
 	}
 
 	a := &UdpComponent{
-		sender: sender,
+		sender:   sender,
+		interval: interval,
+		clock:    clock.Real{},
 	}
 	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
 
 	return a
 }
 
+// SetFormat selects how FFTData messages are serialized, it must be called
+// before the component is started. In FormatBinary no frequency axis is sent,
+// receivers derive it from the configured output range.
+func (a *UdpComponent) SetFormat(format PayloadFormat) {
+	a.format = format
+}
+
+// SetClock replaces the clock used to pace sends, it must be called before the
+// component is started.
+func (a *UdpComponent) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// SetHeartbeat makes the endpoint send a heartbeat whenever no frame was sent
+// for interval, zero disables it. Heartbeats are JSON, so none are sent in
+// FormatBinary. It must be called before the component is started.
+func (a *UdpComponent) SetHeartbeat(interval time.Duration) {
+	a.heartbeat.interval = interval
+}
+
+// Start starts the process loop and, if enabled, the heartbeat ticker.
+func (a *UdpComponent) Start(ctx context.Context) error {
+	if err := a.BaseActor.Start(ctx); err != nil {
+		return err
+	}
+	if a.format != FormatBinary {
+		a.heartbeat.start(ctx, &a.BaseActor)
+	}
+	return nil
+}
+
 func (a *UdpComponent) processMessage(ctx context.Context, msg stage.Message) {
-	// switch m := msg.(type) {
-	// case *UdpDataMessage:
-	// 	_ = a.sender.SendData(m.Payload)
+	switch m := msg.(type) {
+	case *stage.FFTData:
+		// Frames arriving faster than the send interval are dropped, UDP
+		// receivers are typically render loops with a fixed frame rate.
+		now := a.clock.Now()
+		if !a.lastSent.allow(m.SourceID, now, a.interval) {
+			return
+		}
+
+		if a.format == FormatBinary {
+			// The transport writes synchronously, so the buffer can be reused.
+			a.buf = appendBinaryPayload(a.buf[:0], m)
+			_ = a.sender.SendData(a.buf)
+			return
+		}
+
+		// UDP has no connections to greet, receivers that start late only
+		// get the axis on the next change.
+		a.axis.sendAxis(a.sender, m)
+
+		jsonData, err := json.Marshal(fftPayload(m))
+		if err != nil {
+			return
+		}
 
-	// default:
-	// 	// log something about unexpected message type
-	// }
+		// Send the JSON data to the UDP sender, ignore the error
+		_ = a.sender.SendData(jsonData)
+		a.heartbeat.sent(now)
+
+	case heartbeatTick:
+		a.heartbeat.beat(a.sender, a.clock.Now())
+
+	case *UdpDataMessage:
+		if payload, ok := m.Payload.([]byte); ok {
+			_ = a.sender.SendData(payload)
+		}
+
+	default:
+		// log something about unexpected message type
+	}
 }
@@ -4,11 +4,20 @@ package endpoint
 import (
 	"phase4/internal/p4/runtime/stage"
 	"phase4/internal/p4/transport"
+	"phase4/pkg/clock"
+	"time"
 )
 
 type UdpComponent struct {
-	sender transport.Component
+	axis      frequencyAxis
+	heartbeat heartbeat
+	buf       []byte         // Binary payload, reused across frames.
+	lastSent  sourceThrottle // Per input source, see sourceThrottle.allow.
+	clock     clock.Clock
+	sender    transport.Component
 	stage.BaseActor
+	interval time.Duration
+	format   PayloadFormat
 }
 
 /*
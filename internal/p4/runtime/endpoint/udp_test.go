@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSender struct {
+	sent [][]byte
+}
+
+// SendData records a copy of data, which some endpoints reuse once it returns.
+func (s *recordingSender) SendData(data []byte) error {
+	s.sent = append(s.sent, append([]byte(nil), data...))
+	return nil
+}
+
+func (s *recordingSender) Close() error {
+	return nil
+}
+
+func TestUdpComponent_SendInterval(t *testing.T) {
+	sender := &recordingSender{}
+	clk := clock.NewManual(time.Unix(1000, 0))
+
+	a := NewUdpComponent("udp", 1, 100*time.Millisecond, sender)
+	a.SetClock(clk)
+
+	send := func() {
+		a.processMessage(context.Background(), &stage.FFTData{})
+	}
+
+	send()
+	assert.Len(t, sender.sent, 1, "The first frame should be sent")
+
+	clk.Advance(50 * time.Millisecond)
+	send()
+	assert.Len(t, sender.sent, 1, "A frame within the interval should be dropped")
+
+	clk.Advance(50 * time.Millisecond)
+	send()
+	assert.Len(t, sender.sent, 2, "A frame after the interval should be sent")
+}
+
+func TestUdpComponent_SendIntervalPerSource(t *testing.T) {
+	sender := &recordingSender{}
+	clk := clock.NewManual(time.Unix(1000, 0))
+
+	a := NewUdpComponent("udp", 1, 100*time.Millisecond, sender)
+	a.SetClock(clk)
+
+	send := func(source string) {
+		a.processMessage(context.Background(), &stage.FFTData{SourceID: source})
+	}
+
+	// Two sources interleaved at twice the send rate.
+	for range 4 {
+		send("stage")
+		send("bar")
+		clk.Advance(50 * time.Millisecond)
+	}
+	assert.Len(t, sender.sent, 4, "Each source should get its own interval")
+}
+
+func TestUdpComponent_BinaryFormat(t *testing.T) {
+	sender := &recordingSender{}
+	a := NewUdpComponent("udp", 1, time.Millisecond, sender)
+	a.SetFormat(FormatBinary)
+
+	a.processMessage(context.Background(), &stage.FFTData{
+		FrameCount:    42,
+		BPM:           128,
+		BPMConfidence: 0.75,
+		Magnitudes:    []float64{0.5, 1.5, 2.5},
+	})
+
+	// The axis is not sent in binary mode, the only datagram is the frame.
+	assert.Len(t, sender.sent, 1, "Only the frame should be sent")
+	data := sender.sent[0]
+	assert.Len(t, data, BinaryPayloadSize(3), "The payload should be header plus float32 magnitudes")
+	assert.Equal(t, uint64(42), binary.LittleEndian.Uint64(data[0:]))
+	assert.Equal(t, float32(128), math.Float32frombits(binary.LittleEndian.Uint32(data[8:])))
+	assert.Equal(t, float32(0.75), math.Float32frombits(binary.LittleEndian.Uint32(data[12:])))
+	assert.Equal(t, uint32(3), binary.LittleEndian.Uint32(data[16:]))
+	for i, want := range []float32{0.5, 1.5, 2.5} {
+		assert.Equal(t, want, math.Float32frombits(binary.LittleEndian.Uint32(data[binaryHeaderSize+4*i:])))
+	}
+}
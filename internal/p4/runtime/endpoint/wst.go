@@ -3,7 +3,6 @@ package endpoint
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"phase4/internal/p4/runtime/stage"
 	"phase4/internal/p4/transport"
@@ -23,28 +22,71 @@ func NewWstComponent(id string, capacity int, sender transport.Component) *WstCo
 	return a
 }
 
+// SetHeartbeat makes the endpoint send a heartbeat whenever no frame was sent
+// for interval, zero disables it. It must be called before the component is
+// started.
+func (a *WstComponent) SetHeartbeat(interval time.Duration) {
+	a.heartbeat.interval = interval
+}
+
+// SetMaxBins caps the number of magnitudes per frame, zero disables the cap.
+// Frames with more bins are downsampled by merging adjacent bins into their
+// maximum, so a very large FFT can not flood browsers with huge JSON payloads.
+// It must be called before the component is started.
+func (a *WstComponent) SetMaxBins(bins int) {
+	a.maxBins = bins
+}
+
+// Start starts the process loop and, if enabled, the heartbeat ticker.
+func (a *WstComponent) Start(ctx context.Context) error {
+	if err := a.BaseActor.Start(ctx); err != nil {
+		return err
+	}
+	a.heartbeat.start(ctx, &a.BaseActor)
+	return nil
+}
+
 func (a *WstComponent) processMessage(ctx context.Context, msg stage.Message) {
 	switch m := msg.(type) {
 	case *stage.FFTData:
-		payloadMap := map[string]any{
-			"type":          "fft_magnitudes",
-			"frameCount":    m.FrameCount,
-			"startTime":     m.StartTime.Format(time.RFC3339Nano),
-			"magnitudes":    m.Magnitudes,
-			"spectralFlux":  m.SpectralFlux,
-			"bpm":           m.BPM,           // Add BPM
-			"bpmConfidence": m.BPMConfidence, // Add confidence
-		}
-
-		jsonData, err := json.Marshal(payloadMap)
-		if err != nil {
-			return
-		}
-
-		// Send the JSON data to the WebSocket sender, ignore the error
-		_ = a.sender.SendData(jsonData)
+		m = a.limitBins(m)
+		a.axis.sendAxis(a.sender, m)
+
+		// The WebSocket transport has written the frame to every client when
+		// SendData returns, so the pooled buffer can be reused. Errors are ignored.
+		_ = sendJSON(a.sender, fftPayload(m))
+		a.heartbeat.sent(time.Now())
+
+	case heartbeatTick:
+		a.heartbeat.beat(a.sender, time.Now())
 
 	default:
 		// log something about unexpected message type
 	}
 }
+
+// limitBins returns m, or a copy of m downsampled to at most maxBins bins. The
+// message is shared with other endpoints, so the copy reuses the component's
+// own buffers instead of changing m. Phases can not be merged and are dropped.
+func (a *WstComponent) limitBins(m *stage.FFTData) *stage.FFTData {
+	if a.maxBins <= 0 || len(m.Magnitudes) <= a.maxBins {
+		return m
+	}
+
+	factor := (len(m.Magnitudes) + a.maxBins - 1) / a.maxBins
+	magnitudes, flux := a.reduced.Magnitudes, a.reduced.SpectralFlux
+
+	a.reduced = *m
+	a.reduced.Magnitudes = appendMaxPooled(magnitudes[:0], m.Magnitudes, factor)
+	a.reduced.SpectralFlux = appendMaxPooled(flux[:0], m.SpectralFlux, factor)
+	a.reduced.Phases = nil
+	a.reduced.FrequencyResolution = m.FrequencyResolution * float64(factor)
+
+	if !a.warned {
+		log.Printf("Wst[%s] ➜ Warning ➜ %d bins exceed transport.websocket_max_bins (%d), sending %d bins of %.2f Hz instead",
+			a.ID(), len(m.Magnitudes), a.maxBins, len(a.reduced.Magnitudes), a.reduced.FrequencyResolution)
+		a.warned = true
+	}
+
+	return &a.reduced
+}
@@ -7,6 +7,11 @@ import (
 )
 
 type WstComponent struct {
-	sender transport.Component
+	axis      frequencyAxis
+	heartbeat heartbeat
+	reduced   stage.FFTData // Downsampled copy of the current frame, see SetMaxBins.
+	sender    transport.Component
 	stage.BaseActor
+	maxBins int
+	warned  bool // The downsampling warning was logged.
 }
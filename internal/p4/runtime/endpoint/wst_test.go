@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendMaxPooled(t *testing.T) {
+	src := []float64{1, 3, 2, 5, 4}
+
+	assert.Equal(t, []float64{3, 5, 4}, appendMaxPooled(nil, src, 2), "The last group may be shorter")
+	assert.Equal(t, []float64{3, 5}, appendMaxPooled(nil, src, 3))
+	assert.Equal(t, src, appendMaxPooled(nil, src, 1), "A factor of 1 should copy")
+}
+
+func TestWstComponent_MaxBins(t *testing.T) {
+	sender := &recordingSender{}
+	a := NewWstComponent("ws", 1, sender)
+	a.SetMaxBins(2)
+
+	m := &stage.FFTData{
+		Magnitudes:          []float64{1, 4, 2, 3, 5},
+		SpectralFlux:        []float64{0, 1, 0, 0, 2},
+		Phases:              []float64{0.1, 0.2, 0.3, 0.4, 0.5},
+		FrequencyStart:      100,
+		FrequencyResolution: 10,
+	}
+	a.processMessage(context.Background(), m)
+
+	// The axis, then the frame.
+	require.Len(t, sender.sent, 2)
+	var axis struct{ Frequencies []float64 }
+	require.NoError(t, json.Unmarshal(sender.sent[0], &axis))
+	assert.Equal(t, []float64{100, 130}, axis.Frequencies, "The axis should follow the merged bins")
+
+	var frame struct {
+		Magnitudes          []float64
+		SpectralFlux        []float64
+		Phases              []float64
+		FrequencyStart      float64
+		FrequencyResolution float64
+	}
+	require.NoError(t, json.Unmarshal(sender.sent[1], &frame))
+	assert.Equal(t, []float64{4, 5}, frame.Magnitudes, "Bins should be merged into their maximum")
+	assert.Equal(t, []float64{1, 2}, frame.SpectralFlux)
+	assert.Nil(t, frame.Phases, "Phases can not be merged")
+	assert.Equal(t, 100.0, frame.FrequencyStart)
+	assert.Equal(t, 30.0, frame.FrequencyResolution)
+
+	assert.Equal(t, []float64{1, 4, 2, 3, 5}, m.Magnitudes, "The shared message should not be changed")
+	assert.Len(t, m.Phases, 5, "The shared message should not be changed")
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"phase4/internal/p4/analysis"
+	"phase4/internal/p4/runtime/stage"
+	"time"
+)
+
+func NewControl(id string, capacity int, bpm *analysis.BPMDetector) (*ControlComponent, error) {
+	if bpm == nil {
+		return nil, fmt.Errorf("ControlComponent[%s] requires a non-nil BPM detector", id)
+	}
+
+	a := &ControlComponent{bpm: bpm}
+	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
+
+	return a, nil
+}
+
+// SetBandOnsets makes CommandBPMConfigure apply the new onset parameters to the
+// band detectors as well, which share them with the BPM detector. It must be
+// called before the component is started.
+func (a *ControlComponent) SetBandOnsets(bands *analysis.BandOnsetDetector) {
+	a.bands = bands
+}
+
+func (a *ControlComponent) processMessage(ctx context.Context, msg stage.Message) {
+	ctrl, ok := msg.(*stage.ControlMessage)
+	if !ok {
+		log.Printf("Control[%s] ➜ Warning ➜ Received unexpected message type: %T", a.ID(), msg)
+		return
+	}
+
+	switch ctrl.Command {
+	case stage.CommandBPMConfigure:
+		cfg, err := bpmConfig(a.bpm.Config(), ctrl.Params)
+		if err != nil {
+			log.Printf("Control[%s] ➜ Warning ➜ %s rejected: %v", a.ID(), ctrl.Command, err)
+			return
+		}
+		a.bpm.Configure(cfg)
+		if a.bands != nil {
+			a.bands.Configure(cfg)
+		}
+		log.Printf("Control[%s] ➜ BPM detector reconfigured: %+v", a.ID(), a.bpm.Config())
+	case stage.CommandSetBPM:
+		bpm, err := floatParam(ctrl.Params["bpm"])
+		if err != nil || bpm < 0 {
+			log.Printf("Control[%s] ➜ Warning ➜ %s requires a non-negative 'bpm' param", a.ID(), ctrl.Command)
+			return
+		}
+		a.bpm.SetFixedTempo(bpm)
+		if bpm == 0 {
+			log.Printf("Control[%s] ➜ Tempo estimation resumed", a.ID())
+			return
+		}
+		log.Printf("Control[%s] ➜ Tempo fixed at %.2f BPM", a.ID(), bpm)
+	default:
+		log.Printf("Control[%s] ➜ Warning ➜ Unknown control command: %q", a.ID(), ctrl.Command)
+	}
+}
+
+// bpmConfig returns cfg with the parameters of a CommandBPMConfigure applied.
+// Unknown or malformed parameters reject the whole command, so a typo never
+// leaves the detector half reconfigured.
+func bpmConfig(cfg analysis.BPMConfig, params map[string]any) (analysis.BPMConfig, error) {
+	for key, value := range params {
+		var err error
+		switch key {
+		case "onset_threshold":
+			cfg.OnsetThreshold, err = floatParam(value)
+		case "onset_memory":
+			cfg.OnsetMemory, err = durationParam(value)
+		case "onset_pre_window":
+			cfg.OnsetPreWindow, err = intParam(value)
+		case "onset_post_window":
+			cfg.OnsetPostWindow, err = intParam(value)
+		case "onset_interval_beats":
+			cfg.OnsetIntervalBeats, err = floatParam(value)
+		case "tempo_hint":
+			cfg.TempoHintLow, cfg.TempoHintHigh, err = rangeParam(value)
+		default:
+			err = fmt.Errorf("unknown parameter")
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// floatParam accepts any Go number, JSON numbers decode as float64.
+func floatParam(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("expected a number, got %T", value)
+	}
+}
+
+func intParam(value any) (int, error) {
+	v, err := floatParam(value)
+	if err != nil {
+		return 0, err
+	}
+	if v != float64(int(v)) {
+		return 0, fmt.Errorf("expected an integer, got %v", v)
+	}
+	return int(v), nil
+}
+
+// durationParam accepts a duration string such as "5s", or a number of seconds.
+func durationParam(value any) (time.Duration, error) {
+	if s, ok := value.(string); ok {
+		return time.ParseDuration(s)
+	}
+	seconds, err := floatParam(value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// rangeParam accepts a [low, high] pair, an empty list clears the range.
+func rangeParam(value any) (lo, hi float64, err error) {
+	var values []float64
+	switch v := value.(type) {
+	case []float64:
+		values = v
+	case []any:
+		for _, item := range v {
+			f, err := floatParam(item)
+			if err != nil {
+				return 0, 0, err
+			}
+			values = append(values, f)
+		}
+	default:
+		return 0, 0, fmt.Errorf("expected a [low, high] list, got %T", value)
+	}
+
+	switch len(values) {
+	case 0:
+		return 0, 0, nil
+	case 2:
+		return values[0], values[1], nil
+	default:
+		return 0, 0, fmt.Errorf("expected a [low, high] list, got %d values", len(values))
+	}
+}
@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: Apache-2.0
+package pipeline
+
+import (
+	"phase4/internal/p4/analysis"
+	"phase4/internal/p4/runtime/stage"
+)
+
+// ControlComponent applies control messages to the analysis, which runs
+// outside the actor system.
+type ControlComponent struct {
+	bpm   *analysis.BPMDetector
+	bands *analysis.BandOnsetDetector // Configured along with bpm, nil without dsp.band_onsets.
+	stage.BaseActor
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+package pipeline
+
+import (
+	"context"
+	"phase4/internal/p4/analysis"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControl_BPMConfigure(t *testing.T) {
+	bpm := analysis.NewBPMDetector(44100, 512)
+	control, err := NewControl("control", 1, bpm)
+	require.NoError(t, err, "NewControl should succeed")
+
+	configure := func(params map[string]any) {
+		control.processMessage(context.Background(), &stage.ControlMessage{
+			Command: stage.CommandBPMConfigure,
+			Params:  params,
+		})
+	}
+
+	// Params as decoded from JSON.
+	configure(map[string]any{
+		"onset_threshold":   0.3,
+		"onset_memory":      "4s",
+		"onset_post_window": float64(2),
+		"tempo_hint":        []any{float64(120), float64(140)},
+	})
+	cfg := bpm.Config()
+	assert.Equal(t, 0.3, cfg.OnsetThreshold)
+	assert.Equal(t, 4*time.Second, cfg.OnsetMemory)
+	assert.Equal(t, 2, cfg.OnsetPostWindow)
+	assert.Equal(t, 120.0, cfg.TempoHintLow)
+	assert.Equal(t, 140.0, cfg.TempoHintHigh)
+
+	testCases := []struct {
+		name   string
+		params map[string]any
+	}{
+		{"UnknownParam", map[string]any{"onset_threshold": 0.9, "threshold": 1}},
+		{"WrongType", map[string]any{"onset_threshold": 0.9, "onset_pre_window": "3"}},
+		{"Fraction", map[string]any{"onset_threshold": 0.9, "onset_pre_window": 1.5}},
+		{"ShortRange", map[string]any{"onset_threshold": 0.9, "tempo_hint": []any{float64(120)}}},
+		{"BadDuration", map[string]any{"onset_threshold": 0.9, "onset_memory": "soon"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configure(tc.params)
+			assert.Equal(t, cfg, bpm.Config(), "A rejected command should not change anything")
+		})
+	}
+
+	configure(map[string]any{"onset_memory": 2, "tempo_hint": []float64{}})
+	cfg = bpm.Config()
+	assert.Equal(t, 2*time.Second, cfg.OnsetMemory, "A number should be read as seconds")
+	assert.Zero(t, cfg.TempoHintHigh, "An empty range should remove the hint")
+}
+
+func TestNewControl_NilDetector(t *testing.T) {
+	_, err := NewControl("control", 1, nil)
+	assert.Error(t, err, "A nil detector should be rejected")
+}
+
+func TestControl_SetBPM(t *testing.T) {
+	bpm := analysis.NewBPMDetector(44100, 512)
+	control, err := NewControl("control", 1, bpm)
+	require.NoError(t, err, "NewControl should succeed")
+
+	setBPM := func(params map[string]any) {
+		control.processMessage(context.Background(), &stage.ControlMessage{Command: stage.CommandSetBPM, Params: params})
+	}
+
+	setBPM(map[string]any{"bpm": float64(128)})
+	assert.Equal(t, 128.0, bpm.FixedTempo(), "The tempo should be fixed")
+	current, _ := bpm.GetBPM()
+	assert.Equal(t, 128.0, current, "The fixed tempo should be reported")
+
+	setBPM(map[string]any{"bpm": "fast"})
+	setBPM(map[string]any{"bpm": float64(-1)})
+	setBPM(nil)
+	assert.Equal(t, 128.0, bpm.FixedTempo(), "Invalid params should be ignored")
+
+	setBPM(map[string]any{"bpm": 0})
+	assert.Zero(t, bpm.FixedTempo(), "A bpm of 0 should resume estimation")
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+package pipeline
+
+import (
+	"context"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturedFrame is what the capturing endpoint saw of one FFTData message,
+// copied out before the message is returned to the pool.
+type capturedFrame struct {
+	magnitudesAt *float64 // First element of the message's Magnitudes array.
+	magnitudes   []float64
+	spectralFlux []float64
+	frameCount   uint64
+	bpm          float64
+	confidence   float64
+	locked       bool
+}
+
+// newPipeline registers a processor, a router and a capturing endpoint that
+// returns each message to FftDataPool, like a terminal endpoint does.
+func newPipeline(t *testing.T) (*stage.System, <-chan capturedFrame) {
+	t.Helper()
+
+	system := stage.NewSystem()
+	frames := make(chan capturedFrame, 4)
+	capture := stage.NewBaseActor("capture", 4, func(ctx context.Context, msg stage.Message) {
+		m, ok := msg.(*stage.FFTData)
+		if !ok {
+			return
+		}
+		defer FftDataPool.Put(m)
+
+		frames <- capturedFrame{
+			magnitudesAt: &m.Magnitudes[:1][0],
+			magnitudes:   append([]float64(nil), m.Magnitudes...),
+			spectralFlux: append([]float64(nil), m.SpectralFlux...),
+			frameCount:   m.FrameCount,
+			bpm:          m.BPM,
+			confidence:   m.BPMConfidence,
+			locked:       m.TempoLocked,
+		}
+	})
+
+	router, err := NewRouter("router", 4, []string{"capture"}, system)
+	require.NoError(t, err, "NewRouter should succeed")
+	processor, err := NewProcessor("processor", 4, "router", system)
+	require.NoError(t, err, "NewProcessor should succeed")
+
+	for _, actor := range []stage.Actor{capture, router, processor} {
+		require.NoError(t, system.Register(actor), "Register should succeed")
+	}
+	require.Nil(t, system.StartAll(), "StartAll should succeed")
+
+	return system, frames
+}
+
+func TestPipeline_RawAudioToEndpoint(t *testing.T) {
+	system, frames := newPipeline(t)
+	defer system.Close()
+
+	raw := stage.GetRawMessage()
+	raw.FrameCount = 42
+	raw.BPM = 128
+	raw.BPMConfidence = 0.8
+	raw.TempoLocked = true
+	raw.Magnitudes = append(raw.Magnitudes, 0.1, 0.5, 0.25)
+	raw.SpectralFlux = append(raw.SpectralFlux, 0, 0.3, 0)
+	rawMagnitudes := raw.Magnitudes
+
+	require.NoError(t, system.Send("processor", raw), "Send should succeed")
+
+	var frame capturedFrame
+	select {
+	case frame = <-frames:
+	case <-time.After(time.Second):
+		t.Fatal("The endpoint did not receive the frame")
+	}
+
+	assert.Equal(t, uint64(42), frame.frameCount, "Unexpected frame count")
+	assert.Equal(t, 128.0, frame.bpm, "Unexpected BPM")
+	assert.Equal(t, 0.8, frame.confidence, "Unexpected BPM confidence")
+	assert.True(t, frame.locked, "The tempo lock should be forwarded")
+	assert.Equal(t, []float64{0.1, 0.5, 0.25}, frame.magnitudes, "Unexpected magnitudes")
+	assert.Equal(t, []float64{0, 0.3, 0}, frame.spectralFlux, "Unexpected spectral flux")
+
+	// Stopping waits for the actors, so the raw message has been recycled.
+	assert.Nil(t, system.StopAll(), "StopAll should succeed")
+	assert.Equal(t, uint64(0), raw.FrameCount, "The raw message should be reset and returned to the pool")
+	assert.Empty(t, raw.Magnitudes, "The raw message should be reset and returned to the pool")
+	assert.NotSame(t, &rawMagnitudes[0], frame.magnitudesAt, "Magnitudes should be copied, not aliased")
+}
+
+func TestSetFFTDataCapacity(t *testing.T) {
+	defer SetFFTDataCapacity(fftDataCapacity)
+
+	SetFFTDataCapacity(513)
+	msg := FftDataPool.New().(*stage.FFTData)
+
+	assert.Equal(t, 513, cap(msg.Magnitudes), "Magnitudes capacity should match the configured bin count")
+	assert.Equal(t, 513, cap(msg.SpectralFlux), "SpectralFlux capacity should match the configured bin count")
+}
+
+func TestPipeline_MissingRouter(t *testing.T) {
+	system := stage.NewSystem()
+	defer system.Close()
+
+	processor, err := NewProcessor("processor", 1, "router", system)
+	require.NoError(t, err, "NewProcessor should succeed")
+	require.NoError(t, system.Register(processor), "Register should succeed")
+	require.Nil(t, system.StartAll(), "StartAll should succeed")
+
+	raw := stage.GetRawMessage()
+	raw.FrameCount = 7
+	raw.Magnitudes = append(raw.Magnitudes, 1)
+	require.NoError(t, system.Send("processor", raw), "Send should succeed")
+
+	// Stop drains the mailbox before returning.
+	require.NoError(t, processor.Stop(), "Stop should succeed")
+	assert.Equal(t, uint64(0), raw.FrameCount, "The raw message should be returned even if forwarding fails")
+	assert.Empty(t, raw.Magnitudes, "The raw message should be returned even if forwarding fails")
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"phase4/internal/p4/runtime/stage"
-	"time"
 )
 
 func NewProcessor(id string, capacity int, routerID string, system *stage.System) (*ProcessorComponent, error) {
@@ -38,9 +37,35 @@ func (a *ProcessorComponent) processMessage(ctx context.Context, msg stage.Messa
 
 	fftMsg := FftDataPool.Get().(*stage.FFTData)
 	fftMsg.FrameCount = rawMsg.FrameCount
-	fftMsg.StartTime = time.Now()
+	fftMsg.StartTime = rawMsg.AnalysisStart
+	fftMsg.SourceID = rawMsg.SourceID
+	fftMsg.AudioTime = rawMsg.AudioTime
+	fftMsg.CapturedAt = rawMsg.CapturedAt
+	fftMsg.OnsetDelay = rawMsg.OnsetDelay
+	fftMsg.RMS = rawMsg.RMS
+	fftMsg.Peak = rawMsg.Peak
+	fftMsg.ClipCount = rawMsg.ClipCount
+	fftMsg.Channels = rawMsg.Channels
+	fftMsg.Clipping = rawMsg.Clipping
 	fftMsg.BPM = rawMsg.BPM
+	fftMsg.RawBPM = rawMsg.RawBPM
+	fftMsg.BPMValid = rawMsg.BPMValid
+	fftMsg.DisplayBPM = rawMsg.DisplayBPM
 	fftMsg.BPMConfidence = rawMsg.BPMConfidence
+	fftMsg.SmoothedConfidence = rawMsg.SmoothedConfidence
+	fftMsg.VariationConfidence = rawMsg.VariationConfidence
+	fftMsg.PeakConfidence = rawMsg.PeakConfidence
+	fftMsg.FramesSinceOnset = rawMsg.FramesSinceOnset
+	fftMsg.OnsetStrength = rawMsg.OnsetStrength
+	fftMsg.BeatPhase = rawMsg.BeatPhase
+	fftMsg.LFO = rawMsg.LFO
+	fftMsg.HasLFO = rawMsg.HasLFO
+	fftMsg.TempoLocked = rawMsg.TempoLocked
+	fftMsg.SpectralFlatness = rawMsg.SpectralFlatness
+	fftMsg.SpectralCrest = rawMsg.SpectralCrest
+	fftMsg.HasFeatures = rawMsg.HasFeatures
+	fftMsg.FrequencyStart = rawMsg.FrequencyStart
+	fftMsg.FrequencyResolution = rawMsg.FrequencyResolution
 
 	// Copy magnitudes
 	if cap(fftMsg.Magnitudes) < len(rawMsg.Magnitudes) {
@@ -58,6 +83,18 @@ func (a *ProcessorComponent) processMessage(ctx context.Context, msg stage.Messa
 	}
 	copy(fftMsg.SpectralFlux, rawMsg.SpectralFlux)
 
+	// Copy phases, reusing the capacity of a recycled message
+	fftMsg.Phases = append(fftMsg.Phases[:0], rawMsg.Phases...)
+
+	// Copy band onsets, they hold no slices of their own
+	fftMsg.BandOnsets = append(fftMsg.BandOnsets[:0], rawMsg.BandOnsets...)
+
+	// Copy additional resolutions
+	fftMsg.Spectra = fftMsg.Spectra[:0]
+	for _, spectrum := range rawMsg.Spectra {
+		fftMsg.Spectra = stage.AppendSpectrum(fftMsg.Spectra, spectrum)
+	}
+
 	if err := a.system.Send(a.routerID, fftMsg); err != nil {
 		log.Printf("Processor[%s] ➜ Error ➜ Failed to send message to router '%s': %v", a.ID(), a.routerID, err)
 		FftDataPool.Put(fftMsg)
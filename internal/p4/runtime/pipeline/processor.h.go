@@ -6,12 +6,27 @@ import (
 	"sync"
 )
 
+// fftDataCapacity is the slice capacity pre-allocated for pooled messages, it
+// defaults to the bin count of a 256-point FFT.
+var fftDataCapacity = 129
+
 var FftDataPool = sync.Pool{
 	New: func() any {
-		return &stage.FFTData{Magnitudes: make([]float64, 0, 129)}
+		return &stage.FFTData{
+			Magnitudes:   make([]float64, 0, fftDataCapacity),
+			SpectralFlux: make([]float64, 0, fftDataCapacity),
+		}
 	},
 }
 
+// SetFFTDataCapacity sets the slice capacity pre-allocated by FftDataPool,
+// typically the number of FFT bins (fftSize/2 + 1), like
+// stage.SetRawMessageCapacity. It must be called before the first message is
+// processed.
+func SetFFTDataCapacity(bins int) {
+	fftDataCapacity = bins
+}
+
 type ProcessorComponent struct {
 	system   *stage.System
 	routerID string
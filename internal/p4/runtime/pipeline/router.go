@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"phase4/internal/p4/runtime/stage"
+	"slices"
 )
 
 func NewRouter(id string, capacity int, targetIDs []string, system *stage.System) (*RouterComponent, error) {
@@ -14,7 +15,8 @@ func NewRouter(id string, capacity int, targetIDs []string, system *stage.System
 	}
 
 	a := &RouterComponent{
-		targetIDs: targetIDs,
+		targetIDs: slices.Clone(targetIDs),
+		configIDs: slices.Clone(targetIDs),
 		system:    system,
 	}
 	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
@@ -23,16 +25,20 @@ func NewRouter(id string, capacity int, targetIDs []string, system *stage.System
 }
 
 func (a *RouterComponent) processMessage(ctx context.Context, msg stage.Message) {
+	if ctrl, ok := msg.(*stage.ControlMessage); ok {
+		a.handleControl(ctrl)
+		return
+	}
+
 	fftMsg, ok := msg.(*stage.FFTData)
 	if !ok {
-		log.Printf("Router[%s] ➜ Warning ➜ Received unexpected message type: %T", a.ID(), msg)
-		// If this unexpected message happens to be a pooled type, it might leak.
-		// The LogComponent is the designated pool handler, so we don't Put here.
-		// Consider if more robust handling is needed for unexpected pooled types.
+		a.dropMessage(msg)
 		return
 	}
 
 	// Sends the FFTData message to all target clients.
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	for _, targetID := range a.targetIDs {
 		if err := a.system.Send(targetID, fftMsg); err != nil {
 			log.Printf("Engine ➜ Stage ➜ Router[%s] ➜ Error ➜ Failed to forward message to target '%s': %v", a.ID(), targetID, err)
@@ -41,6 +47,77 @@ func (a *RouterComponent) processMessage(ctx context.Context, msg stage.Message)
 	}
 
 	// Note: The RouterComponent does not need to handle the message pool.
-	// The message pool is managed by the LogComponent, which is responsible for
-	// returning messages to the pool after processing.
+	// When the LogComponent is the only target it returns messages to the pool
+	// after processing, otherwise they are left to the garbage collector.
+}
+
+// dropMessage discards a message the router cannot forward. Raw audio messages
+// belong in front of the processor, they are returned to their pool so a
+// misrouted message does not leak pooled buffers.
+func (a *RouterComponent) dropMessage(msg stage.Message) {
+	switch m := msg.(type) {
+	case *stage.RawAudioMessage:
+		log.Printf("Router[%s] ➜ Warning ➜ Dropping raw audio frame %d, expected processed FFT data", a.ID(), m.FrameCount)
+		stage.PutRawMessage(m)
+	default:
+		log.Printf("Router[%s] ➜ Warning ➜ Received unexpected message type: %T", a.ID(), msg)
+	}
+}
+
+func (a *RouterComponent) handleControl(msg *stage.ControlMessage) {
+	switch msg.Command {
+	case stage.CommandEndpointEnable:
+		id, _ := msg.Params["id"].(string)
+		enabled, ok := msg.Params["enabled"].(bool)
+		if !ok {
+			log.Printf("Router[%s] ➜ Warning ➜ %s requires a boolean 'enabled' param", a.ID(), msg.Command)
+			return
+		}
+		if err := a.SetTargetEnabled(id, enabled); err != nil {
+			log.Printf("Router[%s] ➜ Warning ➜ %v", a.ID(), err)
+			return
+		}
+		log.Printf("Router[%s] ➜ Target '%s' enabled: %v", a.ID(), id, enabled)
+	default:
+		log.Printf("Router[%s] ➜ Warning ➜ Unknown control command: %q", a.ID(), msg.Command)
+	}
+}
+
+// SetTargetEnabled starts or stops forwarding to a configured target at runtime.
+// Re-enabled targets keep their configured position in the forwarding order.
+func (a *RouterComponent) SetTargetEnabled(id string, enabled bool) error {
+	if !slices.Contains(a.configIDs, id) {
+		return fmt.Errorf("unknown router target '%s'", id)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	active := slices.Contains(a.targetIDs, id)
+	if enabled == active {
+		return nil
+	}
+
+	targets := make([]string, 0, len(a.configIDs))
+	for _, targetID := range a.configIDs {
+		if targetID == id {
+			if enabled {
+				targets = append(targets, targetID)
+			}
+			continue
+		}
+		if slices.Contains(a.targetIDs, targetID) {
+			targets = append(targets, targetID)
+		}
+	}
+	a.targetIDs = targets
+
+	return nil
+}
+
+// Targets returns the currently enabled targets.
+func (a *RouterComponent) Targets() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return slices.Clone(a.targetIDs)
 }
@@ -1,10 +1,15 @@
 // SPDX-License-Identifier: Apache-2.0
 package pipeline
 
-import "phase4/internal/p4/runtime/stage"
+import (
+	"phase4/internal/p4/runtime/stage"
+	"sync"
+)
 
 type RouterComponent struct {
 	system    *stage.System
-	targetIDs []string
+	targetIDs []string // Enabled targets, in configured order.
+	configIDs []string // All configured targets.
 	stage.BaseActor
+	mu sync.RWMutex // Protects targetIDs.
 }
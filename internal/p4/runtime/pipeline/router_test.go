@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+package pipeline
+
+import (
+	"context"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_SetTargetEnabled(t *testing.T) {
+	router, err := NewRouter("router", 1, []string{"ws", "tcp", "udp"}, stage.NewSystem())
+	require.NoError(t, err, "NewRouter should succeed")
+
+	require.NoError(t, router.SetTargetEnabled("ws", false))
+	assert.Equal(t, []string{"tcp", "udp"}, router.Targets(), "ws should be removed")
+
+	require.NoError(t, router.SetTargetEnabled("udp", false))
+	require.NoError(t, router.SetTargetEnabled("ws", true))
+	assert.Equal(t, []string{"ws", "tcp"}, router.Targets(), "ws should return to its configured position")
+
+	require.NoError(t, router.SetTargetEnabled("tcp", true), "Enabling an active target is a no-op")
+	assert.Equal(t, []string{"ws", "tcp"}, router.Targets())
+
+	assert.Error(t, router.SetTargetEnabled("midi", true), "Unknown targets should be rejected")
+}
+
+func TestRouter_EndpointEnableControlMessage(t *testing.T) {
+	router, err := NewRouter("router", 1, []string{"ws", "udp"}, stage.NewSystem())
+	require.NoError(t, err, "NewRouter should succeed")
+
+	router.processMessage(context.Background(), &stage.ControlMessage{
+		Command: stage.CommandEndpointEnable,
+		Params:  map[string]any{"id": "udp", "enabled": false},
+	})
+	assert.Equal(t, []string{"ws"}, router.Targets(), "udp should be disabled")
+
+	// Malformed params are ignored.
+	router.processMessage(context.Background(), &stage.ControlMessage{
+		Command: stage.CommandEndpointEnable,
+		Params:  map[string]any{"id": "ws", "enabled": "no"},
+	})
+	assert.Equal(t, []string{"ws"}, router.Targets(), "Targets should be unchanged")
+}
+
+func TestRouter_UnexpectedMessages(t *testing.T) {
+	system := stage.NewSystem()
+	defer system.Close()
+
+	router, err := NewRouter("router", 1, []string{"ws"}, system)
+	require.NoError(t, err, "NewRouter should succeed")
+
+	assert.NotPanics(t, func() {
+		router.processMessage(context.Background(), &stage.ControlMessage{Command: "unknown"})
+		router.processMessage(context.Background(), &stage.DataMessage{Data: "text"})
+		router.processMessage(context.Background(), &stage.StatusMessage{Status: "ok"})
+	}, "Unexpected messages should be dropped")
+	assert.Equal(t, []string{"ws"}, router.Targets(), "Targets should be unchanged")
+
+	raw := stage.GetRawMessage()
+	raw.FrameCount = 3
+	raw.Magnitudes = append(raw.Magnitudes, 1, 2)
+	router.processMessage(context.Background(), raw)
+	assert.Equal(t, uint64(0), raw.FrameCount, "A misrouted raw message should be returned to the pool")
+	assert.Empty(t, raw.Magnitudes, "A misrouted raw message should be returned to the pool")
+}
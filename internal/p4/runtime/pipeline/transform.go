@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"phase4/internal/p4/runtime/stage"
+)
+
+// NewTransform creates a stage applying transform to each FFTData message and
+// forwarding the result to targetID, normally the router. Other messages are
+// forwarded unchanged, so control messages pass through. See TransformFunc for
+// the ownership rules.
+func NewTransform(id string, capacity int, targetID string, transform TransformFunc, system *stage.System) (*TransformComponent, error) {
+	if system == nil {
+		return nil, fmt.Errorf("TransformComponent[%s] requires a non-nil system", id)
+	}
+	if targetID == "" {
+		return nil, fmt.Errorf("TransformComponent[%s] requires a non-empty targetID", id)
+	}
+	if transform == nil {
+		return nil, fmt.Errorf("TransformComponent[%s] requires a non-nil transform", id)
+	}
+
+	a := &TransformComponent{
+		transform: transform,
+		targetID:  targetID,
+		system:    system,
+	}
+	a.BaseActor = *stage.NewBaseActor(id, capacity, a.processMessage)
+
+	return a, nil
+}
+
+func (a *TransformComponent) processMessage(ctx context.Context, msg stage.Message) {
+	fftMsg, ok := msg.(*stage.FFTData)
+	if !ok {
+		a.forward(msg)
+		return
+	}
+
+	out := a.transform(fftMsg)
+	if out != msg {
+		FftDataPool.Put(fftMsg)
+	}
+	if out == nil {
+		return
+	}
+	a.forward(out)
+}
+
+// forward sends msg to the target, pooled messages that can not be delivered
+// are returned to FftDataPool.
+func (a *TransformComponent) forward(msg stage.Message) {
+	if err := a.system.Send(a.targetID, msg); err != nil {
+		log.Printf("Transform[%s] ➜ Error ➜ Failed to send message to '%s': %v", a.ID(), a.targetID, err)
+		if fftMsg, ok := msg.(*stage.FFTData); ok {
+			FftDataPool.Put(fftMsg)
+		}
+	}
+}
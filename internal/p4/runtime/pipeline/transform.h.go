@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+package pipeline
+
+import "phase4/internal/p4/runtime/stage"
+
+// TransformFunc computes the message forwarded for one frame. It runs on the
+// transform's process loop, one frame at a time, and owns m until it returns:
+//
+//   - Returning m, modified in place or not, forwards it. This is the cheap
+//     path, no allocation is needed to add a feature to an existing field.
+//   - Returning another message forwards that one instead, m is returned to
+//     FftDataPool afterwards and must not be retained. A replacement *FFTData
+//     should come from FftDataPool, downstream endpoints recycle it.
+//   - Returning nil drops the frame, m is returned to FftDataPool.
+//
+// The router only forwards *stage.FFTData, other message types are dropped
+// there unless the transform targets a custom actor.
+type TransformFunc func(m *stage.FFTData) stage.Message
+
+// TransformComponent applies a user-supplied TransformFunc to every FFTData
+// message between the processor and the router.
+type TransformComponent struct {
+	transform TransformFunc
+	system    *stage.System
+	targetID  string
+	stage.BaseActor
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+package pipeline
+
+import (
+	"context"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTransform registers a transform forwarding to a capturing actor.
+func newTransform(t *testing.T, fn TransformFunc) (*stage.System, <-chan stage.Message) {
+	t.Helper()
+
+	system := stage.NewSystem()
+	received := make(chan stage.Message, 4)
+	capture := stage.NewBaseActor("capture", 4, func(ctx context.Context, msg stage.Message) {
+		received <- msg
+	})
+
+	transform, err := NewTransform("transform", 4, "capture", fn, system)
+	require.NoError(t, err, "NewTransform should succeed")
+	for _, actor := range []stage.Actor{capture, transform} {
+		require.NoError(t, system.Register(actor), "Register should succeed")
+	}
+	require.Nil(t, system.StartAll(), "StartAll should succeed")
+
+	return system, received
+}
+
+func receive(t *testing.T, received <-chan stage.Message) stage.Message {
+	t.Helper()
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("The target did not receive a message")
+		return nil
+	}
+}
+
+func TestNewTransform_Errors(t *testing.T) {
+	system := stage.NewSystem()
+	identity := func(m *stage.FFTData) stage.Message { return m }
+
+	_, err := NewTransform("transform", 1, "router", identity, nil)
+	assert.Error(t, err, "A nil system should be rejected")
+	_, err = NewTransform("transform", 1, "", identity, system)
+	assert.Error(t, err, "An empty target should be rejected")
+	_, err = NewTransform("transform", 1, "router", nil, system)
+	assert.Error(t, err, "A nil transform should be rejected")
+}
+
+func TestTransform_InPlace(t *testing.T) {
+	system, received := newTransform(t, func(m *stage.FFTData) stage.Message {
+		m.RMS *= 2
+		return m
+	})
+	defer system.Close()
+
+	in := &stage.FFTData{FrameCount: 3, RMS: 0.25}
+	require.NoError(t, system.Send("transform", in))
+
+	out := receive(t, received)
+	assert.Same(t, in, out, "The frame should be forwarded without a copy")
+	assert.Equal(t, 0.5, in.RMS, "The transform should be applied")
+}
+
+func TestTransform_Replace(t *testing.T) {
+	replacement := &stage.FFTData{FrameCount: 9}
+	system, received := newTransform(t, func(m *stage.FFTData) stage.Message {
+		if m.FrameCount%2 == 1 {
+			return nil
+		}
+		return replacement
+	})
+	defer system.Close()
+
+	require.NoError(t, system.Send("transform", &stage.FFTData{FrameCount: 1}))
+	require.NoError(t, system.Send("transform", &stage.FFTData{FrameCount: 2}))
+
+	assert.Same(t, replacement, receive(t, received), "The returned message should be forwarded, the dropped frame not")
+}
+
+func TestTransform_PassThrough(t *testing.T) {
+	called := false
+	system, received := newTransform(t, func(m *stage.FFTData) stage.Message {
+		called = true
+		return m
+	})
+	defer system.Close()
+
+	ctrl := &stage.ControlMessage{Command: stage.CommandEndpointEnable}
+	require.NoError(t, system.Send("transform", ctrl))
+
+	assert.Same(t, ctrl, receive(t, received), "Other messages should be forwarded unchanged")
+	assert.False(t, called, "The transform should only see FFTData")
+}
@@ -3,6 +3,7 @@ package stage
 
 import (
 	"context"
+	"fmt"
 	"log"
 )
 
@@ -54,12 +55,14 @@ func (a *BaseActor) Send(msg Message) error {
 }
 
 func (a *BaseActor) SendNonBlocking(msg Message) error {
+	// The select never blocks, so the read lock is held across it and Stop can
+	// not close the mailbox between the check and the send.
 	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	if a.stopping || !a.started {
-		a.mu.RUnlock()
 		return ErrActorClosed
 	}
-	a.mu.RUnlock()
 
 	select {
 	case a.mailbox <- msg:
@@ -69,6 +72,28 @@ func (a *BaseActor) SendNonBlocking(msg Message) error {
 	}
 }
 
+// SendContext blocks until the mailbox accepts msg or ctx is done, in which case
+// the context error is returned wrapped. It is meant for control messages that
+// must not be dropped, never for the audio hot path. A Stop issued meanwhile
+// waits for the pending SendContext to finish.
+func (a *BaseActor) SendContext(ctx context.Context, msg Message) error {
+	// The read lock is held while blocked so Stop cannot close the mailbox
+	// underneath the send.
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.stopping || !a.started {
+		return ErrActorClosed
+	}
+
+	select {
+	case a.mailbox <- msg:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("send to actor %s: %w", a.id, ctx.Err())
+	}
+}
+
 func (a *BaseActor) Start(ctx context.Context) error {
 	a.mu.Lock()
 
@@ -23,6 +23,12 @@ type Actor interface {
 	Stop() error                     // Stop gracefully shuts down the actor.
 }
 
+// ContextSender is implemented by actors that support blocking delivery bounded
+// by a context, such as BaseActor and the components embedding it.
+type ContextSender interface {
+	SendContext(ctx context.Context, msg Message) error
+}
+
 type TypedActor[T Message] interface {
 	Actor
 	SendTyped(msg T) error
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+package stage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaseActor_SendContext(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan Message, 4)
+	actor := NewBaseActor("slow", 1, func(ctx context.Context, msg Message) {
+		<-release
+		received <- msg
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.ErrorIs(t, actor.SendContext(ctx, &StatusMessage{}), ErrActorClosed, "Unstarted actor should reject messages")
+	require.NoError(t, actor.Start(ctx))
+
+	// One message is held by the processor, one fills the mailbox.
+	require.NoError(t, actor.SendContext(ctx, &StatusMessage{}))
+	require.Eventually(t, func() bool { return len(actor.mailbox) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, actor.SendContext(ctx, &StatusMessage{}))
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer timeoutCancel()
+	err := actor.SendContext(timeoutCtx, &StatusMessage{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "Full mailbox should time out")
+
+	// Once the processor drains the mailbox a pending send is accepted.
+	done := make(chan error, 1)
+	go func() { done <- actor.SendContext(ctx, &StatusMessage{}) }()
+	close(release)
+	assert.NoError(t, <-done, "Send should complete once the mailbox has room")
+
+	for i := 0; i < 3; i++ {
+		<-received
+	}
+	require.NoError(t, actor.Stop())
+}
@@ -14,6 +14,24 @@ const (
 	TypeFFTData     = "data.audio.fft.processed" // From ingress -> router -> endpoints
 )
 
+// Control commands, carried in ControlMessage.Command.
+const (
+	// CommandEndpointEnable enables or disables forwarding to an endpoint,
+	// Params: {"id": string, "enabled": bool}.
+	CommandEndpointEnable = "endpoint_enable"
+
+	// CommandBPMConfigure changes BPM detector parameters while running, Params
+	// are any of {"onset_threshold": number, "onset_memory": seconds or a
+	// duration string, "onset_pre_window": int, "onset_post_window": int,
+	// "onset_interval_beats": number, "tempo_hint": [low, high]}.
+	CommandBPMConfigure = "bpm_configure"
+
+	// CommandSetBPM fixes the reported tempo, e.g. from a DJ controller, while
+	// the beat phase still follows the onsets, Params: {"bpm": number}. A bpm
+	// of 0 resumes tempo estimation.
+	CommandSetBPM = "set_bpm"
+)
+
 type ControlMessage struct {
 	Params  map[string]any
 	Command string
@@ -42,12 +60,79 @@ func (m *StatusMessage) Type() string {
 	return TypeStatus
 }
 
+// Spectrum is the output of one additional FFT resolution (dsp.extra_fft_sizes),
+// cropped to the output range like the primary magnitudes.
+type Spectrum struct {
+	Magnitudes          []float64
+	FrequencyStart      float64 // Frequency (Hz) of the first emitted bin.
+	FrequencyResolution float64 // Spacing (Hz) between emitted bins.
+	FFTSize             int
+}
+
+// BandOnset is the onset state of one dsp.onset_bands band, see
+// analysis.BandOnsetDetector.
+type BandOnset struct {
+	Name             string
+	FramesSinceOnset int64   // Buffers since the band's last onset, -1 if none yet.
+	Strength         float64 // Last onset relative to the band's recent onsets, 0 if none yet.
+	Onset            bool    // An onset was detected in this frame.
+}
+
+// AppendSpectrum appends a copy of src to spectra. A slot beyond len(spectra)
+// that is still within its capacity has its Magnitudes reused, so pooled
+// messages stop allocating once their slices have grown.
+func AppendSpectrum(spectra []Spectrum, src Spectrum) []Spectrum {
+	n := len(spectra)
+	if n < cap(spectra) {
+		spectra = spectra[:n+1]
+	} else {
+		spectra = append(spectra, Spectrum{})
+	}
+
+	dst := &spectra[n]
+	dst.Magnitudes = append(dst.Magnitudes[:0], src.Magnitudes...)
+	dst.FrequencyStart = src.FrequencyStart
+	dst.FrequencyResolution = src.FrequencyResolution
+	dst.FFTSize = src.FFTSize
+	return spectra
+}
+
 type RawAudioMessage struct {
-	Magnitudes    []float64
-	SpectralFlux  []float64
-	FrameCount    uint64
-	BPM           float64
-	BPMConfidence float64
+	AnalysisStart       time.Time // Wall-clock time the analysis of the buffer started, see CapturedAt for its capture.
+	SourceID            string    // Input source of the frame, empty unless input.extra_sources are analyzed separately.
+	Magnitudes          []float64
+	SpectralFlux        []float64
+	Spectra             []Spectrum  // Additional FFT resolutions, see dsp.extra_fft_sizes.
+	Phases              []float64   // Phase of each emitted bin in radians, empty unless dsp.emit_phase.
+	BandOnsets          []BandOnset // Per-band onsets, empty unless dsp.band_onsets.
+	FrameCount          uint64
+	BPM                 float64 // RawBPM, or 0 below dsp.bpm_min_confidence.
+	RawBPM              float64 // Latest estimate regardless of its confidence.
+	DisplayBPM          float64 // BPM averaged over dsp.bpm_display_window.
+	BPMConfidence       float64
+	SmoothedConfidence  float64       // BPMConfidence smoothed over dsp.confidence_smoothing.
+	VariationConfidence float64       // Interval-variation confidence, see dsp.bpm_confidence.
+	PeakConfidence      float64       // Histogram peak-to-mean confidence, see dsp.bpm_confidence.
+	FrequencyStart      float64       // Frequency (Hz) of the first emitted bin.
+	FrequencyResolution float64       // Spacing (Hz) between emitted bins.
+	AudioTime           float64       // Audio-clock position (seconds) of the first sample of the buffer.
+	CapturedAt          time.Time     // Estimated wall-clock time the first sample of the buffer was captured, zero unless dsp.report_latency.
+	OnsetDelay          time.Duration // How much later onsets are detected due to peak picking, only set with CapturedAt.
+	RMS                 float64       // Input level of the buffer before windowing.
+	Peak                float64       // Largest absolute input sample after input.gain_db, normalized to [0, 1] at unity gain.
+	ClipCount           int           // Input samples at or near full scale.
+	Channels            int           // Interleaved channels of the analyzed buffer, after device clamping.
+	FramesSinceOnset    int64         // Buffers since the last detected onset, -1 if none yet.
+	OnsetStrength       float64       // Last onset relative to recent onsets, 0 if none yet.
+	BeatPhase           float64       // Position within the beat in [0, 1), -1 without a tempo or onset.
+	LFO                 float64       // Beat-synced oscillator in [0, 1], -1 without a tempo. Only set when HasLFO is true.
+	SpectralFlatness    float64       // Only set when HasFeatures is true.
+	SpectralCrest       float64       // Only set when HasFeatures is true.
+	Clipping            bool          // ClipCount is non-zero.
+	TempoLocked         bool          // BPM confidence has been stable for long enough.
+	BPMValid            bool          // BPM is an estimate with at least dsp.bpm_min_confidence.
+	HasFeatures         bool          // Spectral features were computed (dsp.spectral_features).
+	HasLFO              bool          // The LFO was computed (dsp.lfo).
 }
 
 func (m *RawAudioMessage) Type() string {
@@ -55,32 +140,106 @@ func (m *RawAudioMessage) Type() string {
 }
 
 type FFTData struct {
-	StartTime     time.Time
-	Magnitudes    []float64
-	SpectralFlux  []float64
-	FrameCount    uint64
-	BPM           float64
-	BPMConfidence float64
+	StartTime           time.Time // RawAudioMessage.AnalysisStart.
+	SourceID            string
+	Magnitudes          []float64
+	SpectralFlux        []float64
+	Spectra             []Spectrum
+	Phases              []float64
+	BandOnsets          []BandOnset
+	FrameCount          uint64
+	BPM                 float64
+	RawBPM              float64
+	DisplayBPM          float64
+	BPMConfidence       float64
+	SmoothedConfidence  float64
+	VariationConfidence float64
+	PeakConfidence      float64
+	FrequencyStart      float64
+	FrequencyResolution float64
+	AudioTime           float64
+	CapturedAt          time.Time
+	OnsetDelay          time.Duration
+	RMS                 float64
+	Peak                float64
+	ClipCount           int
+	Channels            int
+	FramesSinceOnset    int64
+	OnsetStrength       float64
+	BeatPhase           float64
+	LFO                 float64
+	SpectralFlatness    float64
+	SpectralCrest       float64
+	TempoLocked         bool
+	BPMValid            bool
+	Clipping            bool
+	HasFeatures         bool
+	HasLFO              bool
 }
 
 func (m *FFTData) Type() string {
 	return TypeFFTData
 }
 
+// rawMessageCapacity is the slice capacity pre-allocated for pooled messages,
+// it defaults to the bin count of a 256-point FFT.
+var rawMessageCapacity = 129
+
 var RawMessagePool = sync.Pool{
 	New: func() any {
 		return &RawAudioMessage{
-			Magnitudes: make([]float64, 0, 129), // Pre-allocate typical FFT size
+			Magnitudes:   make([]float64, 0, rawMessageCapacity),
+			SpectralFlux: make([]float64, 0, rawMessageCapacity),
 		}
 	},
 }
 
+// SetRawMessageCapacity sets the slice capacity pre-allocated by RawMessagePool,
+// typically the number of FFT bins (fftSize/2 + 1). It must be called before the
+// first call to GetRawMessage.
+func SetRawMessageCapacity(bins int) {
+	rawMessageCapacity = bins
+}
+
 func GetRawMessage() *RawAudioMessage {
 	return RawMessagePool.Get().(*RawAudioMessage)
 }
 
 func PutRawMessage(msg *RawAudioMessage) {
-	msg.Magnitudes = msg.Magnitudes[:0] // Reset slice but keep capacity
+	msg.Magnitudes = msg.Magnitudes[:0] // Reset slices but keep capacity
+	msg.SpectralFlux = msg.SpectralFlux[:0]
+	msg.Spectra = msg.Spectra[:0]
+	msg.Phases = msg.Phases[:0]
+	msg.BandOnsets = msg.BandOnsets[:0]
 	msg.FrameCount = 0
+	msg.BPM = 0
+	msg.RawBPM = 0
+	msg.BPMValid = false
+	msg.DisplayBPM = 0
+	msg.BPMConfidence = 0
+	msg.SmoothedConfidence = 0
+	msg.VariationConfidence = 0
+	msg.PeakConfidence = 0
+	msg.FrequencyStart = 0
+	msg.FrequencyResolution = 0
+	msg.AnalysisStart = time.Time{}
+	msg.SourceID = ""
+	msg.AudioTime = 0
+	msg.CapturedAt = time.Time{}
+	msg.OnsetDelay = 0
+	msg.RMS = 0
+	msg.Peak = 0
+	msg.ClipCount = 0
+	msg.Channels = 0
+	msg.Clipping = false
+	msg.FramesSinceOnset = 0
+	msg.OnsetStrength = 0
+	msg.BeatPhase = 0
+	msg.LFO = 0
+	msg.TempoLocked = false
+	msg.SpectralFlatness = 0
+	msg.SpectralCrest = 0
+	msg.HasFeatures = false
+	msg.HasLFO = false
 	RawMessagePool.Put(msg)
 }
@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+package stage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutRawMessage_ResetsAllFields(t *testing.T) {
+	msg := &RawAudioMessage{
+		Magnitudes:          []float64{1, 2, 3},
+		SpectralFlux:        []float64{4, 5, 6},
+		Spectra:             []Spectrum{{FFTSize: 4096, Magnitudes: []float64{7}}},
+		Phases:              []float64{0.1, 0.2, 0.3},
+		BandOnsets:          []BandOnset{{Name: "low", FramesSinceOnset: 0, Onset: true}},
+		FrameCount:          42,
+		BPM:                 128,
+		RawBPM:              128,
+		BPMValid:            true,
+		DisplayBPM:          127.5,
+		BPMConfidence:       0.9,
+		SmoothedConfidence:  0.7,
+		VariationConfidence: 0.6,
+		PeakConfidence:      0.5,
+		FrequencyStart:      21.5,
+		FrequencyResolution: 172.3,
+		AnalysisStart:       time.Now(),
+		SourceID:            "zone2",
+		AudioTime:           1.5,
+		CapturedAt:          time.Now(),
+		OnsetDelay:          12 * time.Millisecond,
+		RMS:                 0.25,
+		Peak:                1,
+		ClipCount:           3,
+		Channels:            2,
+		Clipping:            true,
+		FramesSinceOnset:    12,
+		OnsetStrength:       2.5,
+		BeatPhase:           0.25,
+		LFO:                 0.5,
+		TempoLocked:         true,
+		SpectralFlatness:    0.5,
+		SpectralCrest:       3,
+		HasFeatures:         true,
+		HasLFO:              true,
+	}
+
+	PutRawMessage(msg)
+
+	assert.Empty(t, msg.Magnitudes, "Magnitudes should be reset")
+	assert.Equal(t, 3, cap(msg.Magnitudes), "Magnitudes capacity should be retained")
+	assert.Empty(t, msg.SpectralFlux, "SpectralFlux should be reset")
+	assert.Equal(t, 3, cap(msg.SpectralFlux), "SpectralFlux capacity should be retained")
+	assert.Empty(t, msg.Spectra, "Spectra should be reset")
+	assert.Equal(t, 1, cap(msg.Spectra), "Spectra capacity should be retained")
+	assert.Empty(t, msg.Phases, "Phases should be reset")
+	assert.Equal(t, 3, cap(msg.Phases), "Phases capacity should be retained")
+	assert.Empty(t, msg.BandOnsets, "BandOnsets should be reset")
+	assert.Zero(t, msg.FrameCount, "FrameCount should be reset")
+	assert.Zero(t, msg.BPM, "BPM should be reset")
+	assert.Zero(t, msg.RawBPM, "RawBPM should be reset")
+	assert.False(t, msg.BPMValid, "BPMValid should be reset")
+	assert.Zero(t, msg.BPMConfidence, "BPMConfidence should be reset")
+	assert.Zero(t, msg.SmoothedConfidence, "SmoothedConfidence should be reset")
+	assert.Zero(t, msg.DisplayBPM, "DisplayBPM should be reset")
+	assert.Zero(t, msg.VariationConfidence, "VariationConfidence should be reset")
+	assert.Zero(t, msg.PeakConfidence, "PeakConfidence should be reset")
+	assert.Zero(t, msg.FrequencyStart, "FrequencyStart should be reset")
+	assert.Zero(t, msg.FrequencyResolution, "FrequencyResolution should be reset")
+	assert.Zero(t, msg.AnalysisStart, "AnalysisStart should be reset")
+	assert.Empty(t, msg.SourceID, "SourceID should be reset")
+	assert.Zero(t, msg.AudioTime, "AudioTime should be reset")
+	assert.Zero(t, msg.CapturedAt, "CapturedAt should be reset")
+	assert.Zero(t, msg.OnsetDelay, "OnsetDelay should be reset")
+	assert.Zero(t, msg.RMS, "RMS should be reset")
+	assert.Zero(t, msg.Peak, "Peak should be reset")
+	assert.Zero(t, msg.ClipCount, "ClipCount should be reset")
+	assert.Zero(t, msg.Channels, "Channels should be reset")
+	assert.False(t, msg.Clipping, "Clipping should be reset")
+	assert.Zero(t, msg.FramesSinceOnset, "FramesSinceOnset should be reset")
+	assert.Zero(t, msg.OnsetStrength, "OnsetStrength should be reset")
+	assert.Zero(t, msg.BeatPhase, "BeatPhase should be reset")
+	assert.Zero(t, msg.LFO, "LFO should be reset")
+	assert.False(t, msg.TempoLocked, "TempoLocked should be reset")
+	assert.Zero(t, msg.SpectralFlatness, "SpectralFlatness should be reset")
+	assert.Zero(t, msg.SpectralCrest, "SpectralCrest should be reset")
+	assert.False(t, msg.HasFeatures, "HasFeatures should be reset")
+	assert.False(t, msg.HasLFO, "HasLFO should be reset")
+}
+
+func TestSetRawMessageCapacity(t *testing.T) {
+	defer SetRawMessageCapacity(rawMessageCapacity)
+
+	SetRawMessageCapacity(513)
+	msg := RawMessagePool.New().(*RawAudioMessage)
+
+	assert.Equal(t, 513, cap(msg.Magnitudes), "Magnitudes capacity should match the configured bin count")
+	assert.Equal(t, 513, cap(msg.SpectralFlux), "SpectralFlux capacity should match the configured bin count")
+}
+
+func TestAppendSpectrum(t *testing.T) {
+	src := Spectrum{Magnitudes: []float64{1, 2}, FrequencyStart: 10, FrequencyResolution: 5, FFTSize: 4096}
+
+	spectra := AppendSpectrum(nil, src)
+	assert.Equal(t, []Spectrum{src}, spectra, "The spectrum should be appended")
+	src.Magnitudes[0] = 9
+	assert.Equal(t, 1.0, spectra[0].Magnitudes[0], "Magnitudes should be copied")
+
+	reused := &spectra[0].Magnitudes[0]
+	spectra = AppendSpectrum(spectra[:0], src)
+	assert.Same(t, reused, &spectra[0].Magnitudes[0], "A slot within capacity should reuse its magnitudes")
+	assert.Equal(t, []float64{9, 2}, spectra[0].Magnitudes)
+}
@@ -71,6 +71,58 @@ func (s *System) SendNonBlocking(actorID string, msg Message) error {
 	return actor.Send(msg)
 }
 
+// SendContext delivers msg to the actor, waiting for mailbox space until ctx is
+// done. Actors that do not implement ContextSender fall back to Send.
+func (s *System) SendContext(ctx context.Context, actorID string, msg Message) error {
+	s.mu.RLock()
+	actor, exists := s.actors[actorID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("actor with ID %s not found", actorID)
+	}
+
+	if sender, ok := actor.(ContextSender); ok {
+		return sender.SendContext(ctx, msg)
+	}
+
+	return actor.Send(msg)
+}
+
+// Broadcast sends msg to every registered actor and returns the per-actor
+// errors, or nil if all sends succeeded. The same message value is delivered to
+// all actors, so it must not be mutated or returned to a pool by receivers.
+func (s *System) Broadcast(msg Message) map[string]error {
+	return s.BroadcastMatching(func(string, Actor) bool { return true }, msg)
+}
+
+// BroadcastMatching sends msg to every registered actor for which match returns
+// true, e.g. all actors of a given Go type, and returns the per-actor errors, or
+// nil if all sends succeeded.
+func (s *System) BroadcastMatching(match func(id string, actor Actor) bool, msg Message) map[string]error {
+	s.mu.RLock()
+	actors := make(map[string]Actor, len(s.actors))
+	maps.Copy(actors, s.actors)
+	s.mu.RUnlock()
+
+	errors := make(map[string]error)
+	for id, actor := range actors {
+		if !match(id, actor) {
+			continue
+		}
+		if err := actor.Send(msg); err != nil {
+			errors[id] = err
+			log.Printf("Stage ➜ Failed to broadcast to actor %s: %v", id, err)
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	}
+
+	return errors
+}
+
 func (s *System) StartAll() map[string]error {
 	s.mu.RLock()
 	actors := make(map[string]Actor, len(s.actors))
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+package stage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystem_Broadcast(t *testing.T) {
+	system := NewSystem()
+	received := make(chan string, 8)
+	for _, id := range []string{"ws", "udp", "router"} {
+		actorID := id
+		require.NoError(t, system.Register(NewBaseActor(actorID, 4, func(ctx context.Context, msg Message) {
+			received <- actorID
+		})))
+	}
+	// Registered but never started, so sends to it fail.
+	require.NoError(t, system.Register(NewBaseActor("idle", 4, nil)))
+
+	for _, id := range []string{"ws", "udp", "router"} {
+		actor, _ := system.Get(id)
+		require.NoError(t, actor.Start(context.Background()))
+	}
+	defer system.StopAll()
+
+	errs := system.Broadcast(&ControlMessage{Command: "reset"})
+	require.Len(t, errs, 1, "Only the idle actor should fail")
+	assert.ErrorIs(t, errs["idle"], ErrActorClosed)
+	assert.ElementsMatch(t, []string{"ws", "udp", "router"}, drain(t, received, 3))
+
+	errs = system.BroadcastMatching(func(id string, _ Actor) bool {
+		return !strings.HasPrefix(id, "router") && id != "idle"
+	}, &ControlMessage{Command: "pause"})
+	assert.Nil(t, errs, "All matching actors should accept the message")
+	assert.ElementsMatch(t, []string{"ws", "udp"}, drain(t, received, 2))
+}
+
+func TestSystem_SendContext(t *testing.T) {
+	system := NewSystem()
+	actor := NewBaseActor("a", 1, nil)
+	require.NoError(t, system.Register(actor))
+	system.StartAll()
+	defer system.StopAll()
+
+	assert.NoError(t, system.SendContext(context.Background(), "a", &StatusMessage{}))
+	assert.Error(t, system.SendContext(context.Background(), "missing", &StatusMessage{}), "Unknown actor should be an error")
+}
+
+func drain(t *testing.T, ch <-chan string, n int) []string {
+	t.Helper()
+	got := make([]string, 0, n)
+	for range n {
+		select {
+		case id := <-ch:
+			got = append(got, id)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out after %d of %d messages", len(got), n)
+		}
+	}
+	return got
+}
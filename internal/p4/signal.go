@@ -11,10 +11,12 @@ import (
 )
 
 type SignalHandler struct {
-	signals chan os.Signal
-	done    chan struct{}
-	cancel  context.CancelFunc
-	once    sync.Once // Add this to prevent double-close
+	signals    chan os.Signal
+	done       chan struct{}
+	cancel     context.CancelFunc
+	onSnapshot func()
+	mu         sync.Mutex
+	once       sync.Once // Add this to prevent double-close
 }
 
 func NewSignalHandler(cancel context.CancelFunc) *SignalHandler {
@@ -30,14 +32,40 @@ func NewSignalHandler(cancel context.CancelFunc) *SignalHandler {
 	return sh
 }
 
-func (sh *SignalHandler) handle() {
-	select {
-	case sig := <-sh.signals:
-		log.Printf("Received signal: %v, initiating shutdown...", sig)
-		sh.cancel()
-	case <-sh.done:
+// OnSnapshot calls fn whenever the process receives snapshotSignal (SIGUSR1),
+// e.g. to log the analysis state without stopping. It has no effect on
+// platforms without that signal.
+func (sh *SignalHandler) OnSnapshot(fn func()) {
+	if snapshotSignal == nil {
 		return
 	}
+
+	sh.mu.Lock()
+	sh.onSnapshot = fn
+	sh.mu.Unlock()
+	signal.Notify(sh.signals, snapshotSignal)
+}
+
+func (sh *SignalHandler) handle() {
+	for {
+		select {
+		case sig := <-sh.signals:
+			if snapshotSignal != nil && sig == snapshotSignal {
+				sh.mu.Lock()
+				fn := sh.onSnapshot
+				sh.mu.Unlock()
+				if fn != nil {
+					fn()
+				}
+				continue
+			}
+			log.Printf("Received signal: %v, initiating shutdown...", sig)
+			sh.cancel()
+			return
+		case <-sh.done:
+			return
+		}
+	}
 }
 
 func (sh *SignalHandler) Stop() {
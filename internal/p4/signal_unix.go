@@ -0,0 +1,12 @@
+// SPDX-License-Identifier: Apache-2.0
+//go:build !windows
+
+package p4
+
+import (
+	"os"
+	"syscall"
+)
+
+// snapshotSignal requests an Engine.LogSnapshot, see SignalHandler.OnSnapshot.
+var snapshotSignal os.Signal = syscall.SIGUSR1
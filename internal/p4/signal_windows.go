@@ -0,0 +1,11 @@
+// SPDX-License-Identifier: Apache-2.0
+//go:build windows
+
+package p4
+
+import (
+	"os"
+)
+
+// snapshotSignal is unavailable on Windows, which has no SIGUSR1.
+var snapshotSignal os.Signal
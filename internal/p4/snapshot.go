@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"log"
+	"phase4/internal/p4/analysis"
+	"phase4/internal/p4/runtime/endpoint"
+	"time"
+)
+
+// Snapshot returns a copy of the current analysis state. The processor and BPM
+// detector are read under the same lock analyzeFrame holds while advancing
+// them, so all fields describe the same frame. Before the first frame, or with
+// analysis disabled, only Time and Gaps are set.
+func (e *Engine) Snapshot() Snapshot {
+	e.analysisMu.Lock()
+	defer e.analysisMu.Unlock()
+
+	s := Snapshot{Time: time.Now()}
+	if e.gaps != nil {
+		gaps := e.gaps.Stats()
+		s.Gaps = &gaps
+	}
+	if e.fftProc == nil || e.analyzedFrame == 0 {
+		return s
+	}
+
+	s.FrameCount = e.analyzedFrame
+	s.Latency = time.Duration(e.lastLatency.Load())
+	s.Magnitudes = e.fftProc.GetMagnitudes() // Copies, see Float64DoubleBuffer.Get.
+	s.SpectralFlux = e.fftProc.GetSpectralFlux()
+	s.FrequencyResolution = e.fftProc.GetFrequencyResolution()
+	s.PeakFrequency, s.PeakMagnitude = e.fftProc.FindPeakFrequency()
+	s.Bands = make([]BandEnergy, len(snapshotBands))
+	for i, band := range snapshotBands {
+		band.Energy = e.fftProc.GetBandEnergy(band.Low, band.High)
+		s.Bands[i] = band
+	}
+
+	s.FramesSinceOnset = -1
+	if e.bpmDetector != nil {
+		s.BPM, s.BPMConfidence = e.bpmDetector.GetBPM()
+		s.SmoothedConfidence = e.bpmDetector.GetSmoothedConfidence()
+		s.FramesSinceOnset = e.bpmDetector.FramesSinceOnset()
+		s.OnsetStrength = e.bpmDetector.OnsetStrength()
+		s.OnsetCount = e.bpmDetector.GetOnsetCount()
+		s.TempoLocked = e.bpmDetector.IsLocked()
+		if stats, ok := e.bpmDetector.Stats(); ok {
+			s.BPMStats = &stats
+		}
+	}
+
+	return s
+}
+
+// LogSnapshot logs a summary of Snapshot, e.g. in response to SIGUSR1.
+func (e *Engine) LogSnapshot() {
+	s := e.Snapshot()
+	if s.Gaps != nil {
+		logGapStats("Snapshot", *s.Gaps)
+	}
+	if s.FrameCount == 0 {
+		log.Print("Engine ➜ Snapshot ➜ No frame analyzed yet")
+		return
+	}
+
+	var totalFlux float64
+	for _, v := range s.SpectralFlux {
+		totalFlux += v
+	}
+
+	log.Printf("Engine ➜ Snapshot ➜ frame=%d bins=%d resolution=%.2f Hz/bin peak=%.2f Hz (%.4f) flux=%.4f",
+		s.FrameCount, len(s.Magnitudes), s.FrequencyResolution, s.PeakFrequency, s.PeakMagnitude, totalFlux)
+	for _, band := range s.Bands {
+		log.Printf("Engine ➜ Snapshot ➜ band %s (%.0f-%.0f Hz) energy=%.4f", band.Name, band.Low, band.High, band.Energy)
+	}
+	log.Printf("Engine ➜ Snapshot ➜ bpm=%.1f confidence=%.2f smoothed=%.2f locked=%t onsets=%d framesSinceOnset=%d strength=%.2f",
+		s.BPM, s.BPMConfidence, s.SmoothedConfidence, s.TempoLocked, s.OnsetCount, s.FramesSinceOnset, s.OnsetStrength)
+	if s.Latency > 0 {
+		log.Printf("Engine ➜ Snapshot ➜ latency=%s", s.Latency)
+	}
+	if s.BPMStats != nil {
+		logBPMStats("Snapshot", *s.BPMStats)
+	}
+}
+
+// logBPMStats logs the session statistics of the BPM detector, if enabled.
+func (e *Engine) logBPMStats() {
+	if e.bpmDetector == nil {
+		return
+	}
+	if stats, ok := e.bpmDetector.Stats(); ok {
+		logBPMStats("Session", stats)
+	}
+}
+
+func logBPMStats(label string, stats analysis.BPMStats) {
+	log.Printf("Engine ➜ %s ➜ bpm mode=%.0f mean=%.1f stddev=%.2f locked=%.1f%% corrections=%d frames=%d",
+		label, stats.ModeBPM, stats.MeanBPM, stats.StdDev, 100*stats.LockedFraction, stats.Corrections, stats.Frames)
+}
+
+// logGapStats logs the frames lost before the endpoints, if gap detection is
+// enabled.
+func (e *Engine) logGapStats() {
+	if e.gaps != nil {
+		logGapStats("Session", e.gaps.Stats())
+	}
+}
+
+func logGapStats(label string, stats endpoint.GapStats) {
+	var lost float64
+	if total := stats.Frames + stats.Missed; total > 0 {
+		lost = 100 * float64(stats.Missed) / float64(total)
+	}
+	log.Printf("Engine ➜ %s ➜ gaps frames=%d missed=%d (%.1f%% lost) gaps=%d largest=%d",
+		label, stats.Frames, stats.Missed, lost, stats.Gaps, stats.Largest)
+}
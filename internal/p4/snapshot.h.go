@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"phase4/internal/p4/analysis"
+	"phase4/internal/p4/runtime/endpoint"
+	"time"
+)
+
+// Snapshot is the analysis state after one frame, see Engine.Snapshot. Spectra
+// cover all FFT bins, not only the range emitted to clients.
+type Snapshot struct {
+	Time                time.Time
+	Magnitudes          []float64
+	SpectralFlux        []float64
+	Bands               []BandEnergy
+	FrequencyResolution float64 // Hz per bin, bin 0 is at 0 Hz.
+	PeakFrequency       float64
+	PeakMagnitude       float64
+	BPM                 float64
+	BPMConfidence       float64
+	SmoothedConfidence  float64
+	FrameCount          uint64
+	FramesSinceOnset    int64
+	OnsetStrength       float64
+	OnsetCount          int
+	TempoLocked         bool
+	BPMStats            *analysis.BPMStats // Nil unless dsp.bpm_stats.
+	Gaps                *endpoint.GapStats // Nil unless transport.gap_detection.
+	Latency             time.Duration      // Capture to hand-off to the pipeline of the last emitted frame, 0 unless dsp.report_latency.
+}
+
+// BandEnergy is the summed squared magnitude of the bins in [Low, High] Hz, a
+// High of 0 extends the band to Nyquist.
+type BandEnergy struct {
+	Name   string
+	Low    float64
+	High   float64
+	Energy float64
+}
+
+// snapshotBands are the bands reported in a Snapshot.
+var snapshotBands = []BandEnergy{
+	{Name: "bass", Low: 20, High: 250},
+	{Name: "mid", Low: 250, High: 4000},
+	{Name: "high", Low: 4000, High: 0},
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"math"
+	"phase4/internal/app/config"
+	"phase4/internal/p4/runtime/endpoint"
+	"phase4/internal/p4/runtime/stage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Snapshot(t *testing.T) {
+	e := &Engine{
+		ctx: context.Background(),
+		config: &config.Config{
+			Input: config.InputConfig{Channels: 1, SampleRate: 44100, BufferSize: 1024},
+			DSP:   config.DSPConfig{Enabled: true, FFTWindow: "Hann"},
+		},
+		system: stage.NewSystem(),
+	}
+	defer e.system.Close()
+	require.NoError(t, e.initializeAnalysis(), "Analysis should initialize")
+
+	s := e.Snapshot()
+	assert.Zero(t, s.FrameCount, "No frame should be reported before the first buffer")
+	assert.Empty(t, s.Magnitudes, "No spectrum should be reported before the first buffer")
+
+	// A 1kHz tone at half scale.
+	buffer := make([]int32, 1024)
+	for i := range buffer {
+		buffer[i] = int32(0.5 * math.MaxInt32 * math.Sin(2*math.Pi*1000*float64(i)/44100))
+	}
+	for frame := uint64(1); frame <= 3; frame++ {
+		e.analyzeFrame(buffer, frame)
+	}
+
+	s = e.Snapshot()
+	assert.Equal(t, uint64(3), s.FrameCount, "The snapshot should describe the last analyzed frame")
+	assert.Len(t, s.Magnitudes, 513, "The snapshot should cover all FFT bins")
+	assert.Len(t, s.SpectralFlux, 513, "The snapshot should cover all flux bins")
+	assert.InDelta(t, 1000, s.PeakFrequency, s.FrequencyResolution, "The peak should be at the tone")
+	require.Len(t, s.Bands, len(snapshotBands))
+	assert.Equal(t, "mid", s.Bands[1].Name)
+	assert.Greater(t, s.Bands[1].Energy, s.Bands[0].Energy+s.Bands[2].Energy, "The tone's energy should be in the mid band")
+
+	assert.Zero(t, s.Latency, "No latency should be reported unless enabled")
+
+	s.Magnitudes[0] = -1
+	assert.NotEqual(t, -1.0, e.Snapshot().Magnitudes[0], "The snapshot should not alias the processor's buffers")
+
+	e.config.DSP.ReportLatency = true
+	e.analyzeFrame(buffer, 4)
+	assert.GreaterOrEqual(t, e.Snapshot().Latency, e.analysisBudget, "The latency should include the buffer period")
+}
+
+func TestEngine_SnapshotGaps(t *testing.T) {
+	e := &Engine{system: stage.NewSystem()}
+	defer e.system.Close()
+	assert.Nil(t, e.Snapshot().Gaps, "No gaps should be reported unless enabled")
+
+	e.gaps = endpoint.NewGapComponent("gaps", 8, time.Hour, false)
+	require.NoError(t, e.system.Register(e.gaps), "Register should succeed")
+	require.Nil(t, e.system.StartAll(), "StartAll should succeed")
+
+	for _, frame := range []uint64{1, 2, 5, 6, 10} {
+		require.NoError(t, e.system.Send("gaps", &stage.FFTData{FrameCount: frame}))
+	}
+	require.Eventually(t, func() bool {
+		s := e.Snapshot()
+		return s.Gaps != nil && s.Gaps.Frames == 5
+	}, time.Second, 5*time.Millisecond, "The snapshot should include the frames the gap detector received")
+
+	s := e.Snapshot()
+	assert.Equal(t, endpoint.GapStats{Frames: 5, Missed: 5, Gaps: 2, Largest: 3}, *s.Gaps, "Unexpected gap totals")
+	assert.Zero(t, s.FrameCount, "Gaps should be reported without analysis")
+}
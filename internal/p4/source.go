@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"phase4/internal/app/config"
+	"phase4/internal/app/errors"
+	"phase4/pkg/buffer"
+	"strings"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+const (
+	// mixRingSlots is the number of buffers a mixed source can deliver ahead of
+	// the primary input, and mixMaxBacklog how many it may keep queued before
+	// the oldest are dropped. Device clocks drift, the backlog bounds the delay
+	// a faster source builds up.
+	mixRingSlots  = 8
+	mixMaxBacklog = 2
+)
+
+// ParseSourceMode converts a string name (case-insensitive) to a SourceMode
+// enum, returns a known default (SourceSeparate) and an error if the name is
+// unknown.
+func ParseSourceMode(name string) (SourceMode, error) {
+	switch strings.ToLower(name) {
+	case "separate":
+		return SourceSeparate, nil
+	case "mix":
+		return SourceMix, nil
+	default:
+		return SourceSeparate, fmt.Errorf("unknown source mode name: '%s'", name)
+	}
+}
+
+// initializeSources selects the devices of input.extra_sources. Separate
+// sources get their own analysis, mixed ones a ring their callback hands
+// buffers to. It must be called after initializeAnalysis.
+func (e *Engine) initializeSources() error {
+	if len(e.config.Input.ExtraSources) == 0 {
+		return nil
+	}
+
+	mode, _ := ParseSourceMode(e.config.Input.SourceMode)
+	for _, sourceConfig := range e.config.Input.ExtraSources {
+		device, err := sourceDevice(e.audio.devices, sourceConfig)
+		if err != nil {
+			return &errors.FatalError{
+				Message: fmt.Sprintf("failed to select input source '%s'", sourceConfig.ID),
+				Err:     err,
+			}
+		}
+		src := &inputSource{
+			id:       sourceConfig.ID,
+			device:   device,
+			channels: min(sourceConfig.Channels, device.MaxInputChannels),
+		}
+		if src.channels < sourceConfig.Channels {
+			log.Printf("Engine ➜ Warning ➜ Source '%s' requested %d channels but device only supports %d",
+				src.id, sourceConfig.Channels, src.channels)
+		}
+
+		switch mode {
+		case SourceMix:
+			src.ring = buffer.NewInt32FrameRing(mixRingSlots, e.config.Input.BufferSize*src.channels)
+			src.frame = make([]int32, src.ring.FrameSize())
+		default:
+			if src.analysis, err = e.sourceAnalysis(src); err != nil {
+				return err
+			}
+		}
+		log.Printf("Engine ➜ Source '%s' ➜ %s (%d channels, %s)", src.id, device.Name, src.channels, mode)
+		e.sources = append(e.sources, src)
+	}
+
+	if mode == SourceMix {
+		e.mixBuffer = make([]int32, e.config.Input.BufferSize*e.config.Input.Channels)
+	} else {
+		e.sourceID = e.config.Input.SourceID
+	}
+	return nil
+}
+
+// sourceDevice returns the input device of a source, sources are selected by
+// index only.
+func sourceDevice(devices []*portaudio.DeviceInfo, source config.InputSourceConfig) (*portaudio.DeviceInfo, error) {
+	if source.Device >= len(devices) {
+		return nil, fmt.Errorf("device %d out of range, %d devices found", source.Device, len(devices))
+	}
+	device := devices[source.Device]
+	if device.MaxInputChannels <= 0 {
+		return nil, fmt.Errorf("device %d (%s) has no inputs", source.Device, device.Name)
+	}
+	return device, nil
+}
+
+// sourceAnalysis returns an engine analyzing src with the DSP configuration of
+// e. It sends its frames, labeled with the source ID, through the actor system
+// of e and is never started or closed itself, e owns its stream and closables.
+func (e *Engine) sourceAnalysis(src *inputSource) (*Engine, error) {
+	sourceConfig := *e.config
+	sourceConfig.Input.Channels = src.channels
+	sourceConfig.Input.ExtraSources = nil
+
+	sa := &Engine{
+		config:   &sourceConfig,
+		command:  &cmd{},
+		ctx:      e.ctx,
+		cancel:   e.cancel,
+		system:   e.system,
+		done:     e.done,
+		audio:    &pa{client: e.audio.client, inputDevice: src.device},
+		sourceID: src.id,
+	}
+	if err := sa.initializeAnalysis(); err != nil {
+		return nil, err
+	}
+	e.closables = append(e.closables, sa.closables...)
+	return sa, nil
+}
+
+// startSources opens and starts the stream of every additional source. Separate
+// sources analyze their buffers like the primary input, with async analysis on
+// their own goroutine until ctx is cancelled.
+func (e *Engine) startSources(ctx context.Context) error {
+	for _, src := range e.sources {
+		params := e.streamParameters()
+		params.Input.Device = src.device
+		params.Input.Channels = src.channels
+
+		callback := func(inputBuffer []int32) {
+			src.ring.Push(inputBuffer, 0)
+		}
+		if src.analysis != nil {
+			callback = src.analysis.processInputStream
+		}
+
+		stream, err := e.audio.client.OpenStream(params, e.sampleFormat(), callback)
+		if err != nil {
+			return &errors.FatalError{
+				Message: fmt.Sprintf("failed to open the stream of input source '%s'", src.id),
+				Err:     err,
+			}
+		}
+		src.stream = stream
+		if err := stream.Start(); err != nil {
+			return &errors.FatalError{
+				Message: fmt.Sprintf("failed to start the stream of input source '%s'", src.id),
+				Err:     err,
+			}
+		}
+
+		if src.analysis != nil && src.analysis.analysisRing != nil {
+			src.analysis.analysisWg.Add(1)
+			go src.analysis.runAnalysis(ctx)
+		}
+		log.Printf("Engine ➜ Source '%s' ➜ Started", src.id)
+	}
+	return nil
+}
+
+// stopSources stops and closes the streams of the additional sources.
+func (e *Engine) stopSources() error {
+	var errs []error
+	for _, src := range e.sources {
+		if src.stream == nil {
+			continue
+		}
+		if err := src.stream.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("source %s stop: %w", src.id, err))
+		}
+		if err := src.stream.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("source %s close: %w", src.id, err))
+		}
+		src.stream = nil
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("source shutdown errors: %v", errs)
+	}
+	return nil
+}
+
+// mixSources returns inputBuffer with the oldest waiting buffer of every mixed
+// source added sample by sample, clipped to full scale. A source with fewer
+// channels repeats its last channel, one without a buffer adds silence, and one
+// that has run more than mixMaxBacklog buffers ahead drops its oldest.
+func (e *Engine) mixSources(inputBuffer []int32) []int32 {
+	if len(inputBuffer) > len(e.mixBuffer) {
+		return inputBuffer // Mismatched buffers are reported by checkBufferLength.
+	}
+
+	mixed := e.mixBuffer[:len(inputBuffer)]
+	copy(mixed, inputBuffer)
+	channels := e.config.Input.Channels
+	for _, src := range e.sources {
+		for src.ring.Len() > mixMaxBacklog {
+			src.ring.Pop(src.frame)
+		}
+		n, _, ok := src.ring.Pop(src.frame)
+		if !ok {
+			continue
+		}
+
+		frames := min(len(mixed)/channels, n/src.channels)
+		for f := 0; f < frames; f++ {
+			for c := 0; c < channels; c++ {
+				i := f*channels + c
+				sum := int64(mixed[i]) + int64(src.frame[f*src.channels+min(c, src.channels-1)])
+				mixed[i] = int32(min(max(sum, math.MinInt32), math.MaxInt32))
+			}
+		}
+	}
+	return mixed
+}
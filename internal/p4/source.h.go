@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"fmt"
+	"phase4/pkg/buffer"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// SourceMode is how additional input sources (input.extra_sources) are
+// analyzed alongside the primary input device.
+type SourceMode int
+
+const (
+	SourceSeparate SourceMode = iota // Every source has its own analysis and frames.
+	SourceMix                        // Sources are summed into the primary input.
+)
+
+// String returns the string representation of the SourceMode.
+func (m SourceMode) String() string {
+	switch m {
+	case SourceSeparate:
+		return "separate"
+	case SourceMix:
+		return "mix"
+	default:
+		return fmt.Sprintf("UnknownSourceMode(%d)", int(m))
+	}
+}
+
+// inputSource is an additional capture device, see input.extra_sources.
+type inputSource struct {
+	id       string
+	device   *portaudio.DeviceInfo
+	stream   paStream
+	analysis *Engine                // Own analysis sharing the actor system, nil when mixed.
+	ring     *buffer.Int32FrameRing // Buffers waiting to be mixed, nil when separate.
+	frame    []int32                // Buffer popped from ring for the mix.
+	channels int                    // Captured channels, clamped to the device.
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"context"
+	"math"
+	"phase4/internal/app/config"
+	"phase4/internal/app/errors"
+	"phase4/pkg/buffer"
+	"testing"
+
+	"github.com/gordonklaus/portaudio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSourceMode(t *testing.T) {
+	for _, mode := range []SourceMode{SourceSeparate, SourceMix} {
+		parsed, err := ParseSourceMode(mode.String())
+		assert.NoError(t, err, "Known mode %s should parse", mode)
+		assert.Equal(t, mode, parsed)
+	}
+
+	parsed, err := ParseSourceMode("stereo")
+	assert.Error(t, err, "Unknown mode should return an error")
+	assert.Equal(t, SourceSeparate, parsed, "Unknown mode should fall back to separate")
+}
+
+func newSourcesEngine(t *testing.T, mode string, sources ...config.InputSourceConfig) (*Engine, []*portaudio.DeviceInfo) {
+	t.Helper()
+
+	api := &portaudio.HostApiInfo{Name: "ALSA"}
+	devices := []*portaudio.DeviceInfo{
+		{Name: "Stage", Index: 0, MaxInputChannels: 2, HostApi: api},
+		{Name: "Bar", Index: 1, MaxInputChannels: 1, HostApi: api},
+		{Name: "Speakers", Index: 2, MaxOutputChannels: 2, HostApi: api},
+	}
+	e := NewEngine(&config.Config{
+		Input: config.InputConfig{
+			Device: 0, Channels: 2, SampleRate: 44100, BufferSize: 256, Source: "device",
+			SourceMode: mode, SourceID: "stage", ExtraSources: sources,
+		},
+		DSP: config.DSPConfig{FFTWindow: "Hann"},
+	})
+	e.audio.client = &mockPaClient{DevicesResult: devices}
+	t.Cleanup(func() { _ = e.Close() })
+	return e, devices
+}
+
+func TestInitialize_SeparateSources(t *testing.T) {
+	e, devices := newSourcesEngine(t, "separate", config.InputSourceConfig{ID: "bar", Device: 1, Channels: 2})
+
+	require.NoError(t, e.Initialize())
+
+	require.Len(t, e.sources, 1)
+	src := e.sources[0]
+	assert.Same(t, devices[1], src.device, "The source should use its own device")
+	assert.Equal(t, 1, src.channels, "The channel count should be clamped to the device")
+	assert.Equal(t, "stage", e.sourceID, "The primary frames should be labeled")
+	assert.Nil(t, e.mixBuffer, "Separate sources should not be mixed")
+
+	require.NotNil(t, src.analysis, "A separate source should have its own analysis")
+	assert.Equal(t, "bar", src.analysis.sourceID, "The source frames should be labeled")
+	assert.Same(t, e.system, src.analysis.system, "The source should share the actor system")
+	assert.NotSame(t, e.fftProc, src.analysis.fftProc, "The source should have its own FFT")
+	assert.NotSame(t, e.bpmDetector, src.analysis.bpmDetector, "The source should have its own BPM detector")
+	assert.Equal(t, 1, src.analysis.config.Input.Channels, "The source analysis should follow its channel count")
+	assert.Equal(t, 2, e.config.Input.Channels, "The primary configuration should not change")
+}
+
+func TestInitialize_SourceErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		device int
+	}{
+		{"OutOfRange", 7},
+		{"OutputOnly", 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, _ := newSourcesEngine(t, "separate", config.InputSourceConfig{ID: "bar", Device: tc.device, Channels: 1})
+
+			var fatalErr *errors.FatalError
+			require.ErrorAs(t, e.Initialize(), &fatalErr, "An unusable source device should be fatal")
+			assert.Contains(t, fatalErr.Message, "'bar'", "The error should name the source")
+		})
+	}
+}
+
+func TestStartSources(t *testing.T) {
+	e, devices := newSourcesEngine(t, "mix", config.InputSourceConfig{ID: "bar", Device: 1, Channels: 1})
+	require.NoError(t, e.Initialize())
+	stream := &mockPaStream{}
+	client := e.audio.client.(*mockPaClient)
+	client.OpenStreamResult = stream
+
+	require.NoError(t, e.startSources(context.Background()))
+
+	assert.Same(t, devices[1], client.OpenStreamParams.Input.Device, "The source device should be opened")
+	assert.Equal(t, 1, client.OpenStreamParams.Input.Channels, "The source channel count should be opened")
+	assert.True(t, stream.StartCalled, "The source stream should be started")
+
+	client.OpenStreamCallback([]int32{1, 2, 3})
+	assert.Equal(t, 1, e.sources[0].ring.Len(), "A mixed source should queue its buffers")
+
+	require.NoError(t, e.stopSources())
+	assert.True(t, stream.StopCalled, "The source stream should be stopped")
+	assert.True(t, stream.CloseCalled, "The source stream should be closed")
+}
+
+func TestMixSources(t *testing.T) {
+	newMix := func() (*Engine, *inputSource) {
+		src := &inputSource{id: "mono", channels: 1, ring: buffer.NewInt32FrameRing(mixRingSlots, 2)}
+		src.frame = make([]int32, src.ring.FrameSize())
+		e := &Engine{
+			config:    &config.Config{Input: config.InputConfig{Channels: 2, BufferSize: 2}},
+			sources:   []*inputSource{src},
+			mixBuffer: make([]int32, 4),
+		}
+		return e, src
+	}
+
+	t.Run("ChannelMapping", func(t *testing.T) {
+		e, src := newMix()
+		src.ring.Push([]int32{10, 20}, 0)
+
+		mixed := e.mixSources([]int32{1, 2, 3, 4})
+
+		assert.Equal(t, []int32{11, 12, 23, 24}, mixed, "A mono source should be added to both channels")
+		assert.Zero(t, src.ring.Len(), "The mixed buffer should be consumed")
+	})
+
+	t.Run("Clipping", func(t *testing.T) {
+		e, src := newMix()
+		src.ring.Push([]int32{math.MaxInt32, math.MinInt32}, 0)
+
+		mixed := e.mixSources([]int32{1, 1, -1, -1})
+
+		assert.Equal(t, []int32{math.MaxInt32, math.MaxInt32, math.MinInt32, math.MinInt32}, mixed, "The sum should clip at full scale")
+	})
+
+	t.Run("NoBuffer", func(t *testing.T) {
+		e, _ := newMix()
+		input := []int32{1, 2, 3, 4}
+
+		mixed := e.mixSources(input)
+
+		assert.Equal(t, input, mixed, "A source without a buffer should add silence")
+		assert.NotSame(t, &input[0], &mixed[0], "The input buffer should not be modified")
+	})
+
+	t.Run("Backlog", func(t *testing.T) {
+		e, src := newMix()
+		for i := int32(1); i <= 4; i++ {
+			src.ring.Push([]int32{i, i}, 0)
+		}
+
+		mixed := e.mixSources([]int32{0, 0, 0, 0})
+
+		assert.Equal(t, []int32{3, 3, 3, 3}, mixed, "The oldest buffers beyond the backlog should be dropped")
+		assert.Equal(t, 1, src.ring.Len())
+	})
+}
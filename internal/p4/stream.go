@@ -6,40 +6,42 @@ import (
 	"fmt"
 	"log"
 	"phase4/internal/app/errors"
+	"phase4/internal/p4/analysis"
 	"phase4/internal/p4/runtime/stage"
 	"time"
 
 	"github.com/gordonklaus/portaudio"
 )
 
+// latencyWarnInterval is the minimum time between latency budget warnings, and
+// between buffer length warnings.
+const latencyWarnInterval = 5 * time.Second
+
 func (e *Engine) startStream(ctx context.Context) error {
 	if e.audio.stream != nil {
 		log.Print("Engine ➜ Stream already active")
 		return nil
 	}
 
-	var latency time.Duration
-	if e.config.Input.LowLatency {
-		latency = e.audio.inputDevice.DefaultLowInputLatency
-	} else {
-		latency = e.audio.inputDevice.DefaultHighInputLatency
-	}
-	streamParams := portaudio.StreamParameters{
-		Input: portaudio.StreamDeviceParameters{
-			Device:   e.audio.inputDevice,
-			Channels: e.config.Input.Channels,
-			Latency:  latency,
-		},
-		SampleRate:      e.config.Input.SampleRate,
-		FramesPerBuffer: e.config.Input.BufferSize,
-	}
-	log.Printf("Engine ➜ Stream ➜ SampleRate: %.2f, BufferSize: %d, Channels: %d",
+	streamParams := e.streamParameters()
+	log.Printf("Engine ➜ Stream ➜ SampleRate: %.2f, BufferSize: %d, Channels: %d, Format: %s",
 		streamParams.SampleRate,
 		streamParams.FramesPerBuffer,
 		streamParams.Input.Channels,
+		e.sampleFormat(),
 	)
+	deviceChannels := 0
+	if streamParams.Input.Device != nil {
+		deviceChannels = streamParams.Input.Device.MaxInputChannels
+	}
+	logChannelLayout(streamParams.Input.Channels, deviceChannels)
 
-	stream, err := e.audio.client.OpenStream(streamParams, e.processInputStream)
+	var stream paStream
+	err := e.retryOpen(ctx, "Opening stream", func(int) error {
+		var err error
+		stream, err = e.audio.client.OpenStream(streamParams, e.sampleFormat(), e.processInputStream)
+		return err
+	})
 	if err != nil {
 		return &errors.FatalError{
 			Message: "failed to open PortAudio stream",
@@ -49,50 +51,266 @@ func (e *Engine) startStream(ctx context.Context) error {
 	e.audio.stream = stream
 
 	if err := e.audio.stream.Start(); err != nil {
+		if closeErr := e.audio.stream.Close(); closeErr != nil {
+			log.Printf("Engine ➜ Warning ➜ Failed to close the stream after a failed start: %v", closeErr)
+		}
 		e.audio.stream = nil
 		return &errors.FatalError{
 			Message: "failed to start PortAudio stream",
 			Err:     err,
 		}
 	}
+	log.Printf("Engine ➜ Stream ➜ Input latency: requested %s, actual %s", streamParams.Input.Latency, e.audio.stream.InputLatency())
+	if err := e.startSources(ctx); err != nil {
+		return err
+	}
 	log.Print("Engine ➜ Stream ➜ Started. (Ctrl+C) or (SigTerm) to stop.")
 
+	// Frames pushed before the analysis goroutine starts wait in the ring.
+	if e.analysisRing != nil {
+		e.analysisWg.Add(1)
+		go e.runAnalysis(ctx)
+		log.Print("Engine ➜ Stream ➜ Analysis running asynchronously")
+	}
+
 	// Wait for the context to be cancelled
 	<-ctx.Done()
+	e.analysisWg.Wait()
+	for _, src := range e.sources {
+		if src.analysis != nil {
+			src.analysis.analysisWg.Wait()
+		}
+	}
 	log.Print("Engine ➜ run() terminated")
 
 	return nil
 }
 
+// logChannelLayout logs which device inputs are captured and how they reach the
+// analysis. PortAudio opens the first channels of the device, so on a
+// multichannel interface the wrong input is easy to miss. The FFT does not
+// deinterleave, with more than one channel it sees the interleaved samples of
+// all of them as one signal.
+func logChannelLayout(channels, deviceChannels int) {
+	log.Printf("Engine ➜ Stream ➜ %s", channelLayout(channels, deviceChannels))
+	if channels > 1 {
+		log.Printf("Engine ➜ Warning ➜ %d channels are analyzed interleaved, not summed or selected. "+
+			"Set input.channels to 1 to analyze input 1 only", channels)
+	}
+}
+
+// channelLayout describes the captured device inputs, deviceChannels is 0 when
+// the device is unknown.
+func channelLayout(channels, deviceChannels int) string {
+	inputs := "input 1"
+	if channels > 1 {
+		inputs = fmt.Sprintf("inputs 1-%d", channels)
+	}
+	if deviceChannels <= 0 {
+		return "Capturing " + inputs
+	}
+	return fmt.Sprintf("Capturing %s of %d", inputs, deviceChannels)
+}
+
+// sampleFormat returns the configured input sample format.
+func (e *Engine) sampleFormat() SampleFormat {
+	format, _ := ParseSampleFormat(e.config.Input.SampleFormat)
+	return format
+}
+
+// streamParameters builds the input stream parameters from the configuration
+// and the selected input device.
+func (e *Engine) streamParameters() portaudio.StreamParameters {
+	var latency time.Duration
+	switch {
+	case e.config.Input.LatencyMs > 0:
+		latency = time.Duration(e.config.Input.LatencyMs * float64(time.Millisecond))
+	case e.config.Input.LowLatency:
+		latency = e.audio.inputDevice.DefaultLowInputLatency
+	default:
+		latency = e.audio.inputDevice.DefaultHighInputLatency
+	}
+
+	return portaudio.StreamParameters{
+		Input: portaudio.StreamDeviceParameters{
+			Device:   e.audio.inputDevice,
+			Channels: e.config.Input.Channels,
+			Latency:  latency,
+		},
+		SampleRate:      e.config.Input.SampleRate,
+		FramesPerBuffer: e.config.Input.BufferSize,
+	}
+}
+
 func (e *Engine) processInputStream(inputBuffer []int32) {
 	frameCount := e.frameCount.Add(1)
+	// Checked before the ring, which would truncate a longer buffer.
+	e.checkBufferLength(len(inputBuffer))
+	if e.mixBuffer != nil {
+		inputBuffer = e.mixSources(inputBuffer)
+	}
+
+	// With async analysis the callback only hands the samples over, if the ring
+	// is full the analysis goroutine is behind and the frame is dropped.
+	if e.analysisRing != nil {
+		if e.analysisRing.Push(inputBuffer, frameCount) {
+			select {
+			case e.analysisReady <- struct{}{}:
+			default:
+			}
+		}
+		return
+	}
 
+	e.analyzeFrame(inputBuffer, frameCount)
+}
+
+// runAnalysis drains the analysis ring off the real-time thread until ctx is
+// cancelled.
+func (e *Engine) runAnalysis(ctx context.Context) {
+	defer e.analysisWg.Done()
+	e.tuneAnalysisThread()
+
+	frame := make([]int32, e.analysisRing.FrameSize())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.analysisReady:
+			for {
+				n, frameCount, ok := e.analysisRing.Pop(frame)
+				if !ok {
+					break
+				}
+				e.analyzeFrame(frame[:n], frameCount)
+			}
+		}
+	}
+}
+
+func (e *Engine) analyzeFrame(inputBuffer []int32, frameCount uint64) {
 	if e.fftProc == nil || e.system == nil {
 		return
 	}
 
+	analysisStart := time.Now()
+
+	// Held while the processor and detector advance, so Snapshot never sees the
+	// spectrum of one frame with the tempo of another.
+	e.analysisMu.Lock()
 	e.fftProc.Process(inputBuffer)
 	magnitudes := e.fftProc.GetMagnitudes()
-	spectralFlux := e.fftProc.GetSpectralFlux()
+	phases := e.fftProc.GetPhases()
+
+	// Process flux for BPM detection
+	var bpm, displayBPM, confidence, smoothedConfidence, variationConfidence, peakConfidence float64
+	framesSinceOnset, tempoLocked := int64(-1), false
+	onsetStrength, beatPhase, lfo := 0.0, -1.0, -1.0
+	var bandOnsets []analysis.BandOnset
+	if e.bpmDetector != nil && len(magnitudes) > 0 {
+		e.bpmDetector.ProcessFFT(e.fftProc, frameCount)
+		bpm, confidence = e.bpmDetector.GetBPM()
+		displayBPM = e.bpmDetector.GetDisplayBPM()
+		smoothedConfidence = e.bpmDetector.GetSmoothedConfidence()
+		variationConfidence, peakConfidence = e.bpmDetector.GetConfidences()
+		framesSinceOnset = e.bpmDetector.FramesSinceOnset()
+		onsetStrength = e.bpmDetector.OnsetStrength()
+		beatPhase = e.bpmDetector.BeatPhase()
+		if e.lfo != nil {
+			lfo = e.lfo.Value(e.bpmDetector.BeatPosition())
+		}
+		tempoLocked = e.bpmDetector.IsLocked()
+		if e.bandOnsets != nil {
+			bandOnsets = e.bandOnsets.Process(e.fftProc, frameCount)
+		}
+	}
+	e.analyzedFrame = frameCount
+	e.analysisMu.Unlock()
 
 	if len(magnitudes) == 0 {
 		return
 	}
 
-	// Process flux for BPM detection
-	var bpm, confidence float64
-	if e.bpmDetector != nil {
-		e.bpmDetector.ProcessFlux(spectralFlux, frameCount)
-		bpm, confidence = e.bpmDetector.GetBPM()
+	// Additional resolutions are updated during warmup as well, so their sample
+	// history is filled by the time frames are emitted.
+	for _, extra := range e.extraFFTs {
+		extra.fft.Push(inputBuffer)
+	}
+
+	e.checkLatencyBudget(time.Since(analysisStart))
+
+	// The first buffers after the stream starts still go through the analysis
+	// above so its state settles, but are not emitted. Frame counts start at 1.
+	if frameCount <= uint64(e.config.DSP.WarmupFrames) {
+		return
 	}
 
 	// Pre-allocate this message to avoid hot path allocation
 	rawMsg := stage.GetRawMessage()
-	rawMsg.Magnitudes = magnitudes
-	rawMsg.SpectralFlux = spectralFlux
+	// Crop the emitted spectrum to the configured output range, BPM detection
+	// above still sees the full flux. Copying into the pooled slices keeps the
+	// message independent of the processor's buffers without allocating.
+	rawMsg.Magnitudes = append(rawMsg.Magnitudes, magnitudes[e.outputBinLo:e.outputBinHi]...)
+	rawMsg.SpectralFlux = e.fftProc.AppendSpectralFlux(rawMsg.SpectralFlux, e.outputBinLo, e.outputBinHi)
+	if phases != nil {
+		rawMsg.Phases = append(rawMsg.Phases, phases[e.outputBinLo:e.outputBinHi]...)
+	}
+	rawMsg.FrequencyResolution = e.fftProc.GetFrequencyResolution()
+	rawMsg.FrequencyStart = float64(e.outputBinLo) * rawMsg.FrequencyResolution
 	rawMsg.FrameCount = frameCount
-	rawMsg.BPM = bpm
+	rawMsg.RMS = e.fftProc.GetInputRMS()
+	rawMsg.Peak = e.fftProc.GetInputPeak()
+	rawMsg.ClipCount = e.fftProc.GetClipCount()
+	rawMsg.Clipping = rawMsg.ClipCount > 0
+	rawMsg.Channels = e.config.Input.Channels
+	rawMsg.SourceID = e.sourceID
+	rawMsg.AnalysisStart = analysisStart
+	// Frame counts start at 1, the first buffer starts at 0 on the audio clock.
+	rawMsg.AudioTime = float64(frameCount-1) * float64(e.config.Input.BufferSize) / e.config.Input.SampleRate
+	rawMsg.BPM, rawMsg.BPMValid = gatedBPM(bpm, confidence, e.config.DSP.BPMMinConfidence)
+	rawMsg.RawBPM = bpm
+	rawMsg.DisplayBPM = displayBPM
 	rawMsg.BPMConfidence = confidence
+	rawMsg.SmoothedConfidence = smoothedConfidence
+	rawMsg.VariationConfidence = variationConfidence
+	rawMsg.PeakConfidence = peakConfidence
+	rawMsg.FramesSinceOnset = framesSinceOnset
+	rawMsg.OnsetStrength = onsetStrength
+	rawMsg.BeatPhase = beatPhase
+	rawMsg.LFO = lfo
+	rawMsg.HasLFO = e.lfo != nil
+	rawMsg.TempoLocked = tempoLocked
+	for _, onset := range bandOnsets {
+		rawMsg.BandOnsets = append(rawMsg.BandOnsets, stage.BandOnset{
+			Name:             onset.Name,
+			FramesSinceOnset: onset.FramesSinceOnset,
+			Strength:         onset.Strength,
+			Onset:            onset.Onset,
+		})
+	}
+	for _, extra := range e.extraFFTs {
+		rawMsg.Spectra = stage.AppendSpectrum(rawMsg.Spectra, stage.Spectrum{
+			Magnitudes:          extra.fft.GetMagnitudes()[extra.binLo:extra.binHi],
+			FrequencyStart:      float64(extra.binLo) * extra.fft.GetFrequencyResolution(),
+			FrequencyResolution: extra.fft.GetFrequencyResolution(),
+			FFTSize:             extra.size,
+		})
+	}
+	if e.config.DSP.SpectralFeatures {
+		// Features describe the emitted range, i.e. what clients see.
+		features := analysis.ComputeSpectralFeatures(rawMsg.Magnitudes)
+		rawMsg.SpectralFlatness = features.Flatness
+		rawMsg.SpectralCrest = features.Crest
+		rawMsg.HasFeatures = true
+	}
+	if e.config.DSP.ReportLatency {
+		// Endpoints measure the latency from CapturedAt when they serialize
+		// the frame, which includes the queueing through the pipeline.
+		latency, onsetDelay := e.frameLatency(time.Since(analysisStart))
+		rawMsg.CapturedAt = analysisStart.Add(-e.analysisBudget)
+		rawMsg.OnsetDelay = onsetDelay
+		e.lastLatency.Store(int64(latency))
+	}
 
 	// Non-blocking send - if system is busy, drop the frame
 	select {
@@ -106,6 +324,79 @@ func (e *Engine) processInputStream(inputBuffer []int32) {
 	}
 }
 
+// frameLatency returns how long after the first sample of a buffer was
+// captured its frame is handed to the pipeline, given the time its analysis
+// took: the buffer period plus elapsed. Onsets are peak-picked over later
+// buffers, onsetDelay is that additional delay. Input latency of the device
+// itself is not included. With dsp.async_analysis, the first sample is assumed
+// to be captured one buffer period before analysis starts, time a buffer waits
+// in the analysis ring is not included.
+func (e *Engine) frameLatency(elapsed time.Duration) (latency, onsetDelay time.Duration) {
+	if e.bpmDetector != nil {
+		onsetDelay = e.bpmDetector.OnsetLatency()
+	}
+	return e.analysisBudget + elapsed, onsetDelay
+}
+
+// gatedBPM returns bpm and true, or 0 and false when there is no estimate yet or
+// its confidence is below minConfidence, see dsp.bpm_min_confidence. Clients
+// that act on any non-zero tempo then ignore ambiguous estimates.
+func gatedBPM(bpm, confidence, minConfidence float64) (float64, bool) {
+	if bpm <= 0 || confidence < minConfidence {
+		return 0, false
+	}
+	return bpm, true
+}
+
+// checkLatencyBudget warns when analysis of one buffer takes more than half of
+// the buffer period, at 100% the callback can no longer keep up and the audio
+// drops out. Warnings are rate-limited, overruns in between are counted.
+func (e *Engine) checkLatencyBudget(elapsed time.Duration) {
+	if e.analysisBudget <= 0 || elapsed <= e.analysisBudget/2 {
+		return
+	}
+
+	e.budgetOverruns++
+	now := time.Now()
+	if now.Sub(e.lastBudgetWarn) < latencyWarnInterval {
+		return
+	}
+
+	log.Printf("Engine ➜ Warning ➜ Analysis took %s of a %s buffer period (%.0f%%, %d overrun(s) since last warning), "+
+		"consider a smaller FFT size, a larger buffer size or dsp.async_analysis",
+		elapsed, e.analysisBudget, 100*float64(elapsed)/float64(e.analysisBudget), e.budgetOverruns)
+	e.lastBudgetWarn = now
+	e.budgetOverruns = 0
+}
+
+// checkBufferLength warns when a buffer does not hold the input.buffer_size *
+// input.channels samples the stream was opened with, or with async analysis the
+// samples a slot of the analysis ring holds. The ring truncates longer buffers
+// and the FFT silently zero-pads shorter ones, which hides channel
+// configuration bugs. Warnings are rate-limited, mismatches in between are
+// counted.
+func (e *Engine) checkBufferLength(n int) {
+	expected := e.config.Input.BufferSize * e.config.Input.Channels
+	if e.analysisRing != nil {
+		expected = e.analysisRing.FrameSize()
+	}
+	if n == expected {
+		return
+	}
+
+	e.lengthMismatches++
+	now := time.Now()
+	if now.Sub(e.lastLengthWarn) < latencyWarnInterval {
+		return
+	}
+
+	log.Printf("Engine ➜ Warning ➜ Received a buffer of %d samples, expected %d (%d frames x %d channels), "+
+		"%d mismatch(es) since last warning: check input.channels and input.buffer_size",
+		n, expected, e.config.Input.BufferSize, e.config.Input.Channels, e.lengthMismatches)
+	e.lastLengthWarn = now
+	e.lengthMismatches = 0
+}
+
 func (e *Engine) stopAudioStream() error {
 	if e.audio.stream == nil {
 		return nil
@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"phase4/internal/app/config"
+	"phase4/internal/app/errors"
+	"phase4/internal/p4/analysis"
+	"phase4/internal/p4/runtime/stage"
+	"phase4/pkg/buffer"
+	"testing"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelLayout(t *testing.T) {
+	testCases := []struct {
+		name           string
+		channels       int
+		deviceChannels int
+		expect         string
+	}{
+		{"Mono", 1, 2, "Capturing input 1 of 2"},
+		{"Multichannel", 2, 8, "Capturing inputs 1-2 of 8"},
+		{"UnknownDevice", 2, 0, "Capturing inputs 1-2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, channelLayout(tc.channels, tc.deviceChannels))
+		})
+	}
+}
+
+func TestGatedBPM(t *testing.T) {
+	testCases := []struct {
+		name          string
+		bpm           float64
+		confidence    float64
+		minConfidence float64
+		expectBPM     float64
+		expectValid   bool
+	}{
+		{"NoGate", 128, 0.1, 0, 128, true},
+		{"AboveGate", 128, 0.6, 0.5, 128, true},
+		{"AtGate", 128, 0.5, 0.5, 128, true},
+		{"BelowGate", 128, 0.1, 0.5, 0, false},
+		{"NoEstimate", 0, 0, 0, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bpm, valid := gatedBPM(tc.bpm, tc.confidence, tc.minConfidence)
+			assert.Equal(t, tc.expectBPM, bpm)
+			assert.Equal(t, tc.expectValid, valid)
+		})
+	}
+}
+
+func TestFrameLatency(t *testing.T) {
+	e := &Engine{analysisBudget: 10 * time.Millisecond}
+
+	latency, onsetDelay := e.frameLatency(2 * time.Millisecond)
+	assert.Equal(t, 12*time.Millisecond, latency, "The latency should be the buffer period plus the analysis time")
+	assert.Zero(t, onsetDelay, "Without a detector onsets should not be delayed")
+
+	e.bpmDetector = analysis.NewBPMDetector(44100, 441)
+	e.bpmDetector.SetPeakPicking(1, 3)
+	latency, onsetDelay = e.frameLatency(2 * time.Millisecond)
+	assert.Equal(t, 12*time.Millisecond, latency, "Peak picking should not delay the spectrum")
+	assert.Equal(t, 30*time.Millisecond, onsetDelay, "Peak picking should delay onsets by its post window")
+}
+
+func TestAnalyzeFrame_AudioTime(t *testing.T) {
+	e := &Engine{
+		ctx: context.Background(),
+		config: &config.Config{
+			Input: config.InputConfig{Channels: 1, SampleRate: 1024, BufferSize: 256},
+			DSP:   config.DSPConfig{Enabled: true, FFTWindow: "Hann"},
+		},
+		system: stage.NewSystem(),
+	}
+	defer e.system.Close()
+	require.NoError(t, e.initializeAnalysis(), "Analysis should initialize")
+
+	// Stands in for the processor, which is the first stage of the pipeline.
+	frames := make(chan *stage.RawAudioMessage, 2)
+	processor := stage.NewBaseActor("processor", 2, func(_ context.Context, msg stage.Message) {
+		frames <- msg.(*stage.RawAudioMessage)
+	})
+	require.NoError(t, e.system.Register(processor))
+	require.NoError(t, processor.Start(context.Background()))
+	defer processor.Stop()
+
+	before := time.Now()
+	e.analyzeFrame(make([]int32, 256), 1)
+	e.analyzeFrame(make([]int32, 256), 2)
+	for frame, want := range []float64{0, 0.25} {
+		select {
+		case msg := <-frames:
+			assert.Equal(t, want, msg.AudioTime, "Frame %d should start %.2fs into the audio", frame+1, want)
+			assert.False(t, msg.AnalysisStart.Before(before), "The analysis start should be stamped")
+			stage.PutRawMessage(msg)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for a frame")
+		}
+	}
+}
+
+func TestCheckBufferLength(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	e := &Engine{config: &config.Config{
+		Input: config.InputConfig{Channels: 2, BufferSize: 256},
+	}}
+
+	e.checkBufferLength(512)
+	assert.Empty(t, logs.String(), "A buffer of buffer_size * channels samples should not warn")
+
+	e.checkBufferLength(1024)
+	assert.Contains(t, logs.String(), "Received a buffer of 1024 samples, expected 512", "A mismatch should be logged")
+
+	logs.Reset()
+	e.checkBufferLength(1024)
+	assert.Empty(t, logs.String(), "Warnings should be rate-limited")
+	assert.Equal(t, 1, e.lengthMismatches, "Mismatches within the interval should be counted")
+
+	// With async analysis the buffer is checked before the ring truncates it.
+	async := &Engine{
+		config:        e.config,
+		analysisRing:  buffer.NewInt32FrameRing(analysisRingSlots, 512),
+		analysisReady: make(chan struct{}, 1),
+	}
+	logs.Reset()
+	async.processInputStream(make([]int32, 1024))
+	assert.Contains(t, logs.String(), "Received a buffer of 1024 samples, expected 512", "A buffer longer than a ring slot should be logged")
+	n, _, _ := async.analysisRing.Pop(make([]int32, 1024))
+	assert.Equal(t, 512, n, "The ring should hold one slot of samples")
+}
+
+func TestStartStream_Errors(t *testing.T) {
+	device := &portaudio.DeviceInfo{Name: "Mic", MaxInputChannels: 2, HostApi: &portaudio.HostApiInfo{Name: "MME"}}
+	newEngine := func(client *mockPaClient) *Engine {
+		return &Engine{
+			audio: &pa{client: client, inputDevice: device},
+			config: &config.Config{Input: config.InputConfig{
+				Channels:          1,
+				SampleRate:        44100,
+				BufferSize:        256,
+				SampleFormat:      "int32",
+				OpenRetries:       2,
+				OpenRetryInterval: time.Millisecond,
+			}},
+		}
+	}
+
+	t.Run("OpenError", func(t *testing.T) {
+		client := &mockPaClient{OpenStreamErr: fmt.Errorf("device busy")}
+		e := newEngine(client)
+
+		err := e.startStream(context.Background())
+
+		var fatalErr *errors.FatalError
+		require.ErrorAs(t, err, &fatalErr, "A failed open should be fatal")
+		assert.Equal(t, client.OpenStreamErr, fatalErr.Err)
+		assert.Equal(t, 3, client.OpenStreamCalls, "The open should be retried input.open_retries times")
+		assert.Nil(t, e.audio.stream, "No stream should be kept")
+	})
+
+	t.Run("StartError", func(t *testing.T) {
+		stream := &mockPaStream{StartErr: fmt.Errorf("start failed")}
+		client := &mockPaClient{OpenStreamResult: stream}
+		e := newEngine(client)
+
+		err := e.startStream(context.Background())
+
+		var fatalErr *errors.FatalError
+		require.ErrorAs(t, err, &fatalErr, "A failed start should be fatal")
+		assert.Equal(t, stream.StartErr, fatalErr.Err)
+		assert.True(t, stream.CloseCalled, "The opened stream should be closed")
+		assert.Nil(t, e.audio.stream, "No stream should be kept")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		stream := &mockPaStream{}
+		client := &mockPaClient{OpenStreamResult: stream}
+		e := newEngine(client)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.NoError(t, e.startStream(ctx), "A cancelled run should return cleanly")
+
+		assert.True(t, stream.StartCalled, "The stream should be started")
+		assert.Same(t, device, client.OpenStreamParams.Input.Device, "The selected device should be opened")
+		assert.Equal(t, 256, client.OpenStreamParams.FramesPerBuffer)
+		require.NotNil(t, client.OpenStreamCallback, "A callback should be registered")
+
+		client.OpenStreamCallback(make([]int32, 256))
+		assert.Equal(t, uint64(1), e.frameCount.Load(), "The callback should count frames")
+		assert.Equal(t, stream, e.audio.stream, "The stream should be kept until stopped")
+	})
+}
+
+func TestStopAudioStream(t *testing.T) {
+	testCases := []struct {
+		name         string
+		stream       *mockPaStream
+		expectSubstr []string
+	}{
+		{"Clean", &mockPaStream{}, nil},
+		{"StopError", &mockPaStream{StopErr: fmt.Errorf("stop failed")}, []string{"stop: stop failed"}},
+		{"CloseError", &mockPaStream{CloseErr: fmt.Errorf("close failed")}, []string{"close: close failed"}},
+		{"BothErrors", &mockPaStream{StopErr: fmt.Errorf("stop failed"), CloseErr: fmt.Errorf("close failed")}, []string{"stop: stop failed", "close: close failed"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &Engine{audio: &pa{stream: tc.stream}}
+
+			err := e.stopAudioStream()
+
+			assert.True(t, tc.stream.StopCalled, "The stream should be stopped")
+			assert.True(t, tc.stream.CloseCalled, "The stream should be closed even if stopping failed")
+			assert.Nil(t, e.audio.stream, "The stream should be released")
+			if tc.expectSubstr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, substr := range tc.expectSubstr {
+				assert.Contains(t, err.Error(), substr)
+			}
+		})
+	}
+
+	t.Run("NoStream", func(t *testing.T) {
+		e := &Engine{audio: &pa{}}
+		assert.NoError(t, e.stopAudioStream(), "Stopping without a stream should be a no-op")
+	})
+}
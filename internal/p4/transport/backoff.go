@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// NewBackoff creates an exponential backoff starting at initial and growing by
+// multiplier per attempt up to maxInterval. Each interval is randomized by +/- jitter
+// (a fraction in [0, 1]) so that several transports don't retry in lockstep.
+func NewBackoff(initial, maxInterval time.Duration, multiplier, jitter float64) *Backoff {
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	if maxInterval < initial {
+		maxInterval = initial
+	}
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	return &Backoff{
+		initial:    initial,
+		max:        maxInterval,
+		multiplier: multiplier,
+		jitter:     min(max(jitter, 0), 1),
+	}
+}
+
+// Next returns the interval to wait before the next attempt and advances the
+// backoff.
+func (b *Backoff) Next() time.Duration {
+	interval := float64(b.initial)
+	for i := 0; i < b.attempt && interval < float64(b.max); i++ {
+		interval *= b.multiplier
+	}
+	interval = min(interval, float64(b.max))
+	b.attempt++
+
+	if b.jitter > 0 {
+		interval += interval * b.jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(interval)
+}
+
+// Reset returns the backoff to its initial interval, call it after a successful
+// attempt.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
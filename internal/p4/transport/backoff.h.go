@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import "time"
+
+// Backoff computes retry intervals for outbound transports. It is not safe for
+// concurrent use, callers serialize access together with the connection state.
+type Backoff struct {
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+	attempt    int
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Exponential(t *testing.T) {
+	b := NewBackoff(100*time.Millisecond, time.Second, 2, 0)
+
+	assert.Equal(t, 100*time.Millisecond, b.Next())
+	assert.Equal(t, 200*time.Millisecond, b.Next())
+	assert.Equal(t, 400*time.Millisecond, b.Next())
+	assert.Equal(t, 800*time.Millisecond, b.Next())
+	assert.Equal(t, time.Second, b.Next(), "Interval should be capped at max")
+	assert.Equal(t, time.Second, b.Next(), "Interval should stay at max")
+
+	b.Reset()
+	assert.Equal(t, 100*time.Millisecond, b.Next(), "Reset should return to the initial interval")
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	b := NewBackoff(time.Second, time.Second, 2, 0.2)
+
+	for range 100 {
+		interval := b.Next()
+		assert.GreaterOrEqual(t, interval, 800*time.Millisecond, "Interval below jitter range")
+		assert.LessOrEqual(t, interval, 1200*time.Millisecond, "Interval above jitter range")
+	}
+}
+
+func TestNewBackoff_Defaults(t *testing.T) {
+	b := NewBackoff(0, 0, 0, 5)
+
+	assert.Equal(t, 500*time.Millisecond, b.initial, "Non-positive initial should use the default")
+	assert.Equal(t, b.initial, b.max, "Max should not be below initial")
+	assert.Equal(t, 1.0, b.multiplier, "Multiplier should be at least 1")
+	assert.Equal(t, 1.0, b.jitter, "Jitter should be clamped to [0, 1]")
+}
@@ -5,3 +5,11 @@ type Component interface {
 	SendData(data []byte) error
 	Close() error
 }
+
+// Greeter is implemented by connection-oriented transports that can send a
+// message to each client as it connects, before any other data, e.g. state a
+// client cannot reconstruct from later messages. SetGreeting also sends the
+// message to the clients already connected, bypassing any per-client throttling.
+type Greeter interface {
+	SetGreeting(data []byte)
+}
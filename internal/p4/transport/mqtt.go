@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"phase4/pkg/clock"
+	"time"
+)
+
+// mqttConnectTimeout bounds dialing the broker and waiting for its CONNACK.
+const mqttConnectTimeout = 5 * time.Second
+
+var errMqttReconnecting = errors.New("mqtt transport reconnecting")
+
+// NewMqttTransport connects to the MQTT broker at addr (host:port) as clientID
+// and publishes everything passed to SendData to topic, with QoS 0 so a slow or
+// lost broker never holds up the pipeline. Only the MQTT 3.1.1 subset needed to
+// publish is implemented, without TLS or authentication.
+//
+// If the connection is lost the broker is redialed on a later send, spaced out
+// by backoff. A nil backoff uses the defaults.
+func NewMqttTransport(addr, topic, clientID string, backoff *Backoff) (*MqttTransport, error) {
+	if backoff == nil {
+		backoff = NewBackoff(0, 0, 0, 0)
+	}
+
+	mqtt := &MqttTransport{
+		addr:     addr,
+		topic:    topic,
+		clientID: clientID,
+		backoff:  backoff,
+		clock:    clock.Real{},
+	}
+	if err := mqtt.connect(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("MqttTransport: Publishing to %s on %s", topic, addr)
+
+	return mqtt, nil
+}
+
+// connect dials the broker and completes the MQTT handshake. Keep alive is
+// disabled, so the broker does not disconnect a publisher that goes quiet, e.g.
+// while the input is paused.
+func (mqtt *MqttTransport) connect() error {
+	conn, err := net.DialTimeout("tcp", mqtt.addr, mqttConnectTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to dial %s: %w", mqtt.addr, err)
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(mqttConnectTimeout))
+	if _, err := conn.Write(appendMqttConnect(nil, mqtt.clientID)); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to connect to %s: %w", mqtt.addr, err)
+	}
+	var ack [4]byte
+	if _, err := io.ReadFull(conn, ack[:]); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("no CONNACK from %s: %w", mqtt.addr, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	if ack[0] != mqttConnack || ack[1] != 2 {
+		_ = conn.Close()
+		return fmt.Errorf("unexpected reply from %s: %#x", mqtt.addr, ack[0])
+	}
+	if ack[3] != 0 {
+		_ = conn.Close()
+		return fmt.Errorf("broker %s refused the connection: return code %d", mqtt.addr, ack[3])
+	}
+
+	mqtt.conn = conn
+	if mqtt.greeting != nil {
+		// The broker may have restarted and lost the retained message.
+		return mqtt.publish(mqtt.greeting, true)
+	}
+	return nil
+}
+
+// SetClock replaces the clock used to schedule reconnects.
+func (mqtt *MqttTransport) SetClock(c clock.Clock) {
+	mqtt.mu.Lock()
+	defer mqtt.mu.Unlock()
+	mqtt.clock = c
+}
+
+// SetGreeting publishes data as the retained message of the topic, which the
+// broker delivers to every subscriber as it subscribes, before any other data.
+func (mqtt *MqttTransport) SetGreeting(data []byte) {
+	mqtt.mu.Lock()
+	defer mqtt.mu.Unlock()
+
+	mqtt.greeting = append([]byte(nil), data...)
+	if mqtt.conn != nil {
+		if err := mqtt.publish(mqtt.greeting, true); err != nil {
+			mqtt.scheduleRetry(err)
+		}
+	}
+}
+
+func (mqtt *MqttTransport) SendData(data []byte) error {
+	mqtt.mu.Lock()
+	defer mqtt.mu.Unlock()
+
+	if mqtt.closed {
+		return net.ErrClosed
+	}
+
+	if mqtt.conn == nil {
+		if mqtt.clock.Now().Before(mqtt.retryAt) {
+			return errMqttReconnecting
+		}
+		if err := mqtt.connect(); err != nil {
+			mqtt.scheduleRetry(err)
+			return err
+		}
+		mqtt.backoff.Reset()
+		log.Printf("MqttTransport: Reconnected to %s", mqtt.addr)
+	}
+
+	if err := mqtt.publish(data, false); err != nil {
+		mqtt.scheduleRetry(err)
+		return err
+	}
+	return nil
+}
+
+// publish writes one PUBLISH packet, dropping the connection if it fails. The
+// caller schedules the reconnect, so a failure is only backed off once.
+func (mqtt *MqttTransport) publish(payload []byte, retain bool) error {
+	mqtt.packet = appendMqttPublish(mqtt.packet[:0], mqtt.topic, payload, retain)
+
+	_ = mqtt.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := mqtt.conn.Write(mqtt.packet)
+	_ = mqtt.conn.SetWriteDeadline(time.Time{})
+	if err != nil {
+		_ = mqtt.conn.Close()
+		mqtt.conn = nil
+		return err
+	}
+
+	return nil
+}
+
+func (mqtt *MqttTransport) scheduleRetry(err error) {
+	interval := mqtt.backoff.Next()
+	mqtt.retryAt = mqtt.clock.Now().Add(interval)
+	log.Printf("MqttTransport: Publish to %s failed: %v. Retrying in %s.", mqtt.addr, err, interval)
+}
+
+func (mqtt *MqttTransport) Close() error {
+	mqtt.mu.Lock()
+	defer mqtt.mu.Unlock()
+
+	log.Printf("MqttTransport: Shutting down...")
+	mqtt.closed = true
+	if mqtt.conn == nil {
+		return nil
+	}
+	// DISCONNECT, so the broker does not treat this as a connection loss.
+	_, _ = mqtt.conn.Write([]byte{0xe0, 0})
+	err := mqtt.conn.Close()
+	mqtt.conn = nil
+	return err
+}
+
+// appendMqttConnect appends a CONNECT packet for a clean session with keep
+// alive disabled.
+func appendMqttConnect(dst []byte, clientID string) []byte {
+	// Protocol name (6), level (1), flags (1) and keep alive (2).
+	const variableHeaderSize = 10
+
+	dst = append(dst, mqttConnect)
+	dst = appendMqttLength(dst, variableHeaderSize+2+len(clientID))
+	dst = appendMqttString(dst, "MQTT")
+	dst = append(dst, mqttProtocolLevel, mqttCleanSession, 0, 0)
+	return appendMqttString(dst, clientID)
+}
+
+// appendMqttPublish appends a QoS 0 PUBLISH packet.
+func appendMqttPublish(dst []byte, topic string, payload []byte, retain bool) []byte {
+	header := byte(mqttPublish)
+	if retain {
+		header |= mqttRetain
+	}
+
+	dst = append(dst, header)
+	dst = appendMqttLength(dst, 2+len(topic)+len(payload))
+	dst = appendMqttString(dst, topic)
+	return append(dst, payload...)
+}
+
+// appendMqttLength appends n as an MQTT remaining length, 7 bits per byte with
+// the high bit set on all but the last.
+func appendMqttLength(dst []byte, n int) []byte {
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		dst = append(dst, b)
+		if n == 0 {
+			return dst
+		}
+	}
+}
+
+// appendMqttString appends s with its big-endian uint16 length prefix.
+func appendMqttString(dst []byte, s string) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(s)))
+	return append(dst, s...)
+}
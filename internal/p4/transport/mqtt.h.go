@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"net"
+	"phase4/pkg/clock"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types and flags used by the publisher.
+const (
+	mqttConnect       = 0x10
+	mqttConnack       = 0x20
+	mqttPublish       = 0x30
+	mqttRetain        = 0x01
+	mqttProtocolLevel = 4
+	mqttCleanSession  = 0x02
+)
+
+type MqttTransport struct {
+	retryAt  time.Time
+	clock    clock.Clock
+	conn     net.Conn
+	backoff  *Backoff
+	greeting []byte // Published retained, nil when unset.
+	packet   []byte // PUBLISH packet, reused across sends.
+	addr     string
+	topic    string
+	clientID string
+	mu       sync.Mutex
+	closed   bool
+}
@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"phase4/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mqttPacket is a control packet as received by fakeBroker.
+type mqttPacket struct {
+	header byte
+	body   []byte
+}
+
+// readMqttPacket reads one control packet, decoding its remaining length.
+func readMqttPacket(r *bufio.Reader) (mqttPacket, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return mqttPacket{}, err
+	}
+	length, multiplier := 0, 1
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return mqttPacket{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return mqttPacket{header: header, body: body}, err
+}
+
+// fakeBroker accepts MQTT connections, acknowledges CONNECT with returnCode and
+// forwards every later packet to the returned channel.
+func fakeBroker(t *testing.T, returnCode byte) (net.Listener, <-chan mqttPacket) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup failed: could not listen")
+	t.Cleanup(func() { listener.Close() })
+
+	packets := make(chan mqttPacket, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				connect, err := readMqttPacket(r)
+				if err != nil || connect.header != mqttConnect {
+					return
+				}
+				if _, err := conn.Write([]byte{mqttConnack, 2, 0, returnCode}); err != nil {
+					return
+				}
+				for {
+					packet, err := readMqttPacket(r)
+					if err != nil {
+						return
+					}
+					packets <- packet
+				}
+			}()
+		}
+	}()
+	return listener, packets
+}
+
+// publishedTo decodes a PUBLISH packet into its topic and payload.
+func publishedTo(t *testing.T, packet mqttPacket) (string, string) {
+	require.Equal(t, byte(mqttPublish), packet.header&0xf0, "Packet should be a PUBLISH")
+	n := int(binary.BigEndian.Uint16(packet.body))
+	return string(packet.body[2 : 2+n]), string(packet.body[2+n:])
+}
+
+func receive(t *testing.T, packets <-chan mqttPacket) mqttPacket {
+	select {
+	case packet := <-packets:
+		return packet
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a packet")
+		return mqttPacket{}
+	}
+}
+
+func TestMqttTransport_Publish(t *testing.T) {
+	broker, packets := fakeBroker(t, 0)
+
+	mqtt, err := NewMqttTransport(broker.Addr().String(), "phase4/fft", "test", nil)
+	require.NoError(t, err, "NewMqttTransport should connect")
+	defer mqtt.Close()
+
+	mqtt.SetGreeting([]byte(`{"type":"axis"}`))
+	greeting := receive(t, packets)
+	assert.Equal(t, byte(mqttRetain), greeting.header&mqttRetain, "The greeting should be retained")
+
+	require.NoError(t, mqtt.SendData([]byte(`{"frameCount":1}`)))
+	frame := receive(t, packets)
+	assert.Zero(t, frame.header&mqttRetain, "Frames should not be retained")
+	topic, payload := publishedTo(t, frame)
+	assert.Equal(t, "phase4/fft", topic)
+	assert.Equal(t, `{"frameCount":1}`, payload)
+}
+
+func TestMqttTransport_LargePayload(t *testing.T) {
+	broker, packets := fakeBroker(t, 0)
+
+	mqtt, err := NewMqttTransport(broker.Addr().String(), "t", "test", nil)
+	require.NoError(t, err, "NewMqttTransport should connect")
+	defer mqtt.Close()
+
+	// Above 16383 bytes the remaining length takes three bytes.
+	data := make([]byte, 20000)
+	for i := range data {
+		data[i] = 'a' + byte(i%26)
+	}
+	require.NoError(t, mqtt.SendData(data))
+	_, payload := publishedTo(t, receive(t, packets))
+	assert.Equal(t, string(data), payload)
+}
+
+func TestNewMqttTransport_Refused(t *testing.T) {
+	broker, _ := fakeBroker(t, 5) // Not authorized.
+
+	mqtt, err := NewMqttTransport(broker.Addr().String(), "t", "test", nil)
+
+	assert.Nil(t, mqtt, "Transport should be nil when the broker refuses the connection")
+	assert.ErrorContains(t, err, "return code 5")
+}
+
+func TestMqttTransport_Reconnect(t *testing.T) {
+	broker, packets := fakeBroker(t, 0)
+	clk := clock.NewManual(time.Unix(1000, 0))
+
+	mqtt, err := NewMqttTransport(broker.Addr().String(), "t", "test", NewBackoff(time.Second, time.Second, 1, 0))
+	require.NoError(t, err, "NewMqttTransport should connect")
+	defer mqtt.Close()
+	mqtt.SetClock(clk)
+	mqtt.SetGreeting([]byte("axis"))
+	receive(t, packets)
+
+	// Simulate a lost broker connection, the next publish fails.
+	mqtt.mu.Lock()
+	_ = mqtt.conn.Close()
+	mqtt.mu.Unlock()
+	assert.Error(t, mqtt.SendData([]byte("lost")), "Publishing on a lost connection should fail")
+	assert.ErrorIs(t, mqtt.SendData([]byte("early")), errMqttReconnecting, "Sends within the backoff should be skipped")
+
+	clk.Advance(time.Second)
+	require.NoError(t, mqtt.SendData([]byte("again")), "The broker should be redialed after the backoff")
+	greeting := receive(t, packets)
+	_, payload := publishedTo(t, greeting)
+	assert.Equal(t, "axis", payload, "The retained greeting should be republished after reconnecting")
+	_, payload = publishedTo(t, receive(t, packets))
+	assert.Equal(t, "again", payload)
+}
+
+func TestMqttTransport_PublishFailureBacksOffOnce(t *testing.T) {
+	broker, packets := fakeBroker(t, 0)
+	clk := clock.NewManual(time.Unix(1000, 0))
+
+	mqtt, err := NewMqttTransport(broker.Addr().String(), "t", "test", NewBackoff(time.Second, time.Minute, 2, 0))
+	require.NoError(t, err, "NewMqttTransport should connect")
+	defer mqtt.Close()
+	mqtt.SetClock(clk)
+	require.NoError(t, mqtt.SendData([]byte("first")))
+	receive(t, packets)
+
+	mqtt.mu.Lock()
+	_ = mqtt.conn.Close()
+	mqtt.mu.Unlock()
+	assert.Error(t, mqtt.SendData([]byte("lost")), "Publishing on a lost connection should fail")
+	assert.Equal(t, clk.Now().Add(time.Second), mqtt.retryAt, "A failed publish should take one step of the backoff")
+}
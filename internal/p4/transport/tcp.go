@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// NewTCPTransport listens on addr and streams newline-delimited JSON to every
+// connected client. Like the WebSocket transport, the listener is bound before
+// returning so that address errors are reported to the caller.
+func NewTCPTransport(addr string) (*TCPTransport, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	tcp := &TCPTransport{
+		listener:  listener,
+		clients:   make(map[net.Conn]bool),
+		readLimit: DefaultReadLimit,
+	}
+
+	log.Printf("TCPTransport: Starting server on %s", listener.Addr())
+	go tcp.acceptLoop()
+
+	return tcp, nil
+}
+
+func (tcp *TCPTransport) acceptLoop() {
+	for {
+		conn, err := tcp.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				log.Printf("TCPTransport: Server shut down.")
+				return
+			}
+			log.Printf("TCPTransport: Accept error: %v", err)
+			continue
+		}
+		log.Printf("TCPTransport: Client connected: %s", conn.RemoteAddr())
+
+		go tcp.addClient(conn)
+	}
+}
+
+// addClient sends the greeting and registers conn for SendData. The lock is held
+// across the greeting so a concurrent SetGreeting and broadcast can not be missed
+// and nothing else writes to conn before it.
+func (tcp *TCPTransport) addClient(conn net.Conn) {
+	tcp.clientsMu.Lock()
+	if tcp.greeting != nil {
+		_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		_, err := conn.Write(tcp.greeting)
+		_ = conn.SetWriteDeadline(time.Time{})
+		if err != nil {
+			tcp.clientsMu.Unlock()
+			log.Printf("TCPTransport: Write error to %s: %v. Dropping client.", conn.RemoteAddr(), err)
+			_ = conn.Close()
+			return
+		}
+	}
+	tcp.clients[conn] = true
+	readLimit := tcp.readLimit
+	tcp.clientsMu.Unlock()
+
+	tcp.watchClient(conn, readLimit)
+}
+
+// SetGreeting sets the line sent to every client that connects from now on, and
+// sends it to the connected clients.
+func (tcp *TCPTransport) SetGreeting(jsonData []byte) {
+	line := make([]byte, len(jsonData)+1)
+	copy(line, jsonData)
+	line[len(jsonData)] = '\n'
+
+	tcp.clientsMu.Lock()
+	tcp.greeting = line
+	tcp.clientsMu.Unlock()
+
+	_ = tcp.SendData(jsonData)
+}
+
+// watchClient detects connection closure. Incoming data is discarded, clients
+// are not expected to send any, and a client sending more than readLimit bytes
+// in total is disconnected. A readLimit of 0 accepts any amount.
+func (tcp *TCPTransport) watchClient(conn net.Conn, readLimit int64) {
+	if readLimit <= 0 {
+		_, _ = io.Copy(io.Discard, conn)
+	} else if n, _ := io.Copy(io.Discard, io.LimitReader(conn, readLimit+1)); n > readLimit {
+		log.Printf("TCPTransport: Client %s sent more than %d bytes. Dropping client.", conn.RemoteAddr(), readLimit)
+	}
+
+	tcp.removeClient(conn)
+	log.Printf("TCPTransport: Client disconnected: %s", conn.RemoteAddr())
+}
+
+// SetReadLimit sets how many bytes clients that connect from now on may send
+// before they are disconnected, 0 removes the limit.
+func (tcp *TCPTransport) SetReadLimit(limit int64) {
+	tcp.clientsMu.Lock()
+	tcp.readLimit = limit
+	tcp.clientsMu.Unlock()
+}
+
+func (tcp *TCPTransport) removeClient(conn net.Conn) {
+	tcp.clientsMu.Lock()
+	if _, ok := tcp.clients[conn]; ok {
+		delete(tcp.clients, conn)
+		_ = conn.Close()
+	}
+	tcp.clientsMu.Unlock()
+}
+
+func (tcp *TCPTransport) SendData(jsonData []byte) error {
+	tcp.clientsMu.RLock()
+	clientsSnapshot := make([]net.Conn, 0, len(tcp.clients))
+	for conn := range tcp.clients {
+		clientsSnapshot = append(clientsSnapshot, conn)
+	}
+	tcp.clientsMu.RUnlock()
+
+	if len(clientsSnapshot) == 0 {
+		return nil
+	}
+
+	line := make([]byte, len(jsonData)+1)
+	copy(line, jsonData)
+	line[len(jsonData)] = '\n'
+
+	var wg sync.WaitGroup
+	for _, conn := range clientsSnapshot {
+		wg.Add(1)
+		go func(c net.Conn, dataToSend []byte) {
+			defer wg.Done()
+			_ = c.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			_, err := c.Write(dataToSend)
+			_ = c.SetWriteDeadline(time.Time{})
+
+			if err != nil {
+				log.Printf("TCPTransport: Write error to %s: %v. Removing client.", c.RemoteAddr(), err)
+				tcp.removeClient(c)
+			}
+		}(conn, line)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (tcp *TCPTransport) Close() error {
+	log.Printf("TCPTransport: Shutting down...")
+	err := tcp.listener.Close()
+
+	tcp.clientsMu.Lock()
+	for conn := range tcp.clients {
+		_ = conn.Close()
+		delete(tcp.clients, conn)
+	}
+	tcp.clientsMu.Unlock()
+
+	log.Printf("TCPTransport: Shutdown complete.")
+	return err
+}
@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"net"
+	"sync"
+)
+
+type TCPTransport struct {
+	listener  net.Listener
+	clients   map[net.Conn]bool
+	greeting  []byte // Newline-terminated, nil when unset.
+	readLimit int64  // Bytes accepted per client, see SetReadLimit.
+	clientsMu sync.RWMutex
+}
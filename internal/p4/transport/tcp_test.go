@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPTransport_SendData(t *testing.T) {
+	tcp, err := NewTCPTransport("127.0.0.1:0")
+	require.NoError(t, err, "NewTCPTransport should succeed")
+	defer tcp.Close()
+
+	conn, err := net.Dial("tcp", tcp.listener.Addr().String())
+	require.NoError(t, err, "Client should connect")
+	defer conn.Close()
+
+	// Wait for the accept loop to register the client.
+	require.Eventually(t, func() bool {
+		tcp.clientsMu.RLock()
+		defer tcp.clientsMu.RUnlock()
+		return len(tcp.clients) == 1
+	}, time.Second, 5*time.Millisecond, "Client should be registered")
+
+	require.NoError(t, tcp.SendData([]byte(`{"frameCount":1}`)))
+	require.NoError(t, tcp.SendData([]byte(`{"frameCount":2}`)))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "{\"frameCount\":1}\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "{\"frameCount\":2}\n", line)
+}
+
+func TestTCPTransport_ClientDisconnect(t *testing.T) {
+	tcp, err := NewTCPTransport("127.0.0.1:0")
+	require.NoError(t, err, "NewTCPTransport should succeed")
+	defer tcp.Close()
+
+	conn, err := net.Dial("tcp", tcp.listener.Addr().String())
+	require.NoError(t, err, "Client should connect")
+	require.NoError(t, conn.Close())
+
+	assert.Eventually(t, func() bool {
+		tcp.clientsMu.RLock()
+		defer tcp.clientsMu.RUnlock()
+		return len(tcp.clients) == 0
+	}, time.Second, 5*time.Millisecond, "Disconnected client should be removed")
+}
+
+func TestNewTCPTransport_BindError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup failed: could not reserve a port")
+	defer listener.Close()
+
+	tcp, err := NewTCPTransport(listener.Addr().String())
+
+	assert.Nil(t, tcp, "Transport should be nil when the address is in use")
+	assert.Error(t, err, "Expected an error when the address is in use")
+}
+
+func TestTCPTransport_Greeting(t *testing.T) {
+	tcp, err := NewTCPTransport("127.0.0.1:0")
+	require.NoError(t, err, "NewTCPTransport should succeed")
+	defer tcp.Close()
+
+	tcp.SetGreeting([]byte(`{"type":"frequency_axis"}`))
+
+	conn, err := net.Dial("tcp", tcp.listener.Addr().String())
+	require.NoError(t, err, "Client should connect")
+	defer conn.Close()
+
+	require.Eventually(t, func() bool {
+		tcp.clientsMu.RLock()
+		defer tcp.clientsMu.RUnlock()
+		return len(tcp.clients) == 1
+	}, time.Second, 5*time.Millisecond, "Client should be registered")
+	require.NoError(t, tcp.SendData([]byte(`{"frameCount":1}`)))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "{\"type\":\"frequency_axis\"}\n", line, "The greeting should be sent first")
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "{\"frameCount\":1}\n", line)
+}
+
+func TestTCPTransport_ReadLimit(t *testing.T) {
+	tcp, err := NewTCPTransport("127.0.0.1:0")
+	require.NoError(t, err, "NewTCPTransport should succeed")
+	defer tcp.Close()
+	tcp.SetReadLimit(16)
+
+	conn, err := net.Dial("tcp", tcp.listener.Addr().String())
+	require.NoError(t, err, "Client should connect")
+	defer conn.Close()
+
+	require.Eventually(t, func() bool {
+		tcp.clientsMu.RLock()
+		defer tcp.clientsMu.RUnlock()
+		return len(tcp.clients) == 1
+	}, time.Second, 5*time.Millisecond, "Client should be registered")
+
+	_, err = conn.Write(make([]byte, 16))
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	tcp.clientsMu.RLock()
+	assert.Len(t, tcp.clients, 1, "A client within the limit should stay connected")
+	tcp.clientsMu.RUnlock()
+
+	_, err = conn.Write([]byte{0})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		tcp.clientsMu.RLock()
+		defer tcp.clientsMu.RUnlock()
+		return len(tcp.clients) == 0
+	}, time.Second, 5*time.Millisecond, "A client exceeding the limit should be dropped")
+}
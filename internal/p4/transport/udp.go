@@ -1,16 +1,167 @@
 // SPDX-License-Identifier: Apache-2.0
 package transport
 
-func NewUdpTransport(addr, path string) (*UdpTransport, error) {
-	udp := &UdpTransport{}
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"phase4/pkg/clock"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+var errUdpReconnecting = errors.New("udp transport reconnecting")
+
+// NewUdpTransport creates a UDP sender for addr. When addr is a multicast group
+// (e.g. 239.0.0.1:9000 or [ff02::1]:9000) the outgoing interface and TTL (hop
+// limit for IPv6) are configured on the socket. An empty ifaceName leaves the
+// interface choice to the OS, a multicastTTL of 0 leaves the OS default (1).
+//
+// If a send fails the socket is dropped and addr is re-resolved and redialed on a
+// later send, spaced out by backoff. A nil backoff uses the defaults.
+func NewUdpTransport(addr, ifaceName string, multicastTTL int, backoff *Backoff) (*UdpTransport, error) {
+	if backoff == nil {
+		backoff = NewBackoff(0, 0, 0, 0)
+	}
+
+	udp := &UdpTransport{
+		addr:         addr,
+		ifaceName:    ifaceName,
+		multicastTTL: multicastTTL,
+		backoff:      backoff,
+		clock:        clock.Real{},
+	}
+	if err := udp.dial(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("UdpTransport: Sending to %s (multicast: %v)", udp.remoteAddr, udp.remoteAddr.IP.IsMulticast())
 
 	return udp, nil
 }
 
+// dial resolves the configured address and opens a new socket to it. Resolving on
+// every dial picks up DNS changes, e.g. a receiver that moved to a new host.
+func (udp *UdpTransport) dial() error {
+	remoteAddr, err := net.ResolveUDPAddr("udp", udp.addr)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", udp.addr, err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, remoteAddr)
+	if err != nil {
+		return fmt.Errorf("failed to dial %s: %w", udp.addr, err)
+	}
+
+	if remoteAddr.IP.IsMulticast() {
+		if err := configureMulticast(conn, remoteAddr, udp.ifaceName, udp.multicastTTL); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+
+	udp.conn = conn
+	udp.remoteAddr = remoteAddr
+	return nil
+}
+
+// configureMulticast sets the egress interface and TTL for a multicast group. A
+// sender does not need to join the group, membership is only required to receive.
+func configureMulticast(conn *net.UDPConn, remoteAddr *net.UDPAddr, ifaceName string, multicastTTL int) error {
+	var iface *net.Interface
+	if ifaceName != "" {
+		i, err := net.InterfaceByName(ifaceName)
+		if err != nil {
+			return fmt.Errorf("failed to find multicast interface %s: %w", ifaceName, err)
+		}
+		iface = i
+	}
+
+	if remoteAddr.IP.To4() != nil {
+		pconn := ipv4.NewPacketConn(conn)
+		if iface != nil {
+			if err := pconn.SetMulticastInterface(iface); err != nil {
+				return fmt.Errorf("failed to set multicast interface: %w", err)
+			}
+		}
+		if multicastTTL > 0 {
+			if err := pconn.SetMulticastTTL(multicastTTL); err != nil {
+				return fmt.Errorf("failed to set multicast TTL: %w", err)
+			}
+		}
+		return nil
+	}
+
+	pconn := ipv6.NewPacketConn(conn)
+	if iface != nil {
+		if err := pconn.SetMulticastInterface(iface); err != nil {
+			return fmt.Errorf("failed to set multicast interface: %w", err)
+		}
+	}
+	if multicastTTL > 0 {
+		if err := pconn.SetMulticastHopLimit(multicastTTL); err != nil {
+			return fmt.Errorf("failed to set multicast hop limit: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetClock replaces the clock used to schedule reconnects.
+func (udp *UdpTransport) SetClock(c clock.Clock) {
+	udp.mu.Lock()
+	defer udp.mu.Unlock()
+	udp.clock = c
+}
+
 func (udp *UdpTransport) SendData(data []byte) error {
+	udp.mu.Lock()
+	defer udp.mu.Unlock()
+
+	if udp.closed {
+		return net.ErrClosed
+	}
+
+	if udp.conn == nil {
+		if udp.clock.Now().Before(udp.retryAt) {
+			return errUdpReconnecting
+		}
+		if err := udp.dial(); err != nil {
+			udp.scheduleRetry(err)
+			return err
+		}
+		udp.backoff.Reset()
+		log.Printf("UdpTransport: Reconnected to %s", udp.remoteAddr)
+	}
+
+	if _, err := udp.conn.Write(data); err != nil {
+		_ = udp.conn.Close()
+		udp.conn = nil
+		udp.scheduleRetry(err)
+		return err
+	}
+
 	return nil
 }
 
+func (udp *UdpTransport) scheduleRetry(err error) {
+	interval := udp.backoff.Next()
+	udp.retryAt = udp.clock.Now().Add(interval)
+	log.Printf("UdpTransport: Send to %s failed: %v. Retrying in %s.", udp.addr, err, interval)
+}
+
 func (udp *UdpTransport) Close() error {
-	return nil
+	udp.mu.Lock()
+	defer udp.mu.Unlock()
+
+	log.Printf("UdpTransport: Shutting down...")
+	udp.closed = true
+	if udp.conn == nil {
+		return nil
+	}
+	err := udp.conn.Close()
+	udp.conn = nil
+	return err
 }
@@ -1,5 +1,22 @@
 // SPDX-License-Identifier: Apache-2.0
 package transport
 
+import (
+	"net"
+	"phase4/pkg/clock"
+	"sync"
+	"time"
+)
+
 type UdpTransport struct {
+	retryAt      time.Time
+	clock        clock.Clock
+	conn         *net.UDPConn
+	remoteAddr   *net.UDPAddr
+	backoff      *Backoff
+	addr         string
+	ifaceName    string
+	multicastTTL int
+	mu           sync.Mutex
+	closed       bool
 }
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"net"
+	"phase4/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUdpTransport_SendData(t *testing.T) {
+	receiver, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup failed: could not listen for UDP")
+	defer receiver.Close()
+
+	udp, err := NewUdpTransport(receiver.LocalAddr().String(), "", 0, nil)
+	require.NoError(t, err, "NewUdpTransport should succeed")
+	defer udp.Close()
+
+	require.NoError(t, udp.SendData([]byte(`{"type":"test"}`)), "SendData should succeed")
+
+	buf := make([]byte, 64)
+	require.NoError(t, receiver.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := receiver.ReadFrom(buf)
+	require.NoError(t, err, "Receiver should get the datagram")
+	assert.Equal(t, `{"type":"test"}`, string(buf[:n]))
+}
+
+func TestNewUdpTransport_InvalidAddress(t *testing.T) {
+	udp, err := NewUdpTransport("invalid-address", "", 0, nil)
+
+	assert.Nil(t, udp, "Transport should be nil for an invalid address")
+	assert.Error(t, err, "Expected an error for an invalid address")
+}
+
+func TestNewUdpTransport_UnknownInterface(t *testing.T) {
+	udp, err := NewUdpTransport("239.0.0.1:9000", "phase4-no-such-if", 1, nil)
+
+	assert.Nil(t, udp, "Transport should be nil for an unknown interface")
+	assert.Error(t, err, "Expected an error for an unknown multicast interface")
+}
+
+func TestUdpTransport_Reconnect(t *testing.T) {
+	receiver, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup failed: could not listen for UDP")
+	defer receiver.Close()
+
+	udp, err := NewUdpTransport(receiver.LocalAddr().String(), "", 0, NewBackoff(time.Hour, time.Hour, 1, 0))
+	require.NoError(t, err, "NewUdpTransport should succeed")
+	defer udp.Close()
+	clk := clock.NewManual(time.Unix(0, 0))
+	udp.SetClock(clk)
+
+	// Simulate a failed send, the next send within the backoff is rejected.
+	_ = udp.conn.Close()
+	assert.Error(t, udp.SendData([]byte("lost")), "Send on a broken socket should fail")
+	assert.ErrorIs(t, udp.SendData([]byte("lost")), errUdpReconnecting, "Send during backoff should be rejected")
+
+	// Once the backoff has elapsed the address is redialed.
+	clk.Advance(time.Hour)
+	require.NoError(t, udp.SendData([]byte("back")), "Send after backoff should reconnect")
+
+	buf := make([]byte, 64)
+	require.NoError(t, receiver.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := receiver.ReadFrom(buf)
+	require.NoError(t, err, "Receiver should get the datagram")
+	assert.Equal(t, "back", string(buf[:n]))
+}
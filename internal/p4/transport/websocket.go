@@ -3,14 +3,32 @@ package transport
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"phase4/pkg/clock"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// drainTimeout bounds how long Close waits for writes in progress.
+const drainTimeout = 2 * time.Second
+
+// DefaultShutdownTimeout bounds Close unless changed with SetShutdownTimeout.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// DefaultBroadcastWorkers is the number of goroutines writing frames to
+// clients unless changed with SetBroadcastWorkers.
+const DefaultBroadcastWorkers = 8
+
+// DefaultReadLimit is the largest message, in bytes, accepted from a client
+// unless changed with SetReadLimit. Clients only send small control messages.
+const DefaultReadLimit = 4096
+
 func NewWebSocketTransport(addr, path string) (*WebSocketTransport, error) {
 	wst := &WebSocketTransport{
 		upgrader: websocket.Upgrader{
@@ -19,10 +37,14 @@ func NewWebSocketTransport(addr, path string) (*WebSocketTransport, error) {
 			// Allow all origins for simplicity, adjust for internet facing services.
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
-		clients:     make(map[*websocket.Conn]bool),
-		serverAddr:  addr,
-		serverPath:  path,
-		shutdownSig: make(chan struct{}),
+		clients:         make(map[*websocket.Conn]*wsClient),
+		clock:           clock.Real{},
+		readLimit:       DefaultReadLimit,
+		workers:         DefaultBroadcastWorkers,
+		shutdownTimeout: DefaultShutdownTimeout,
+		serverAddr:      addr,
+		serverPath:      path,
+		shutdownSig:     make(chan struct{}),
 	}
 
 	mux := http.NewServeMux()
@@ -31,10 +53,18 @@ func NewWebSocketTransport(addr, path string) (*WebSocketTransport, error) {
 		Addr:    addr,
 		Handler: mux,
 	}
+
+	// Bind synchronously so that address errors (e.g. port already in use) are
+	// reported to the caller, then serve in the background.
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
 	go func() {
 		log.Printf("WebSocketTransport: Starting server on %s%s", addr, path)
-		if err := wst.httpServer.ListenAndServe(); err != http.ErrServerClosed {
-			log.Printf("WebSocketTransport: HTTP server ListenAndServe error: %v", err)
+		if err := wst.httpServer.Serve(listener); err != http.ErrServerClosed {
+			log.Printf("WebSocketTransport: HTTP server Serve error: %v", err)
 		}
 		log.Printf("WebSocketTransport: Server shut down.")
 	}()
@@ -42,61 +72,197 @@ func NewWebSocketTransport(addr, path string) (*WebSocketTransport, error) {
 	return wst, nil
 }
 
+// SendData writes jsonData to every connected client, except clients that asked
+// for a lower frame rate and received a frame within their interval. It returns
+// once the writes are done, jsonData is not retained and can be reused.
 func (wst *WebSocketTransport) SendData(jsonData []byte) error {
-	wst.clientsMu.RLock()
+	return wst.send(jsonData, true)
+}
+
+func (wst *WebSocketTransport) send(jsonData []byte, throttle bool) error {
+	wst.clientsMu.Lock()
+	if wst.draining {
+		wst.clientsMu.Unlock()
+		return nil
+	}
+	now := wst.clock.Now()
 	clientsSnapshot := make([]*websocket.Conn, 0, len(wst.clients))
-	for conn := range wst.clients {
+	for conn, client := range wst.clients {
+		if !client.ready {
+			continue // Its greeting is still being written, see handleWebSocket.
+		}
+		if throttle {
+			if client.minInterval > 0 && now.Sub(client.lastSent) < client.minInterval {
+				continue
+			}
+			client.lastSent = now
+		}
 		clientsSnapshot = append(clientsSnapshot, conn)
 	}
-	wst.clientsMu.RUnlock()
+	// Registered under the lock so Drain can not miss writes about to start.
+	wst.writes.Add(len(clientsSnapshot))
+	wst.clientsMu.Unlock()
 
 	if len(clientsSnapshot) == 0 {
 		return nil
 	}
 
+	// Writes are queued to the worker pool rather than given a goroutine each,
+	// and run concurrently so one slow client does not delay the others.
+	wst.startWorkers.Do(wst.startBroadcastWorkers)
+
 	var wg sync.WaitGroup
+	wg.Add(len(clientsSnapshot))
+	wst.jobsMu.RLock()
 	for _, conn := range clientsSnapshot {
-		wg.Add(1)
-		go func(c *websocket.Conn, dataToSend []byte) {
-			defer wg.Done()
-			_ = c.SetWriteDeadline(time.Now().Add(5 * time.Second))
-			err := c.WriteMessage(websocket.TextMessage, dataToSend)
-			_ = c.SetWriteDeadline(time.Time{})
-
-			if err != nil {
-				log.Printf("WebSocketTransport: Write error to %s: %v. Removing client.", c.RemoteAddr(), err)
-				wst.clientsMu.Lock()
-				if _, ok := wst.clients[c]; ok {
-					delete(wst.clients, c)
-					_ = c.Close()
-				}
-				wst.clientsMu.Unlock()
-			}
-		}(conn, jsonData)
+		if wst.jobsClosed {
+			wg.Done()
+			wst.writes.Done()
+			continue
+		}
+		wst.jobs <- wsWrite{conn: conn, data: jsonData, done: &wg}
 	}
+	wst.jobsMu.RUnlock()
 	wg.Wait()
 
 	return nil
 }
 
+// startBroadcastWorkers starts the worker pool, on the first send so
+// SetBroadcastWorkers can still change its size after construction.
+func (wst *WebSocketTransport) startBroadcastWorkers() {
+	wst.clientsMu.RLock()
+	workers := wst.workers
+	wst.clientsMu.RUnlock()
+
+	wst.jobs = make(chan wsWrite, workers)
+	for range workers {
+		go func() {
+			for job := range wst.jobs {
+				wst.write(job)
+			}
+		}()
+	}
+}
+
+// stopBroadcastWorkers lets the workers finish the queued writes and exit,
+// later sends complete without writing.
+func (wst *WebSocketTransport) stopBroadcastWorkers() {
+	wst.startWorkers.Do(func() {}) // No pool to stop if nothing was ever sent.
+
+	wst.jobsMu.Lock()
+	defer wst.jobsMu.Unlock()
+	if !wst.jobsClosed && wst.jobs != nil {
+		close(wst.jobs)
+	}
+	wst.jobsClosed = true
+}
+
+// write sends one frame to one client, removing the client if it fails.
+func (wst *WebSocketTransport) write(job wsWrite) {
+	defer job.done.Done()
+	defer wst.writes.Done()
+
+	c := job.conn
+	_ = c.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	err := c.WriteMessage(websocket.TextMessage, job.data)
+	_ = c.SetWriteDeadline(time.Time{})
+
+	if err != nil {
+		log.Printf("WebSocketTransport: Write error to %s: %v. Removing client.", c.RemoteAddr(), err)
+		wst.clientsMu.Lock()
+		if _, ok := wst.clients[c]; ok {
+			delete(wst.clients, c)
+			_ = c.Close()
+		}
+		wst.clientsMu.Unlock()
+	}
+}
+
+// SetBroadcastWorkers sets how many goroutines write frames to clients, it must
+// be called before the first SendData. More workers keep slow clients from
+// delaying the others, at most one per client is ever busy.
+func (wst *WebSocketTransport) SetBroadcastWorkers(workers int) {
+	wst.clientsMu.Lock()
+	wst.workers = max(workers, 1)
+	wst.clientsMu.Unlock()
+}
+
+// SetGreeting sets the message sent to every client that connects from now on,
+// and sends it to the connected clients regardless of their frame rate.
+func (wst *WebSocketTransport) SetGreeting(jsonData []byte) {
+	wst.clientsMu.Lock()
+	wst.greeting = jsonData
+	wst.greetingSeq++
+	wst.clientsMu.Unlock()
+
+	_ = wst.send(jsonData, false)
+}
+
+// Drain stops sending new data and waits up to timeout for writes already in
+// progress to finish, so closing the connections does not cut a frame off
+// mid-write. Close calls it before closing connections.
+func (wst *WebSocketTransport) Drain(timeout time.Duration) error {
+	wst.clientsMu.Lock()
+	wst.draining = true
+	wst.clientsMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		wst.writes.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("writes still in progress after %s", timeout)
+	}
+}
+
+// SetShutdownTimeout sets how long Close may take in total, including draining
+// writes in progress, before the HTTP server is closed forcibly.
+func (wst *WebSocketTransport) SetShutdownTimeout(timeout time.Duration) {
+	wst.clientsMu.Lock()
+	wst.shutdownTimeout = timeout
+	wst.clientsMu.Unlock()
+}
+
 func (wst *WebSocketTransport) Close() error {
 	log.Printf("WebSocketTransport: Shutting down...")
+	wst.clientsMu.RLock()
+	timeout := wst.shutdownTimeout
+	wst.clientsMu.RUnlock()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	drained := true
+	if err := wst.Drain(min(drainTimeout, timeout)); err != nil {
+		log.Printf("WebSocketTransport: Drain error: %v", err)
+		drained = false
+	}
 	close(wst.shutdownSig) // Signal background tasks if any were using this.
+	wst.stopBroadcastWorkers()
 
 	// Close all client connections.
 	wst.clientsMu.Lock()
-	for conn := range wst.clients {
-		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server shutting down"))
+	for conn, client := range wst.clients {
+		// A client still being greeted, or any client after Drain gave up, may
+		// have a write in progress, which the close frame must not interleave
+		// with. Closing the connection fails that write instead.
+		if drained && client.ready {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server shutting down"))
+		}
 		_ = conn.Close()
 		delete(wst.clients, conn) // Remove while iterating safely due to lock.
 	}
 	wst.clientsMu.Unlock()
 
-	// Graceful shutdown of the HTTP server.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Graceful shutdown of the HTTP server, within what is left of the timeout.
 	if err := wst.httpServer.Shutdown(ctx); err != nil {
 		log.Printf("WebSocketTransport: HTTP server shutdown error: %v", err)
+		_ = wst.httpServer.Close()
 		return err
 	}
 
@@ -112,8 +278,36 @@ func (wst *WebSocketTransport) handleWebSocket(w http.ResponseWriter, r *http.Re
 	}
 	log.Printf("WebSocketTransport: Client connected: %s", conn.RemoteAddr())
 
+	// The client is registered right away, but receives no frames until it is
+	// ready. The greeting is written outside the lock, so a slow client does
+	// not hold up broadcasts to the others, and written again if SetGreeting
+	// replaced it in the meantime.
+	client := &wsClient{}
+	var greetingSeq uint64
 	wst.clientsMu.Lock()
-	wst.clients[conn] = true
+	wst.clients[conn] = client
+	// Larger messages fail ReadMessage before they are buffered, which closes
+	// the connection.
+	conn.SetReadLimit(wst.readLimit)
+	for greetingSeq != wst.greetingSeq {
+		greeting := wst.greeting
+		greetingSeq = wst.greetingSeq
+		wst.clientsMu.Unlock()
+
+		_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		err := conn.WriteMessage(websocket.TextMessage, greeting)
+		_ = conn.SetWriteDeadline(time.Time{})
+
+		wst.clientsMu.Lock()
+		if err != nil {
+			delete(wst.clients, conn)
+			wst.clientsMu.Unlock()
+			log.Printf("WebSocketTransport: Write error to %s: %v. Dropping client.", conn.RemoteAddr(), err)
+			_ = conn.Close()
+			return
+		}
+	}
+	client.ready = true
 	wst.clientsMu.Unlock()
 
 	go func() {
@@ -126,14 +320,58 @@ func (wst *WebSocketTransport) handleWebSocket(w http.ResponseWriter, r *http.Re
 			log.Printf("WebSocketTransport: Client disconnected: %s", conn.RemoteAddr())
 		}()
 		for {
-			// Detect connection closure. Don't process incoming data here.
-			if _, _, err := conn.ReadMessage(); err != nil {
+			// Detect connection closure and apply client requests.
+			_, data, err := conn.ReadMessage()
+			if err != nil {
 				// Check if it's a normal closure or an unexpected error.
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					log.Printf("WebSocketTransport: Read error from %s: %v", conn.RemoteAddr(), err)
 				}
 				break
 			}
+			wst.handleRequest(conn, data)
 		}
 	}()
 }
+
+// handleRequest applies a control message from a client. {"fps": n} limits the
+// frames sent to that client to n per second, independent of other clients, 0
+// removes the limit. Anything else is ignored.
+func (wst *WebSocketTransport) handleRequest(conn *websocket.Conn, data []byte) {
+	var req wsClientRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.FPS == nil {
+		return
+	}
+	if *req.FPS < 0 {
+		log.Printf("WebSocketTransport: Ignoring fps %g from %s", *req.FPS, conn.RemoteAddr())
+		return
+	}
+
+	var minInterval time.Duration
+	if *req.FPS > 0 {
+		minInterval = time.Duration(float64(time.Second) / *req.FPS)
+	}
+
+	wst.clientsMu.Lock()
+	if client, ok := wst.clients[conn]; ok {
+		client.minInterval = minInterval
+	}
+	wst.clientsMu.Unlock()
+	log.Printf("WebSocketTransport: Client %s requested %g fps", conn.RemoteAddr(), *req.FPS)
+}
+
+// SetReadLimit sets the largest message, in bytes, accepted from clients that
+// connect from now on. A client exceeding it is disconnected, 0 removes the
+// limit.
+func (wst *WebSocketTransport) SetReadLimit(limit int64) {
+	wst.clientsMu.Lock()
+	wst.readLimit = limit
+	wst.clientsMu.Unlock()
+}
+
+// SetClock replaces the clock used to pace clients that requested a frame rate.
+func (wst *WebSocketTransport) SetClock(c clock.Clock) {
+	wst.clientsMu.Lock()
+	wst.clock = c
+	wst.clientsMu.Unlock()
+}
@@ -3,17 +3,51 @@ package transport
 
 import (
 	"net/http"
+	"phase4/pkg/clock"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 type WebSocketTransport struct {
-	clients     map[*websocket.Conn]bool
-	httpServer  *http.Server
-	shutdownSig chan struct{}
-	upgrader    websocket.Upgrader
-	serverAddr  string
-	serverPath  string
-	clientsMu   sync.RWMutex
+	clients         map[*websocket.Conn]*wsClient
+	clock           clock.Clock
+	greeting        []byte // Nil when unset.
+	greetingSeq     uint64 // Incremented by SetGreeting, 0 while unset.
+	httpServer      *http.Server
+	shutdownSig     chan struct{}
+	upgrader        websocket.Upgrader
+	serverAddr      string
+	serverPath      string
+	readLimit       int64         // Bytes per client message, see SetReadLimit.
+	shutdownTimeout time.Duration // Budget for Close, see SetShutdownTimeout.
+	jobs            chan wsWrite  // Queued client writes, see startBroadcastWorkers.
+	jobsMu          sync.RWMutex  // Guards sends on jobs against its close.
+	startWorkers    sync.Once
+	workers         int
+	jobsClosed      bool
+	writes          sync.WaitGroup // Client writes in progress, see Drain.
+	clientsMu       sync.RWMutex
+	draining        bool
+}
+
+// wsWrite is one frame to write to one client, done is signaled when it has
+// been written or failed.
+type wsWrite struct {
+	conn *websocket.Conn
+	data []byte
+	done *sync.WaitGroup
+}
+
+// wsClient is the per-connection delivery state, guarded by clientsMu.
+type wsClient struct {
+	lastSent    time.Time
+	minInterval time.Duration // From the client's requested fps, 0 sends every frame.
+	ready       bool          // The greeting was written, frames may be sent.
+}
+
+// wsClientRequest is a control message sent by a client, e.g. {"fps": 10}.
+type wsClientRequest struct {
+	FPS *float64 `json:"fps"`
 }
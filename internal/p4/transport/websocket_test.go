@@ -0,0 +1,309 @@
+// SPDX-License-Identifier: Apache-2.0
+package transport
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"phase4/pkg/clock"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebSocketTransport_BindError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Setup failed: could not reserve a port")
+	defer listener.Close()
+
+	wst, err := NewWebSocketTransport(listener.Addr().String(), "/ws")
+
+	assert.Nil(t, wst, "Transport should be nil when the address is in use")
+	assert.Error(t, err, "Expected an error when the address is in use")
+}
+
+func TestNewWebSocketTransport_Close(t *testing.T) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(t, err, "NewWebSocketTransport should succeed on a free port")
+
+	assert.NoError(t, wst.Close(), "Close should succeed")
+}
+
+func TestWebSocketTransport_ClientFPS(t *testing.T) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(t, err, "NewWebSocketTransport should succeed on a free port")
+	defer wst.Close()
+	clk := clock.NewManual(time.Unix(1000, 0))
+	wst.SetClock(clk)
+
+	server := httptest.NewServer(http.HandlerFunc(wst.handleWebSocket))
+	defer server.Close()
+
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		require.NoError(t, err, "Client should connect")
+		return conn
+	}
+	slow, fast := dial(), dial()
+	defer slow.Close()
+	defer fast.Close()
+
+	require.NoError(t, slow.WriteMessage(websocket.TextMessage, []byte(`{"fps": 2}`)))
+	require.Eventually(t, func() bool {
+		wst.clientsMu.RLock()
+		defer wst.clientsMu.RUnlock()
+		limited := 0
+		for _, client := range wst.clients {
+			if client.minInterval == 500*time.Millisecond {
+				limited++
+			}
+		}
+		return len(wst.clients) == 2 && limited == 1
+	}, time.Second, 5*time.Millisecond, "The fps request should be applied to one client")
+
+	require.NoError(t, wst.SendData([]byte("1")))
+	clk.Advance(100 * time.Millisecond)
+	require.NoError(t, wst.SendData([]byte("2")))
+	clk.Advance(400 * time.Millisecond)
+	require.NoError(t, wst.SendData([]byte("3")))
+
+	read := func(conn *websocket.Conn) string {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "1", read(slow))
+	assert.Equal(t, "3", read(slow), "Frames within the requested interval should be dropped")
+	assert.Equal(t, "1", read(fast))
+	assert.Equal(t, "2", read(fast), "Other clients should not be throttled")
+	assert.Equal(t, "3", read(fast))
+}
+
+func TestWebSocketTransport_Drain(t *testing.T) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(t, err, "NewWebSocketTransport should succeed on a free port")
+	defer wst.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(wst.handleWebSocket))
+	defer server.Close()
+	conn := dialClients(t, wst, server, 1)[0]
+	defer conn.Close()
+
+	// A write that does not finish in time is reported.
+	wst.writes.Add(1)
+	assert.Error(t, wst.Drain(10*time.Millisecond), "Drain should time out while a write is in progress")
+
+	wst.writes.Done()
+	assert.NoError(t, wst.Drain(time.Second), "Drain should succeed once writes have finished")
+
+	assert.NoError(t, wst.SendData([]byte("late")), "SendData after Drain should be a no-op")
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err = conn.ReadMessage()
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr, "No frame should be written after Drain")
+	assert.True(t, netErr.Timeout(), "The read should time out waiting for a frame")
+}
+
+func TestWebSocketTransport_ReadLimit(t *testing.T) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(t, err, "NewWebSocketTransport should succeed on a free port")
+	defer wst.Close()
+	wst.SetReadLimit(64)
+
+	server := httptest.NewServer(http.HandlerFunc(wst.handleWebSocket))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err, "Client should connect")
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"fps": 2}`)))
+	require.Eventually(t, func() bool {
+		wst.clientsMu.RLock()
+		defer wst.clientsMu.RUnlock()
+		for _, client := range wst.clients {
+			return client.minInterval == 500*time.Millisecond
+		}
+		return false
+	}, time.Second, 5*time.Millisecond, "A message within the limit should be applied")
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, make([]byte, 65)))
+	assert.Eventually(t, func() bool {
+		wst.clientsMu.RLock()
+		defer wst.clientsMu.RUnlock()
+		return len(wst.clients) == 0
+	}, time.Second, 5*time.Millisecond, "A client exceeding the limit should be disconnected")
+}
+
+func TestWebSocketTransport_ShutdownTimeout(t *testing.T) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(t, err, "NewWebSocketTransport should succeed on a free port")
+	wst.SetShutdownTimeout(50 * time.Millisecond)
+
+	// A write that never finishes would hold Close for the full drainTimeout.
+	wst.writes.Add(1)
+	defer wst.writes.Done()
+
+	start := time.Now()
+	_ = wst.Close()
+	assert.Less(t, time.Since(start), drainTimeout, "Close should be bounded by the shutdown timeout")
+}
+
+func TestWebSocketTransport_CloseDuringWrite(t *testing.T) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(t, err, "NewWebSocketTransport should succeed on a free port")
+	wst.SetShutdownTimeout(100 * time.Millisecond)
+
+	server := httptest.NewServer(http.HandlerFunc(wst.handleWebSocket))
+	defer server.Close()
+	conn := dialClients(t, wst, server, 1)[0]
+	defer conn.Close()
+
+	// The client never reads, so a frame too large for the socket buffers
+	// keeps its write in progress past Drain.
+	sent := make(chan error, 1)
+	go func() { sent <- wst.SendData([]byte(strings.Repeat("f", 16<<20))) }()
+	select {
+	case <-sent:
+		t.Fatal("The write should be in progress")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A close frame written now would panic on the concurrent write.
+	assert.NotPanics(t, func() { _ = wst.Close() }, "Close should not write to a busy connection")
+	select {
+	case err := <-sent:
+		assert.NoError(t, err, "SendData should return once the connection is closed")
+	case <-time.After(time.Second):
+		t.Fatal("Closing the connection should end the write")
+	}
+}
+
+// dialClients connects n clients to wst through server and waits until they are
+// registered and greeted.
+func dialClients(tb testing.TB, wst *WebSocketTransport, server *httptest.Server, n int) []*websocket.Conn {
+	tb.Helper()
+	conns := make([]*websocket.Conn, n)
+	for i := range conns {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		require.NoError(tb, err, "Client should connect")
+		conns[i] = conn
+	}
+	require.Eventually(tb, func() bool {
+		return readyClients(wst) == n
+	}, 5*time.Second, 5*time.Millisecond, "Clients should be registered")
+	return conns
+}
+
+// readyClients returns how many clients of wst receive frames.
+func readyClients(wst *WebSocketTransport) int {
+	wst.clientsMu.RLock()
+	defer wst.clientsMu.RUnlock()
+	ready := 0
+	for _, client := range wst.clients {
+		if client.ready {
+			ready++
+		}
+	}
+	return ready
+}
+
+func TestWebSocketTransport_Greeting(t *testing.T) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(t, err, "NewWebSocketTransport should succeed on a free port")
+	defer wst.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(wst.handleWebSocket))
+	defer server.Close()
+
+	read := func(conn *websocket.Conn) string {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	// A greeting too large for the socket buffers, a client that does not read
+	// it stays in the middle of its greeting.
+	greeting := strings.Repeat("g", 16<<20)
+	reader := dialClients(t, wst, server, 1)[0]
+	defer reader.Close()
+	received := make(chan string, 1)
+	go func() { received <- read(reader) }()
+	wst.SetGreeting([]byte(greeting))
+	assert.Equal(t, greeting, <-received, "Connected clients should receive a new greeting")
+
+	stalled, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err, "Client should connect")
+	require.Eventually(t, func() bool {
+		wst.clientsMu.RLock()
+		defer wst.clientsMu.RUnlock()
+		return len(wst.clients) == 2
+	}, time.Second, 5*time.Millisecond, "The stalled client should be registered")
+
+	start := time.Now()
+	require.NoError(t, wst.SendData([]byte("frame")))
+	assert.Less(t, time.Since(start), time.Second, "A client being greeted should not hold up broadcasts")
+	assert.Equal(t, "frame", read(reader))
+	assert.Equal(t, 1, readyClients(wst), "A client should get no frames before its greeting")
+
+	// Once read, the greeting is followed by frames.
+	assert.Equal(t, greeting, read(stalled), "A new client should be greeted first")
+	require.Eventually(t, func() bool { return readyClients(wst) == 2 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, wst.SendData([]byte("next")))
+	assert.Equal(t, "next", read(stalled))
+	stalled.Close()
+}
+
+func TestWebSocketTransport_BroadcastWorkers(t *testing.T) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(t, err, "NewWebSocketTransport should succeed on a free port")
+	wst.SetBroadcastWorkers(2)
+
+	server := httptest.NewServer(http.HandlerFunc(wst.handleWebSocket))
+	defer server.Close()
+	conns := dialClients(t, wst, server, 5)
+
+	require.NoError(t, wst.SendData([]byte("frame")))
+	for _, conn := range conns {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err, "Every client should receive the frame with fewer workers than clients")
+		assert.Equal(t, "frame", string(data))
+		conn.Close()
+	}
+
+	require.NoError(t, wst.Close())
+	assert.NoError(t, wst.SendData([]byte("late")), "SendData after Close should be a no-op")
+}
+
+func BenchmarkWebSocketTransport_SendData100Clients(b *testing.B) {
+	wst, err := NewWebSocketTransport("127.0.0.1:0", "/ws")
+	require.NoError(b, err, "NewWebSocketTransport should succeed on a free port")
+	defer wst.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(wst.handleWebSocket))
+	defer server.Close()
+	for _, conn := range dialClients(b, wst, server, 100) {
+		defer conn.Close()
+		go func() {
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	frame := []byte(`{"type":"fft_magnitudes","frameCount":1}`)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		_ = wst.SendData(frame)
+	}
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"phase4/internal/app/errors"
+	"phase4/pkg/bitint"
+	"time"
+)
+
+const (
+	// tuneDuration is how long the stream runs while measuring callback timing.
+	tuneDuration = 5 * time.Second
+
+	// A callback interval longer than tuneLateFactor buffer periods means the
+	// host delivered buffers in a burst after a stall, i.e. an audible glitch.
+	tuneLateFactor = 1.5
+
+	// Jitter (standard deviation of the callback interval) above this fraction
+	// of the buffer period leaves too little headroom for analysis.
+	tuneMaxJitter = 0.25
+)
+
+// tuneBufferSize opens the input stream at the configured buffer size for
+// tuneDuration without running any analysis, measures the callback interval and
+// returns a *errors.CommandCompleted with a buffer size recommendation. It is
+// advisory only and terminates PortAudio, the engine is not usable afterwards.
+func tuneBufferSize(e *Engine) error {
+	bufferSize := e.config.Input.BufferSize
+	period := time.Duration(float64(bufferSize) / e.config.Input.SampleRate * float64(time.Second))
+
+	// Preallocated so the callback does not allocate, the slice is only read
+	// after the stream has been stopped.
+	callbacks := make([]time.Time, 0, 2*int(tuneDuration/period)+1)
+	stream, err := e.audio.client.OpenStream(e.streamParameters(), e.sampleFormat(), func([]int32) {
+		if len(callbacks) < cap(callbacks) {
+			callbacks = append(callbacks, time.Now())
+		}
+	})
+	if err != nil {
+		return &errors.FatalError{
+			Message: "failed to open PortAudio stream for tuning",
+			Err:     err,
+		}
+	}
+
+	log.Printf("Engine ➜ Tune ➜ Measuring callback timing at buffer size %d (%s period) for %s",
+		bufferSize, period, tuneDuration)
+	if err := stream.Start(); err != nil {
+		_ = stream.Close()
+		return &errors.FatalError{
+			Message: "failed to start PortAudio stream for tuning",
+			Err:     err,
+		}
+	}
+	time.Sleep(tuneDuration)
+	if err := stream.Stop(); err != nil {
+		log.Printf("Engine ➜ Warning ➜ Tune ➜ Failed to stop stream: %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		log.Printf("Engine ➜ Warning ➜ Tune ➜ Failed to close stream: %v", err)
+	}
+	if err := exitPA(e); err != nil {
+		return err
+	}
+
+	if len(callbacks) < 2 {
+		return &errors.FatalError{
+			Message: "tuning failed",
+			Err:     fmt.Errorf("received %d callbacks in %s", len(callbacks), tuneDuration),
+		}
+	}
+
+	return &errors.CommandCompleted{Message: tuneReport(callbacks, bufferSize, period)}
+}
+
+// tuneReport summarizes the callback intervals and recommends the next power of
+// two above bufferSize when the timing shows stalls or excessive jitter.
+func tuneReport(callbacks []time.Time, bufferSize int, period time.Duration) string {
+	var sum, sumSq float64
+	var maxInterval time.Duration
+	late := 0
+	for i := 1; i < len(callbacks); i++ {
+		interval := callbacks[i].Sub(callbacks[i-1])
+		sum += float64(interval)
+		sumSq += float64(interval) * float64(interval)
+		maxInterval = max(maxInterval, interval)
+		if float64(interval) > tuneLateFactor*float64(period) {
+			late++
+		}
+	}
+	n := float64(len(callbacks) - 1)
+	mean := sum / n
+	jitter := math.Sqrt(max(sumSq/n-mean*mean, 0))
+
+	report := fmt.Sprintf("Buffer size %d: %d callbacks, mean interval %s (expected %s), jitter %s, max %s, %d late",
+		bufferSize, len(callbacks), time.Duration(mean), period, time.Duration(jitter), maxInterval, late)
+
+	if late > 0 || jitter > tuneMaxJitter*float64(period) {
+		recommended := bitint.NextPowerOfTwo(bufferSize + 1)
+		return fmt.Sprintf("%s\nTiming is unstable, recommended input.buffer_size: %d", report, recommended)
+	}
+
+	return fmt.Sprintf("%s\nTiming is stable, input.buffer_size %d is fine", report, bufferSize)
+}
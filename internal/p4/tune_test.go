@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+package p4
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuneReport(t *testing.T) {
+	const period = 10 * time.Millisecond
+	start := time.Unix(0, 0)
+
+	callbacksAt := func(intervals ...time.Duration) []time.Time {
+		callbacks := []time.Time{start}
+		for _, interval := range intervals {
+			callbacks = append(callbacks, callbacks[len(callbacks)-1].Add(interval))
+		}
+		return callbacks
+	}
+
+	testCases := []struct {
+		name      string
+		callbacks []time.Time
+		expect    string
+	}{
+		{"Stable", callbacksAt(period, period, period, period), "input.buffer_size 256 is fine"},
+		{"Stall", callbacksAt(period, 3*period, time.Millisecond, period), "recommended input.buffer_size: 512"},
+		{"Jitter", callbacksAt(4*period/10, 16*period/10, 4*period/10, 16*period/10), "recommended input.buffer_size: 512"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Contains(t, tuneReport(tc.callbacks, 256, period), tc.expect)
+		})
+	}
+}
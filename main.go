@@ -3,43 +3,79 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"phase4/internal/app/config"
 	"phase4/internal/app/errors"
 	"phase4/internal/p4"
-	"time"
+	"runtime"
+)
+
+var (
+	configPath   = flag.String("config", "", "Config file to load, - reads it from stdin (default config.yaml or config/config.yaml)")
+	checkConfig  = flag.Bool("check-config", false, "Validate the configuration and exit")
+	dumpDefaults = flag.Bool("dump-defaults", false, "Write the default configuration as JSON to stdout and exit")
+	listDevices  = flag.Bool("list-devices", false, "List the available audio devices and exit")
+	printConfig  = flag.Bool("print-config", false, "Log the effective configuration at startup (always on with debug)")
+	tune         = flag.Bool("tune", false, "Measure stream timing and recommend a buffer size, then exit")
 )
 
 func main() {
-	cfg, err := config.Load()
+	flag.Parse()
+
+	if *dumpDefaults {
+		data, err := config.DefaultConfigJSON()
+		if err != nil {
+			handleExit(&errors.FatalError{Message: "failed to encode the default configuration", Err: err})
+		}
+		handleExit(&errors.CommandCompleted{Message: string(data)})
+	}
+
+	log.Printf("Phase4 ➜ Starting (pid %d, %s %s/%s)", os.Getpid(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	cfg, err := config.LoadFrom(*configPath)
 	if err != nil {
-		errors.HandleFatalAndExit(err)
+		handleExit(err)
+	}
+	if *printConfig || cfg.Debug {
+		cfg.LogEffective()
+	}
+	if *checkConfig {
+		handleExit(&errors.CommandCompleted{Message: "Config ➜ OK"})
 	}
 
 	engine := p4.NewEngine(cfg)
+	engine.SetListDevices(*listDevices)
+	engine.SetTune(*tune)
 	lifecycle := p4.NewLifecycleManager(engine)
 
 	// Initialize but don't start yet
 	if err := engine.Initialize(); err != nil {
-		errors.HandleFatalAndExit(err)
+		handleExit(err)
 	}
 
 	// Set up signal handling
 	ctx, cancel := context.WithCancel(context.Background())
 	signalHandler := p4.NewSignalHandler(cancel)
 	defer signalHandler.Stop()
+	signalHandler.OnSnapshot(engine.LogSnapshot)
 
 	// Start the engine
 	if err := lifecycle.Start(); err != nil {
-		errors.HandleFatalAndExit(err)
+		handleExit(err)
 	}
 
-	// Wait for shutdown signal
-	<-ctx.Done()
+	// Wait for shutdown signal, or the end of a file input
+	select {
+	case <-ctx.Done():
+	case <-engine.Done():
+		log.Print("Input finished")
+	}
 
 	// Graceful shutdown with timeout
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer shutdownCancel()
 
 	done := make(chan struct{})
@@ -56,3 +92,15 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// handleExit terminates the process for a non-nil err. A *errors.CommandCompleted
+// is a successful exit for one-shot commands, its message is written to stdout
+// and the exit code is 0. Anything else is treated as fatal.
+func handleExit(err error) {
+	if completed, ok := err.(*errors.CommandCompleted); ok {
+		fmt.Fprintln(os.Stdout, completed.Message)
+		os.Exit(0)
+	}
+
+	errors.HandleFatalAndExit(err)
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"sync"
+)
+
+// Complex128DoubleBuffer provides a thread-safe double buffer specifically for []complex128,
+// e.g. FFT output published to phase-aware consumers. It maintains two []complex128
+// buffers - one for reading and one for writing - and atomically swaps them.
+type Complex128DoubleBuffer struct {
+	buffers [2][]complex128 // The two buffers we alternate between.
+	active  int             // Index of the active buffer (0 or 1).
+	mu      sync.RWMutex    // Protects all buffer operations.
+}
+
+// NewComplex128DoubleBuffer creates a new double buffer for []complex128
+// with the provided initial buffer values.
+// The first buffer (buffer1) is initially set as the active buffer for reading.
+func NewComplex128DoubleBuffer(buffer1, buffer2 []complex128) *Complex128DoubleBuffer {
+	return &Complex128DoubleBuffer{
+		buffers: [2][]complex128{buffer1, buffer2},
+		active:  0,
+	}
+}
+
+// Get returns a copy of the current active []complex128 buffer.
+// This ensures readers have a stable snapshot.
+func (db *Complex128DoubleBuffer) Get() []complex128 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	src := db.buffers[db.active]
+	if src == nil {
+		return nil
+	}
+	dst := make([]complex128, len(src))
+	copy(dst, src)
+	return dst
+}
+
+// Swap updates the inactive []complex128 buffer using the provided function
+// and then makes it the new active buffer for reading.
+func (db *Complex128DoubleBuffer) Swap(updateFn func(buffer *[]complex128)) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	inactive := 1 - db.active
+	updateFn(&db.buffers[inactive])
+	db.active = inactive
+}
+
+// ForceGet gets a copy of the current []complex128 buffer and executes the provided
+// function with that buffer.
+func (db *Complex128DoubleBuffer) ForceGet(fn func(buffer []complex128)) {
+	buffer := db.Get()
+	fn(buffer)
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplex128DoubleBuffer_Basic(t *testing.T) {
+	buffer1 := make([]complex128, 4)
+	buffer2 := make([]complex128, 4)
+
+	db := NewComplex128DoubleBuffer(buffer1, buffer2)
+	assert.Equal(t, buffer1, db.Get())
+
+	values := []complex128{1 + 1i, 2 - 2i, -3 + 0.5i, 0}
+	db.Swap(func(buffer *[]complex128) {
+		copy(*buffer, values)
+	})
+	assert.Equal(t, values, db.Get())
+
+	// The returned slice is a copy, modifying it must not affect the buffer.
+	snapshot := db.Get()
+	snapshot[0] = 42
+	assert.Equal(t, values[0], db.Get()[0])
+
+	db.ForceGet(func(buffer []complex128) {
+		assert.Equal(t, values, buffer)
+	})
+}
+
+func TestComplex128DoubleBuffer_Nil(t *testing.T) {
+	db := NewComplex128DoubleBuffer(nil, nil)
+	assert.Nil(t, db.Get())
+
+	// A nil buffer can be replaced by the update function.
+	db.Swap(func(buffer *[]complex128) {
+		*buffer = []complex128{1i}
+	})
+	assert.Equal(t, []complex128{1i}, db.Get())
+}
+
+func TestComplex128DoubleBuffer_ConcurrentAccess(t *testing.T) {
+	const size = 64
+	db := NewComplex128DoubleBuffer(make([]complex128, size), make([]complex128, size))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 1000; n++ {
+			v := complex(float64(n), -float64(n))
+			db.Swap(func(buffer *[]complex128) {
+				for i := range *buffer {
+					(*buffer)[i] = v
+				}
+			})
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 1000; n++ {
+			snapshot := db.Get()
+			for i := range snapshot {
+				assert.Equal(t, snapshot[0], snapshot[i], "Snapshot should never mix two writes")
+			}
+		}
+	}()
+
+	wg.Wait()
+}
@@ -50,6 +50,26 @@ func (db *Float64DoubleBuffer) Swap(updateFn func(buffer *[]float64)) {
 	db.active = inactive
 }
 
+// View executes fn with the active []float64 buffer itself, under the read
+// lock and without copying. fn must not modify or retain the buffer, and must
+// not call Swap.
+func (db *Float64DoubleBuffer) View(fn func(buffer []float64)) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	fn(db.buffers[db.active])
+}
+
+// AppendTo appends elements lo to hi of the current []float64 buffer to dst and
+// returns the extended slice, so a reader can copy into a buffer it reuses
+// instead of allocating one as Get does.
+func (db *Float64DoubleBuffer) AppendTo(dst []float64, lo, hi int) []float64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return append(dst, db.buffers[db.active][lo:hi]...)
+}
+
 // ForceGet gets a copy of the current []float64 buffer and executes the provided
 // function with that buffer.
 func (db *Float64DoubleBuffer) ForceGet(fn func(buffer []float64)) {
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat64DoubleBuffer_View(t *testing.T) {
+	db := NewFloat64DoubleBuffer([]float64{1, 2}, []float64{0, 0})
+	db.Swap(func(buffer *[]float64) {
+		(*buffer)[0], (*buffer)[1] = 3, 4
+	})
+
+	var viewed []float64
+	db.View(func(buffer []float64) {
+		viewed = append(viewed, buffer...)
+	})
+	assert.Equal(t, []float64{3, 4}, viewed, "View should see the active buffer")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		db.View(func(buffer []float64) {})
+	})
+	assert.Zero(t, allocs, "View should not copy the buffer")
+}
+
+func TestFloat64DoubleBuffer_AppendTo(t *testing.T) {
+	db := NewFloat64DoubleBuffer([]float64{1, 2, 3}, []float64{0, 0, 0})
+	dst := make([]float64, 1, 4)
+	dst = db.AppendTo(dst, 1, 3)
+	assert.Equal(t, []float64{0, 2, 3}, dst, "AppendTo should append the requested range")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = db.AppendTo(dst[:0], 0, 3)
+	})
+	assert.Zero(t, allocs, "Appending within capacity should not allocate")
+}
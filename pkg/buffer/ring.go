@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"phase4/pkg/bitint"
+	"phase4/pkg/simd"
+	"sync/atomic"
+)
+
+// Int32FrameRing is a lock-free single-producer single-consumer ring of fixed
+// capacity []int32 frames. It is intended to hand audio buffers from the real-time
+// callback to a worker goroutine: Push never blocks or allocates, it copies the
+// frame into a pre-allocated slot and reports false if the ring is full.
+//
+// Exactly one goroutine may call Push and exactly one goroutine may call Pop.
+type Int32FrameRing struct {
+	frames    [][]int32 // Pre-allocated frame slots.
+	lengths   []int     // Number of valid samples in each slot.
+	tags      []uint64  // Caller supplied tag (e.g. frame counter) for each slot.
+	head      atomic.Uint64
+	tail      atomic.Uint64
+	mask      uint64
+	frameSize int
+}
+
+// NewInt32FrameRing creates a ring with room for at least slots frames of up to
+// frameSize samples each. The slot count is rounded up to the next power of 2.
+func NewInt32FrameRing(slots, frameSize int) *Int32FrameRing {
+	slots = bitint.NextPowerOfTwo(slots)
+
+	r := &Int32FrameRing{
+		frames:    make([][]int32, slots),
+		lengths:   make([]int, slots),
+		tags:      make([]uint64, slots),
+		mask:      uint64(slots - 1),
+		frameSize: frameSize,
+	}
+	for i := range r.frames {
+		r.frames[i] = simd.AlignedInt32(frameSize)
+	}
+
+	return r
+}
+
+// Push copies frame into the next free slot, tagged with tag. Samples beyond the
+// ring's frame size are truncated. It returns false, dropping the frame, when the
+// ring is full.
+func (r *Int32FrameRing) Push(frame []int32, tag uint64) bool {
+	tail := r.tail.Load()
+	if tail-r.head.Load() > r.mask {
+		return false
+	}
+
+	slot := tail & r.mask
+	r.lengths[slot] = copy(r.frames[slot], frame)
+	r.tags[slot] = tag
+
+	// Publish the slot to the consumer.
+	r.tail.Store(tail + 1)
+	return true
+}
+
+// Pop copies the oldest frame into dst and returns the number of samples copied
+// and its tag. It returns ok == false when the ring is empty.
+func (r *Int32FrameRing) Pop(dst []int32) (n int, tag uint64, ok bool) {
+	head := r.head.Load()
+	if head == r.tail.Load() {
+		return 0, 0, false
+	}
+
+	slot := head & r.mask
+	n = copy(dst, r.frames[slot][:r.lengths[slot]])
+	tag = r.tags[slot]
+
+	// Release the slot back to the producer.
+	r.head.Store(head + 1)
+	return n, tag, true
+}
+
+// Len returns the number of frames waiting to be popped.
+func (r *Int32FrameRing) Len() int {
+	return int(r.tail.Load() - r.head.Load())
+}
+
+// FrameSize returns the maximum number of samples stored per frame.
+func (r *Int32FrameRing) FrameSize() int {
+	return r.frameSize
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInt32FrameRing_Basic(t *testing.T) {
+	r := NewInt32FrameRing(3, 4) // Rounded up to 4 slots.
+	dst := make([]int32, 4)
+
+	_, _, ok := r.Pop(dst)
+	assert.False(t, ok, "Pop on an empty ring should fail")
+
+	for i := range 4 {
+		assert.True(t, r.Push([]int32{int32(i), int32(i), int32(i), int32(i)}, uint64(i)), "Push %d should succeed", i)
+	}
+	assert.False(t, r.Push([]int32{9}, 9), "Push on a full ring should fail")
+	assert.Equal(t, 4, r.Len())
+
+	for i := range 4 {
+		n, tag, ok := r.Pop(dst)
+		require.True(t, ok, "Pop %d should succeed", i)
+		assert.Equal(t, 4, n)
+		assert.Equal(t, uint64(i), tag)
+		assert.Equal(t, []int32{int32(i), int32(i), int32(i), int32(i)}, dst)
+	}
+	assert.Equal(t, 0, r.Len())
+}
+
+func TestInt32FrameRing_ShortAndLongFrames(t *testing.T) {
+	r := NewInt32FrameRing(2, 4)
+	dst := make([]int32, 8)
+
+	r.Push([]int32{1, 2}, 0)
+	r.Push([]int32{1, 2, 3, 4, 5, 6}, 1)
+
+	n, _, _ := r.Pop(dst)
+	assert.Equal(t, 2, n, "Short frames should keep their length")
+
+	n, _, _ = r.Pop(dst)
+	assert.Equal(t, 4, n, "Long frames should be truncated to the frame size")
+	assert.Equal(t, []int32{1, 2, 3, 4}, dst[:n])
+}
+
+func TestInt32FrameRing_ConcurrentAccess(t *testing.T) {
+	const frames = 10000
+	r := NewInt32FrameRing(8, 16)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		frame := make([]int32, 16)
+		for i := 0; i < frames; {
+			for j := range frame {
+				frame[j] = int32(i)
+			}
+			if r.Push(frame, uint64(i)) {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	dst := make([]int32, 16)
+	for expected := 0; expected < frames; {
+		n, tag, ok := r.Pop(dst)
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		require.Equal(t, uint64(expected), tag, "Frames should arrive in order")
+		for j := range n {
+			require.Equal(t, int32(expected), dst[j], "Frame %d should not be torn", expected)
+		}
+		expected++
+	}
+	wg.Wait()
+}
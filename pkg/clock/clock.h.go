@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of wall-clock time for components that rate limit or
+// schedule work, so tests can substitute a Manual clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is the Clock backed by time.Now.
+type Real struct{}
+
+// Manual is a Clock that only moves when told to. It is safe for concurrent use.
+type Manual struct {
+	now time.Time
+	mu  sync.Mutex
+}
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManual(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := NewManual(start)
+
+	assert.Equal(t, start, c.Now(), "Manual clock should start at the given time")
+	assert.Equal(t, start, c.Now(), "Manual clock should not move on its own")
+
+	c.Advance(1500 * time.Millisecond)
+	assert.Equal(t, start.Add(1500*time.Millisecond), c.Now(), "Advance should move the clock forward")
+
+	c.Set(start)
+	assert.Equal(t, start, c.Now(), "Set should move the clock to the given time")
+}
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	now := Real{}.Now()
+
+	assert.False(t, now.Before(before), "Real clock should follow time.Now")
+}
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Package wav provides a minimal streaming WAV (RIFF/WAVE) decoder for offline
+analysis. Integer PCM (16, 24 and 32-bit) and 32-bit IEEE float files are
+supported, including WAVE_FORMAT_EXTENSIBLE headers. Samples are delivered as
+interleaved full-scale int32, the same representation as the live input stream,
+so the analysis code does not need to know where the audio came from.
+*/
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	formatPCM        = 1
+	formatFloat      = 3
+	formatExtensible = 0xFFFE
+)
+
+// NewReader parses the WAV header from r and positions it at the first sample.
+func NewReader(r io.ReadSeeker) (*Reader, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, fmt.Errorf("read RIFF header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a RIFF/WAVE file")
+	}
+
+	wr := &Reader{r: r}
+	haveFormat := false
+	offset := int64(len(riff))
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("no data chunk found: %w", err)
+		}
+		offset += int64(len(header))
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		switch id {
+		case "fmt ":
+			if err := wr.readFormat(size); err != nil {
+				return nil, err
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("data chunk before fmt chunk")
+			}
+			wr.dataStart = offset
+			wr.dataSize = size
+			wr.remaining = size
+			return wr, nil
+		default:
+			if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("skip %q chunk: %w", id, err)
+			}
+		}
+
+		// Chunks are padded to an even size.
+		if size%2 == 1 {
+			if _, err := r.Seek(1, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("skip chunk padding: %w", err)
+			}
+			size++
+		}
+		offset += size
+	}
+}
+
+func (wr *Reader) readFormat(size int64) error {
+	if size < 16 {
+		return fmt.Errorf("fmt chunk too short: %d bytes", size)
+	}
+	chunk := make([]byte, size)
+	if _, err := io.ReadFull(wr.r, chunk); err != nil {
+		return fmt.Errorf("read fmt chunk: %w", err)
+	}
+
+	audioFormat := binary.LittleEndian.Uint16(chunk[0:2])
+	if audioFormat == formatExtensible && size >= 26 {
+		// The first two bytes of the SubFormat GUID carry the actual format.
+		audioFormat = binary.LittleEndian.Uint16(chunk[24:26])
+	}
+
+	wr.format = Format{
+		Channels:      int(binary.LittleEndian.Uint16(chunk[2:4])),
+		SampleRate:    int(binary.LittleEndian.Uint32(chunk[4:8])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(chunk[14:16])),
+		Float:         audioFormat == formatFloat,
+	}
+
+	switch {
+	case wr.format.Channels == 0:
+		return fmt.Errorf("invalid channel count 0")
+	case audioFormat == formatPCM && (wr.format.BitsPerSample == 16 || wr.format.BitsPerSample == 24 || wr.format.BitsPerSample == 32):
+	case audioFormat == formatFloat && wr.format.BitsPerSample == 32:
+	default:
+		return fmt.Errorf("unsupported sample format %d with %d bits per sample", audioFormat, wr.format.BitsPerSample)
+	}
+
+	return nil
+}
+
+// Format returns the sample layout of the file.
+func (wr *Reader) Format() Format {
+	return wr.format
+}
+
+// Read decodes up to len(dst) interleaved samples into dst as full-scale int32
+// and returns the number of samples read. At the end of the data it returns
+// io.EOF, possibly together with a final partial read.
+func (wr *Reader) Read(dst []int32) (int, error) {
+	bytesPerSample := int64(wr.format.BitsPerSample / 8)
+	n := min(int64(len(dst)), wr.remaining/bytesPerSample)
+	if n == 0 {
+		return 0, io.EOF
+	}
+
+	size := int(n * bytesPerSample)
+	if cap(wr.buf) < size {
+		wr.buf = make([]byte, size)
+	}
+	buf := wr.buf[:size]
+	if _, err := io.ReadFull(wr.r, buf); err != nil {
+		return 0, fmt.Errorf("read samples: %w", err)
+	}
+	wr.remaining -= int64(size)
+
+	for i := range int(n) {
+		b := buf[i*int(bytesPerSample):]
+		switch {
+		case wr.format.Float:
+			dst[i] = floatToInt32(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case bytesPerSample == 2:
+			dst[i] = int32(int16(binary.LittleEndian.Uint16(b))) << 16
+		case bytesPerSample == 3:
+			dst[i] = int32(uint32(b[0])<<8 | uint32(b[1])<<16 | uint32(b[2])<<24)
+		default:
+			dst[i] = int32(binary.LittleEndian.Uint32(b))
+		}
+	}
+
+	if wr.remaining < bytesPerSample {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// Rewind positions the reader at the first sample again.
+func (wr *Reader) Rewind() error {
+	if _, err := wr.r.Seek(wr.dataStart, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind: %w", err)
+	}
+	wr.remaining = wr.dataSize
+	return nil
+}
+
+// floatToInt32 converts a sample nominally in [-1, 1] to full-scale int32,
+// clipping out-of-range values.
+func floatToInt32(s float32) int32 {
+	v := float64(s) * (math.MaxInt32 + 1)
+	switch {
+	case v >= math.MaxInt32:
+		return math.MaxInt32
+	case v <= math.MinInt32:
+		return math.MinInt32
+	default:
+		return int32(v)
+	}
+}
@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+package wav
+
+import "io"
+
+// Format describes the sample layout of a WAV file.
+type Format struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Float         bool // IEEE float samples, otherwise signed integer PCM.
+}
+
+// Reader decodes the data chunk of a WAV file into interleaved int32 samples.
+type Reader struct {
+	r         io.ReadSeeker
+	buf       []byte
+	format    Format
+	dataStart int64
+	dataSize  int64
+	remaining int64
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildWav assembles a WAV file with an extra chunk before "fmt " to exercise
+// chunk skipping, including an odd-sized chunk with padding.
+func buildWav(audioFormat uint16, channels, sampleRate, bits int, data []byte) []byte {
+	var fmtChunk bytes.Buffer
+	blockAlign := channels * bits / 8
+	binary.Write(&fmtChunk, binary.LittleEndian, audioFormat)
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(channels))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(sampleRate*blockAlign))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(bits))
+
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	body.WriteString("LIST")
+	binary.Write(&body, binary.LittleEndian, uint32(3))
+	body.Write([]byte{1, 2, 3, 0}) // 3 bytes plus padding.
+	body.WriteString("fmt ")
+	binary.Write(&body, binary.LittleEndian, uint32(fmtChunk.Len()))
+	body.Write(fmtChunk.Bytes())
+	body.WriteString("data")
+	binary.Write(&body, binary.LittleEndian, uint32(len(data)))
+	body.Write(data)
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(body.Len()))
+	file.Write(body.Bytes())
+	return file.Bytes()
+}
+
+func TestReader_PCM16(t *testing.T) {
+	var data bytes.Buffer
+	for _, s := range []int16{math.MinInt16, -1, 0, 1, math.MaxInt16} {
+		binary.Write(&data, binary.LittleEndian, s)
+	}
+
+	r, err := NewReader(bytes.NewReader(buildWav(formatPCM, 1, 44100, 16, data.Bytes())))
+	require.NoError(t, err, "NewReader should parse a valid header")
+	assert.Equal(t, Format{SampleRate: 44100, Channels: 1, BitsPerSample: 16}, r.Format())
+
+	dst := make([]int32, 3)
+	n, err := r.Read(dst)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []int32{math.MinInt32, -1 << 16, 0}, dst)
+
+	n, err = r.Read(dst)
+	assert.Equal(t, io.EOF, err, "The final partial read should report EOF")
+	assert.Equal(t, []int32{1 << 16, math.MaxInt16 << 16}, dst[:n])
+
+	n, err = r.Read(dst)
+	assert.Equal(t, io.EOF, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, r.Rewind())
+	n, err = r.Read(dst)
+	require.NoError(t, err)
+	assert.Equal(t, int32(math.MinInt32), dst[0], "Rewind should restart from the first sample")
+	assert.Equal(t, 3, n)
+}
+
+func TestReader_Formats(t *testing.T) {
+	var pcm24 bytes.Buffer
+	pcm24.Write([]byte{0x00, 0x00, 0x80}) // Minimum 24-bit value.
+	pcm24.Write([]byte{0xFF, 0xFF, 0x7F}) // Maximum 24-bit value.
+
+	var float32s bytes.Buffer
+	for _, s := range []float32{-1, 0.5, 2} {
+		binary.Write(&float32s, binary.LittleEndian, s)
+	}
+
+	testCases := []struct {
+		name     string
+		format   uint16
+		bits     int
+		data     []byte
+		expected []int32
+	}{
+		{"PCM 24-bit", formatPCM, 24, pcm24.Bytes(), []int32{math.MinInt32, 0x7FFFFF00}},
+		{"Float 32-bit", formatFloat, 32, float32s.Bytes(), []int32{math.MinInt32, 1 << 30, math.MaxInt32}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(buildWav(tc.format, 1, 48000, tc.bits, tc.data)))
+			require.NoError(t, err, "NewReader should parse a valid header")
+
+			dst := make([]int32, len(tc.expected))
+			n, err := r.Read(dst)
+			assert.Equal(t, io.EOF, err)
+			assert.Equal(t, tc.expected, dst[:n])
+		})
+	}
+}
+
+func TestReader_Invalid(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("RIFX....WAVE")))
+	assert.Error(t, err, "Non-RIFF data should be rejected")
+
+	_, err = NewReader(bytes.NewReader(buildWav(formatPCM, 1, 44100, 8, nil)))
+	assert.Error(t, err, "8-bit PCM should be rejected")
+}